@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+)
+
+// completionCmd generates shell completion scripts
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for the given shell. Besides commands and
+flags, connection names are completed for --connection (read from the
+configuration file).
+
+Examples:
+  # Bash (current session)
+  source <(flowgauge completion bash)
+
+  # Bash (permanently, Linux)
+  flowgauge completion bash > /etc/bash_completion.d/flowgauge
+
+  # Zsh
+  flowgauge completion zsh > "${fpath[1]}/_flowgauge"
+
+  # Fish
+  flowgauge completion fish > ~/.config/fish/completions/flowgauge.fish
+
+  # PowerShell
+  flowgauge completion powershell | Out-String | Invoke-Expression`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		default:
+			return fmt.Errorf("unsupported shell: %q", args[0])
+		}
+	},
+}
+
+// completeConnectionNames suggests the connection names of the configuration
+// (--config is honored). Nothing is suggested if it can't be loaded.
+func completeConnectionNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names := make([]string, 0, len(c.Connections))
+	for _, conn := range c.Connections {
+		if conn.Enabled {
+			names = append(names, conn.Name)
+		} else {
+			names = append(names, conn.Name+"\tdisabled")
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerConnectionCompletion enables connection name completion for the
+// --connection flag of cmd.
+func registerConnectionCompletion(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("connection", completeConnectionNames)
+}
+
+func init() {
+	// Replace cobra's default completion command, which doesn't skip config loading
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lan-dot-party/flowgauge/internal/logger"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+)
+
+var (
+	pingConnection string
+	pingJSON       bool
+)
+
+// pingCmd represents the ping command
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Run a quick latency check",
+	Long: `Measure the latency of a single connection without running the
+download and upload tests. Results are not saved. The command exits with a
+non-zero status if the check fails, which makes it suitable for health scripts.
+
+Examples:
+  # Check the first enabled connection
+  flowgauge ping
+
+  # Check a specific connection
+  flowgauge ping --connection WAN1
+
+  # Output the result as JSON
+  flowgauge ping --json`,
+	RunE: runPing,
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	connections := cfg.GetEnabledConnections()
+	if len(connections) == 0 {
+		return fmt.Errorf("no enabled connections found in configuration")
+	}
+
+	if pingConnection != "" {
+		conn := cfg.GetConnectionByName(pingConnection)
+		if conn == nil {
+			return fmt.Errorf("connection %q not found", pingConnection)
+		}
+		if !conn.Enabled {
+			return fmt.Errorf("connection %q is disabled", pingConnection)
+		}
+	}
+
+	runner, err := speedtest.NewMultiWANRunner(connections, &cfg.Speedtest, logger.Log)
+	if err != nil {
+		return fmt.Errorf("failed to create speedtest runner: %w", err)
+	}
+
+	result, err := runner.QuickTest(context.Background(), pingConnection)
+	if result == nil {
+		return fmt.Errorf("latency check failed: %w", err)
+	}
+
+	if pingJSON {
+		data, jsonErr := json.MarshalIndent(result, "", "  ")
+		if jsonErr != nil {
+			return fmt.Errorf("failed to marshal result: %w", jsonErr)
+		}
+		fmt.Println(string(data))
+	} else if result.LatencyOK {
+		fmt.Printf("%s: %.2f ms (jitter %.2f ms) via %s\n",
+			result.ConnectionName, result.LatencyMs, result.JitterMs, result.ServerName)
+	}
+
+	if err != nil {
+		return fmt.Errorf("latency check of %q failed: %w", result.ConnectionName, err)
+	}
+	if !result.LatencyOK {
+		return fmt.Errorf("latency check of %q failed: no response from %s", result.ConnectionName, result.ServerName)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pingCmd)
+
+	pingCmd.Flags().StringVarP(&pingConnection, "connection", "C", "",
+		"check a specific connection by name (default: first enabled connection)")
+	pingCmd.Flags().BoolVar(&pingJSON, "json", false,
+		"output the result as JSON")
+
+	registerConnectionCompletion(pingCmd)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/logger"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+var (
+	pruneOlderThan  string
+	pruneConnection string
+	pruneDryRun     bool
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old speedtest results",
+	Long: `Delete stored speedtest results older than a given age.
+
+Examples:
+  # Delete results older than 30 days
+  flowgauge prune --older-than 30d
+
+  # Only delete old results of a specific connection
+  flowgauge prune --older-than 90d --connection WAN1
+
+  # Show how many results would be deleted without deleting them
+  flowgauge prune --older-than 30d --dry-run`,
+	RunE: runPrune,
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	if pruneOlderThan == "" {
+		return fmt.Errorf("--older-than is required")
+	}
+	age, err := config.ParseDuration(pruneOlderThan)
+	if err != nil {
+		return fmt.Errorf("invalid duration format for --older-than: %w", err)
+	}
+	// A zero or negative age would delete every result
+	if age <= 0 {
+		return fmt.Errorf("--older-than must be positive, got %q", pruneOlderThan)
+	}
+	cutoff := time.Now().Add(-age)
+
+	// Initialize storage
+	store, err := storage.NewStorage(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create storage: %w", err)
+	}
+	if err := store.Init(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	scope := "all connections"
+	if pruneConnection != "" {
+		scope = fmt.Sprintf("connection %q", pruneConnection)
+	}
+
+	count, err := store.CountOldResults(ctx, cutoff, pruneConnection)
+	if err != nil {
+		return fmt.Errorf("failed to count results: %w", err)
+	}
+
+	fmt.Printf("Results older than %s (before %s) for %s: %d\n",
+		pruneOlderThan, cutoff.In(cfg.Location()).Format("2006-01-02 15:04:05"), scope, count)
+
+	if pruneDryRun {
+		fmt.Println("Dry run: no results deleted.")
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+
+	deleted, err := store.DeleteOldResults(ctx, cutoff, pruneConnection)
+	if err != nil {
+		return fmt.Errorf("failed to delete results: %w", err)
+	}
+
+	logger.Info("Pruned old results",
+		zap.Int64("deleted", deleted),
+		zap.Time("older_than", cutoff),
+		zap.String("connection", pruneConnection),
+	)
+	fmt.Printf("✅ Deleted %d results\n", deleted)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "",
+		"delete results older than this age (e.g., 720h, 30d)")
+	pruneCmd.Flags().StringVarP(&pruneConnection, "connection", "C", "",
+		"only delete results of this connection")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false,
+		"only report how many results would be deleted")
+
+	registerConnectionCompletion(pruneCmd)
+}
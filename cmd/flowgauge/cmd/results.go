@@ -4,10 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 	"github.com/lan-dot-party/flowgauge/internal/storage"
 )
 
@@ -18,8 +23,16 @@ var (
 	resultsSince      string
 	resultsStats      bool
 	resultsStatsPeriod string
+	resultsDataUsage   bool
+	resultsFailures    bool
+	resultsWatch       bool
+	resultsInterval    time.Duration
+	resultsUntil       string
 )
 
+// watchBatchLimit is the number of results fetched per poll in watch mode.
+const watchBatchLimit = 100
+
 // resultsCmd represents the results command
 var resultsCmd = &cobra.Command{
 	Use:   "results",
@@ -38,9 +51,21 @@ Examples:
   
   # Show results from the last 24 hours
   flowgauge results --since 24h
+
+  # Show results of the first week of January
+  flowgauge results --since 2024-01-01 --until 2024-01-08
   
   # Show statistics for a connection
-  flowgauge results --stats --connection WAN1 --period 7d`,
+  flowgauge results --stats --connection WAN1 --period 7d
+
+  # Show the data consumed by speedtests in the last 30 days
+  flowgauge results --data-usage --period 30d
+
+  # Show the error messages of the last 20 failed tests of a connection
+  flowgauge results --failures --connection WAN1 --limit 20
+
+  # Follow new results as they are saved (until Ctrl-C)
+  flowgauge results --watch`,
 	RunE: runResults,
 }
 
@@ -61,10 +86,16 @@ func runResults(cmd *cobra.Command, args []string) error {
 	defer func() { _ = store.Close() }()
 
 	ctx := context.Background()
+	loc := cfg.Location()
 
 	// Show statistics if requested
 	if resultsStats {
-		return showStats(ctx, store)
+		return showStats(ctx, store, loc, speedtest.SpeedUnit(cfg.General.SpeedUnit))
+	}
+
+	// Show data usage if requested
+	if resultsDataUsage {
+		return showDataUsage(ctx, store, cfg)
 	}
 
 	// Build filter
@@ -73,13 +104,27 @@ func runResults(cmd *cobra.Command, args []string) error {
 		Limit:          resultsLimit,
 	}
 
-	// Parse since duration
+	// Parse the time range
 	if resultsSince != "" {
-		duration, err := time.ParseDuration(resultsSince)
+		filter.Since, err = config.ParseTimeOrDuration(resultsSince, loc)
 		if err != nil {
-			return fmt.Errorf("invalid duration format for --since: %w", err)
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if resultsUntil != "" {
+		filter.Until, err = config.ParseTimeOrDuration(resultsUntil, loc)
+		if err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
 		}
-		filter.Since = time.Now().Add(-duration)
+	}
+
+	if resultsFailures {
+		failed := true
+		filter.ErrorOnly = &failed
+	}
+
+	if resultsWatch {
+		return watchResults(ctx, store, filter, loc, speedtest.SpeedUnit(cfg.General.SpeedUnit))
 	}
 
 	// Get results
@@ -94,25 +139,30 @@ func runResults(cmd *cobra.Command, args []string) error {
 	}
 
 	// Output results
+	for i := range results {
+		results[i].InLocation(loc)
+	}
 	if resultsJSON {
 		data, err := json.MarshalIndent(results, "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to marshal results: %w", err)
 		}
 		fmt.Println(string(data))
+	} else if resultsFailures {
+		printFailuresTable(results)
 	} else {
-		printResultsTable(results)
+		printResultsTable(results, speedtest.SpeedUnit(cfg.General.SpeedUnit))
 	}
 
 	return nil
 }
 
-func showStats(ctx context.Context, store storage.Storage) error {
+func showStats(ctx context.Context, store storage.Storage, loc *time.Location, unit speedtest.SpeedUnit) error {
 	// Parse period
 	period := 24 * time.Hour // Default 24h
 	if resultsStatsPeriod != "" {
 		var err error
-		period, err = time.ParseDuration(resultsStatsPeriod)
+		period, err = config.ParseDuration(resultsStatsPeriod)
 		if err != nil {
 			return fmt.Errorf("invalid duration format for --period: %w", err)
 		}
@@ -127,6 +177,7 @@ func showStats(ctx context.Context, store storage.Storage) error {
 		return fmt.Errorf("failed to get stats: %w", err)
 	}
 
+	stats.InLocation(loc)
 	if resultsJSON {
 		data, err := json.MarshalIndent(stats, "", "  ")
 		if err != nil {
@@ -134,72 +185,279 @@ func showStats(ctx context.Context, store storage.Storage) error {
 		}
 		fmt.Println(string(data))
 	} else {
-		printStats(stats)
+		printStats(stats, unit)
+	}
+
+	return nil
+}
+
+// dataUsage is the data consumed by the speedtests of a connection.
+type dataUsage struct {
+	ConnectionName  string `json:"connection_name"`
+	TestCount       int    `json:"test_count"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+	BytesUploaded   int64  `json:"bytes_uploaded"`
+	BytesTotal      int64  `json:"bytes_total"`
+}
+
+func showDataUsage(ctx context.Context, store storage.Storage, cfg *config.Config) error {
+	period, err := config.ParseDuration(resultsStatsPeriod)
+	if err != nil {
+		return fmt.Errorf("invalid duration format for --period: %w", err)
+	}
+
+	// All enabled connections, unless filtered
+	var names []string
+	if resultsConnection != "" {
+		names = []string{resultsConnection}
+	} else {
+		for _, conn := range cfg.GetEnabledConnections() {
+			names = append(names, conn.Name)
+		}
+	}
+
+	usage := make([]dataUsage, 0, len(names))
+	for _, name := range names {
+		stats, err := store.GetStats(ctx, name, period)
+		if err != nil {
+			return fmt.Errorf("failed to get stats for %s: %w", name, err)
+		}
+		usage = append(usage, dataUsage{
+			ConnectionName:  name,
+			TestCount:       stats.TestCount,
+			BytesDownloaded: stats.BytesDownloaded,
+			BytesUploaded:   stats.BytesUploaded,
+			BytesTotal:      stats.BytesDownloaded + stats.BytesUploaded,
+		})
+	}
+
+	if resultsJSON {
+		data, err := json.MarshalIndent(usage, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal data usage: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDataUsage(usage, period)
 	}
 
 	return nil
 }
 
-func printResultsTable(results []storage.TestResult) {
+func printDataUsage(usage []dataUsage, period time.Duration) {
+	fmt.Println()
+	fmt.Printf("Data Usage (last %s)\n", period)
+	fmt.Println("==========================================")
+	fmt.Println()
+
+	fmt.Printf("%-20s | %6s | %12s | %12s | %12s\n",
+		"Connection", "Tests", "Downloaded", "Uploaded", "Total")
+	fmt.Println("---------------------+--------+--------------+--------------+-------------")
+
+	var total dataUsage
+	for _, u := range usage {
+		fmt.Printf("%-20s | %6d | %12s | %12s | %12s\n",
+			truncate(u.ConnectionName, 20), u.TestCount,
+			formatBytes(u.BytesDownloaded), formatBytes(u.BytesUploaded), formatBytes(u.BytesTotal))
+		total.TestCount += u.TestCount
+		total.BytesDownloaded += u.BytesDownloaded
+		total.BytesUploaded += u.BytesUploaded
+		total.BytesTotal += u.BytesTotal
+	}
+
+	fmt.Println("---------------------+--------+--------------+--------------+-------------")
+	fmt.Printf("%-20s | %6d | %12s | %12s | %12s\n",
+		"Total", total.TestCount,
+		formatBytes(total.BytesDownloaded), formatBytes(total.BytesUploaded), formatBytes(total.BytesTotal))
+}
+
+// formatBytes formats a byte count with decimal units (as used by ISPs for
+// data caps), e.g. 1.50 GB.
+func formatBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	value := float64(b)
+	for _, suffix := range []string{"kB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit || suffix == "TB" {
+			return fmt.Sprintf("%.2f %s", value, suffix)
+		}
+	}
+	return ""
+}
+
+func printResultsTable(results []storage.TestResult, unit speedtest.SpeedUnit) {
 	fmt.Println()
 	fmt.Println("Speedtest Results")
 	fmt.Println("=================")
 	fmt.Println()
 	
-	// Header
+	printResultsHeader()
+	for _, r := range results {
+		printResultRow(r, unit)
+	}
+	
+	fmt.Println()
+	fmt.Printf("Total: %d results\n", len(results))
+}
+
+// printResultsHeader prints the column header of the results table.
+func printResultsHeader() {
 	fmt.Printf("%-5s | %-20s | %11s | %14s | %14s | %-20s | %s\n",
 		"ID", "Connection", "Latency", "Download", "Upload", "Server", "Time")
 	fmt.Println("------+----------------------+-------------+----------------+----------------+----------------------+---------------------")
+}
 
-	for _, r := range results {
-		timeStr := r.CreatedAt.Local().Format("2006-01-02 15:04:05")
-		
-		if r.IsError() {
-			fmt.Printf("%-5d | %-20s | %-11s | %-14s | %-14s | %-20s | %s\n",
-				r.ID, truncate(r.ConnectionName, 20), "ERROR", "-", "-", truncate(r.Error, 20), timeStr)
-		} else {
-			fmt.Printf("%-5d | %-20s | %8.2f ms | %10.2f Mbps | %10.2f Mbps | %-20s | %s\n",
-				r.ID, truncate(r.ConnectionName, 20), r.LatencyMs, r.DownloadMbps, r.UploadMbps,
-				truncate(r.ServerName, 20), timeStr)
+// printResultRow prints a single result as a line of the results table.
+func printResultRow(r storage.TestResult, unit speedtest.SpeedUnit) {
+	timeStr := r.CreatedAt.Format("2006-01-02 15:04:05")
+	connection := truncate(speedtest.ConnectionLabel(r.ConnectionName, r.IPFamily), 20)
+
+	if r.IsError() {
+		fmt.Printf("%-5d | %-20s | %-11s | %-14s | %-14s | %-20s | %s\n",
+			r.ID, connection, "ERROR", "-", "-", truncate(r.Error, 20), timeStr)
+	} else {
+		fmt.Printf("%-5d | %-20s | %8.2f ms | %10.2f %s | %10.2f %s | %-20s | %s\n",
+			r.ID, connection, r.LatencyMs,
+			unit.Convert(r.DownloadMbps), unit.Label(), unit.Convert(r.UploadMbps), unit.Label(),
+			truncate(r.ServerName, 20), timeStr)
+	}
+}
+
+// watchResults prints the most recent results and then polls storage for
+// new ones (by ID), printing each as a table line or, with --json, as one
+// JSON object per line. Runs until interrupted.
+func watchResults(ctx context.Context, store storage.Storage, filter storage.ResultFilter, loc *time.Location, unit speedtest.SpeedUnit) error {
+	if resultsInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !resultsJSON {
+		printResultsHeader()
+	}
+
+	// Start with the most recent --limit results, like tail -f
+	var lastID int64
+	first := true
+	ticker := time.NewTicker(resultsInterval)
+	defer ticker.Stop()
+
+	for {
+		if !first {
+			filter.Limit = watchBatchLimit
+		}
+		results, err := store.GetResults(ctx, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get results: %w", err)
+		}
+
+		// Results are newest first; print new ones in chronological order
+		for i := len(results) - 1; i >= 0; i-- {
+			r := results[i]
+			if r.ID <= lastID {
+				continue
+			}
+			lastID = r.ID
+			r.InLocation(loc)
+			if resultsJSON {
+				data, err := json.Marshal(r)
+				if err != nil {
+					return fmt.Errorf("failed to marshal result: %w", err)
+				}
+				fmt.Println(string(data))
+			} else {
+				printResultRow(r, unit)
+			}
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
 		}
 	}
-	
+}
+
+// printFailuresTable prints failed results with their full error message.
+func printFailuresTable(results []storage.TestResult) {
 	fmt.Println()
-	fmt.Printf("Total: %d results\n", len(results))
+	fmt.Println("Failed Tests")
+	fmt.Println("============")
+	fmt.Println()
+
+	fmt.Printf("%-5s | %-20s | %-19s | %s\n", "ID", "Connection", "Time", "Error")
+	fmt.Println("------+----------------------+---------------------+----------------------")
+
+	for _, r := range results {
+		fmt.Printf("%-5d | %-20s | %-19s | %s\n",
+			r.ID, truncate(r.ConnectionName, 20), r.CreatedAt.Format("2006-01-02 15:04:05"), r.Error)
+	}
+
+	fmt.Println()
+	fmt.Printf("Total: %d failures\n", len(results))
 }
 
-func printStats(stats *storage.Stats) {
+func printStats(stats *storage.Stats, unit speedtest.SpeedUnit) {
 	fmt.Println()
 	fmt.Printf("Statistics for: %s\n", stats.ConnectionName)
 	fmt.Printf("Period: %s (from %s to %s)\n",
 		stats.Period,
-		stats.Since.Local().Format("2006-01-02 15:04"),
-		stats.Until.Local().Format("2006-01-02 15:04"))
+		stats.Since.Format("2006-01-02 15:04"),
+		stats.Until.Format("2006-01-02 15:04"))
 	fmt.Println("==========================================")
 	fmt.Println()
 	
 	fmt.Printf("Tests:     %d total, %d errors\n", stats.TestCount, stats.ErrorCount)
+	if stats.TestCount > 0 {
+		fmt.Printf("Uptime:    %.2f%%\n", stats.Uptime)
+	}
 	fmt.Println()
 	
 	if stats.TestCount > stats.ErrorCount {
-		fmt.Println("Download (Mbps):")
-		fmt.Printf("  Average: %.2f | Min: %.2f | Max: %.2f\n",
-			stats.AvgDownload, stats.MinDownload, stats.MaxDownload)
+		fmt.Printf("Download (%s):\n", unit.Label())
+		fmt.Printf("  Average: %.2f | Min: %s | Max: %s\n", unit.Convert(stats.AvgDownload),
+			formatExtreme(unit.Convert(stats.MinDownload), stats.MinDownloadAt),
+			formatExtreme(unit.Convert(stats.MaxDownload), stats.MaxDownloadAt))
 		fmt.Println()
 		
-		fmt.Println("Upload (Mbps):")
-		fmt.Printf("  Average: %.2f | Min: %.2f | Max: %.2f\n",
-			stats.AvgUpload, stats.MinUpload, stats.MaxUpload)
+		fmt.Printf("Upload (%s):\n", unit.Label())
+		fmt.Printf("  Average: %.2f | Min: %s | Max: %s\n", unit.Convert(stats.AvgUpload),
+			formatExtreme(unit.Convert(stats.MinUpload), stats.MinUploadAt),
+			formatExtreme(unit.Convert(stats.MaxUpload), stats.MaxUploadAt))
 		fmt.Println()
 		
 		fmt.Println("Latency (ms):")
+		fmt.Printf("  Average: %.2f | Min: %s | Max: %s\n", stats.AvgLatency,
+			formatExtreme(stats.MinLatency, stats.MinLatencyAt),
+			formatExtreme(stats.MaxLatency, stats.MaxLatencyAt))
+		fmt.Println()
+
+		fmt.Println("Jitter (ms):")
 		fmt.Printf("  Average: %.2f | Min: %.2f | Max: %.2f\n",
-			stats.AvgLatency, stats.MinLatency, stats.MaxLatency)
+			stats.AvgJitter, stats.MinJitter, stats.MaxJitter)
 	} else {
 		fmt.Println("No successful tests in this period.")
 	}
 }
 
+// formatExtreme formats a min/max value with the time it occurred, e.g.
+// "950.00 (01-15 03:00)".
+func formatExtreme(value float64, at *time.Time) string {
+	if at == nil {
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%.2f (%s)", value, at.Format("01-02 15:04"))
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -217,9 +475,21 @@ func init() {
 	resultsCmd.Flags().BoolVar(&resultsJSON, "json", false,
 		"output results as JSON")
 	resultsCmd.Flags().StringVar(&resultsSince, "since", "",
-		"show results since duration (e.g., 24h, 7d)")
+		"show results since a time, date or duration ago (e.g., 2024-01-02T15:04:05Z, 2024-01-02, 24h, 7d)")
+	resultsCmd.Flags().StringVar(&resultsUntil, "until", "",
+		"show results until a time, date or duration ago (same formats as --since)")
 	resultsCmd.Flags().BoolVar(&resultsStats, "stats", false,
 		"show statistics instead of individual results")
 	resultsCmd.Flags().StringVar(&resultsStatsPeriod, "period", "24h",
-		"time period for statistics (e.g., 24h, 7d, 30d)")
+		"time period for statistics and data usage (e.g., 24h, 7d, 30d)")
+	resultsCmd.Flags().BoolVar(&resultsDataUsage, "data-usage", false,
+		"show the data consumed by speedtests per connection")
+	resultsCmd.Flags().BoolVar(&resultsFailures, "failures", false,
+		"show only failed tests with their error messages")
+	resultsCmd.Flags().BoolVarP(&resultsWatch, "watch", "w", false,
+		"keep running and print new results as they are saved (until Ctrl-C)")
+	resultsCmd.Flags().DurationVar(&resultsInterval, "interval", 5*time.Second,
+		"polling interval for --watch")
+
+	registerConnectionCompletion(resultsCmd)
 }
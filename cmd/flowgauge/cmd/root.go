@@ -35,8 +35,10 @@ var rootCmd = &cobra.Command{
 Documentation: https://github.com/lan-dot-party/flowgauge`,
 	Version: version.GetVersion(),
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Skip config loading for certain commands
-		if cmd.Name() == "version" || cmd.Name() == "help" {
+		// Skip config loading for certain commands. Shell completion requests
+		// load the config themselves and must not fail without one.
+		switch cmd.Name() {
+		case "version", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
 			return nil
 		}
 		if cmd.Parent() != nil && cmd.Parent().Name() == "config" && cmd.Name() == "init" {
@@ -86,7 +88,7 @@ func Execute() error {
 func init() {
 	// Global persistent flags
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", 
-		"config file (default: /etc/flowgauge/config.yaml)")
+		"config file, \"-\" for stdin or an http(s) URL (default: /etc/flowgauge/config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, 
 		"enable verbose/debug output")
 
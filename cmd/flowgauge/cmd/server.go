@@ -12,6 +12,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/lan-dot-party/flowgauge/internal/api"
+	"github.com/lan-dot-party/flowgauge/internal/config"
 	"github.com/lan-dot-party/flowgauge/internal/logger"
 	"github.com/lan-dot-party/flowgauge/internal/scheduler"
 	"github.com/lan-dot-party/flowgauge/internal/speedtest"
@@ -70,6 +71,8 @@ func runServer(cmd *cobra.Command, args []string) error {
 		runner, err = speedtest.NewMultiWANRunner(connections, &cfg.Speedtest, logger.Log)
 		if err != nil {
 			logger.Warn("Failed to create speedtest runner", zap.Error(err))
+		} else {
+			runner.SetServerHistory(store.GetServerDownloads)
 		}
 	}
 
@@ -80,7 +83,9 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize Prometheus metrics from stored results
+	api.SetMetricsConfig(cfg.Prometheus)
 	initPrometheusMetrics(context.Background(), store)
+	deleteStaleMetrics(cfg)
 
 	// Create scheduler if enabled
 	var sched *scheduler.Scheduler
@@ -90,6 +95,15 @@ func runServer(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			logger.Warn("Failed to create scheduler", zap.Error(err))
 			schedulerEnabled = false
+		} else {
+			sched.SetOnResultSaved(server.InvalidateCache)
+			sched.SetReadOnly(server.ReadOnly)
+			sched.SetLocation(cfg.Location())
+			sched.SetRoundDecimals(cfg.General.RoundDecimals)
+			sched.SetAlerts(cfg.Alerts)
+			sched.SetRollup(cfg.Storage.Rollup)
+			sched.SetThinning(cfg.Storage.Thinning)
+			sched.SetReports(cfg.Reports, reportConnections(cfg), speedtest.SpeedUnit(cfg.General.SpeedUnit))
 		}
 	}
 
@@ -101,8 +115,48 @@ func runServer(cmd *cobra.Command, args []string) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// SIGHUP reloads the configuration without restarting the listener
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	// SIGUSR1 toggles read-only mode for maintenance
+	readOnlyChan := make(chan os.Signal, 1)
+	if len(readOnlySignals) > 0 {
+		signal.Notify(readOnlyChan, readOnlySignals...)
+	}
+
+	// Closed once shutdown has completed, so storage stays open until
+	// triggered tests have saved their results
+	shutdownDone := make(chan struct{})
+
+	// Start the scheduler before handling signals, as a reload replaces sched
+	var schedulerStarted bool
+	var nextRun string
+	if schedulerEnabled && sched != nil {
+		if err := sched.Start(); err != nil {
+			logger.Error("Failed to start scheduler", zap.Error(err))
+		} else {
+			schedulerStarted = true
+			nextRun = sched.NextRun()
+			sched.CatchUp()
+		}
+	}
+
 	go func() {
-		sig := <-sigChan
+		defer close(shutdownDone)
+
+		var sig os.Signal
+		for sig == nil {
+			select {
+			case <-hupChan:
+				logger.Info("Received SIGHUP, reloading configuration")
+				sched = reloadServerConfig(server, sched, store)
+			case <-readOnlyChan:
+				logger.Info("Received SIGUSR1, toggling read-only mode")
+				server.SetReadOnly(!server.ReadOnly())
+			case sig = <-sigChan:
+			}
+		}
 		logger.Info("Received signal, shutting down", zap.String("signal", sig.String()))
 		cancel()
 
@@ -111,7 +165,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 			sched.Stop()
 		}
 
-		// Give server time to shutdown gracefully
+		// Give server and triggered tests time to shutdown gracefully
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer shutdownCancel()
 
@@ -126,7 +180,11 @@ func runServer(cmd *cobra.Command, args []string) error {
 	fmt.Println("║       FlowGauge Web Server                ║")
 	fmt.Println("╚═══════════════════════════════════════════╝")
 	fmt.Println()
-	fmt.Printf("  Listen:      http://%s\n", cfg.Webserver.Listen)
+	if _, ok := cfg.Webserver.UnixSocketPath(); ok {
+		fmt.Printf("  Listen:      %s\n", cfg.Webserver.Listen)
+	} else {
+		fmt.Printf("  Listen:      http://%s\n", cfg.Webserver.Listen)
+	}
 	fmt.Printf("  Storage:     %s\n", cfg.Storage.Type)
 	fmt.Printf("  Connections: %d configured\n", len(cfg.Connections))
 	if cfg.Webserver.Auth != nil && cfg.Webserver.Auth.Username != "" {
@@ -134,16 +192,16 @@ func runServer(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("  Auth:        None\n")
 	}
-
-	// Start scheduler if enabled
-	if schedulerEnabled && sched != nil {
-		if err := sched.Start(); err != nil {
-			logger.Error("Failed to start scheduler", zap.Error(err))
-		} else {
-			fmt.Printf("  Scheduler:   ✅ enabled (%s)\n", cfg.Scheduler.Schedule)
-			fmt.Printf("  Next run:    %s\n", sched.NextRun())
-		}
+	if cfg.Webserver.AllowTriggers {
+		fmt.Printf("  Triggers:    enabled\n")
 	} else {
+		fmt.Printf("  Triggers:    disabled (webserver.allow_triggers)\n")
+	}
+
+	if schedulerStarted {
+		fmt.Printf("  Scheduler:   ✅ enabled (%s)\n", cfg.Scheduler.Schedule)
+		fmt.Printf("  Next run:    %s\n", nextRun)
+	} else if !schedulerEnabled || sched == nil {
 		fmt.Printf("  Scheduler:   disabled\n")
 	}
 
@@ -151,16 +209,31 @@ func runServer(cmd *cobra.Command, args []string) error {
 	fmt.Println("  Dashboard:")
 	fmt.Println("    GET  /                    - Web Dashboard")
 	fmt.Println()
-	fmt.Println("  API Endpoints (Read-Only):")
+	fmt.Println("  API Endpoints:")
 	fmt.Println("    GET  /api/                - API Documentation")
+	fmt.Println("    GET  /api/openapi.json    - OpenAPI specification")
 	fmt.Println("    GET  /health              - Health check")
 	fmt.Println("    GET  /api/v1/results      - List results")
 	fmt.Println("    GET  /api/v1/results/latest - Latest results")
 	fmt.Println("    GET  /api/v1/connections  - List connections")
 	fmt.Println("    GET  /api/v1/connections/{name}/stats - Connection stats")
+	fmt.Println("    GET  /api/v1/connections/{name}/availability - Connection availability")
+	fmt.Println("    GET  /api/v1/connections/{name}/trends - Connection trends")
+	fmt.Println("    GET  /api/v1/connections/{name}/failures - Recent failed tests")
+	fmt.Println("    GET  /api/v1/connections/{name}/events - Health state changes")
+	fmt.Println("    GET  /api/v1/dscp         - DSCP names")
+	fmt.Println("    POST /api/v1/connections/{name}/test  - Trigger a speedtest")
+	fmt.Println("    POST /api/v1/connections/{name}/test/cancel - Cancel a triggered speedtest")
+	fmt.Println("    GET  /api/v1/triggers     - Triggered test queue")
+	fmt.Println("    GET  /api/v1/read-only    - Read-only mode")
+	fmt.Println("    POST /api/v1/read-only    - Toggle read-only mode")
+	fmt.Println("    GET  /api/v1/groups       - List connection groups")
+	fmt.Println("    GET  /api/v1/groups/{group}/stats - Group stats")
+	fmt.Println("    GET  /api/v1/config       - Sanitized configuration")
 	fmt.Println("    GET  /api/v1/metrics      - Prometheus metrics")
 	fmt.Println()
-	fmt.Println("  Press Ctrl+C to stop")
+	fmt.Println("  Press Ctrl+C to stop, send SIGHUP to reload the configuration")
+	fmt.Println("  Send SIGUSR1 to toggle read-only mode for maintenance")
 	fmt.Println()
 
 	// Start server (blocks until shutdown)
@@ -168,12 +241,12 @@ func runServer(cmd *cobra.Command, args []string) error {
 		// Check if we're shutting down
 		select {
 		case <-ctx.Done():
-			return nil
 		default:
 			return fmt.Errorf("server error: %w", err)
 		}
 	}
 
+	<-shutdownDone
 	return nil
 }
 
@@ -184,6 +257,88 @@ func init() {
 		"disable scheduler even if enabled in config")
 }
 
+// reloadServerConfig reloads the configuration file and applies it to the
+// scheduler, runner and web server. The current configuration is kept if the
+// new one fails to load or validate. Returns the (possibly new) scheduler.
+func reloadServerConfig(server *api.Server, sched *scheduler.Scheduler, store storage.Storage) *scheduler.Scheduler {
+	oldCfg := GetConfig()
+
+	newCfg, err := config.Load(cfgFile)
+	if err != nil {
+		logger.Error("Configuration reload failed, keeping current configuration", zap.Error(err))
+		return sched
+	}
+
+	// The diff is only logged; the new configuration is always applied so
+	// settings it doesn't list take effect too
+	changes := config.Diff(oldCfg, newCfg)
+	for _, change := range changes {
+		logger.Info("Configuration change", zap.String("change", change))
+	}
+
+	// Rebuild the runner from the new connection list
+	var runner *speedtest.MultiWANRunner
+	if connections := newCfg.GetEnabledConnections(); len(connections) > 0 {
+		runner, err = speedtest.NewMultiWANRunner(connections, &newCfg.Speedtest, logger.Log)
+		if err != nil {
+			logger.Error("Failed to create speedtest runner, keeping current configuration", zap.Error(err))
+			return sched
+		}
+		runner.SetServerHistory(store.GetServerDownloads)
+	}
+
+	// Apply scheduler changes first so an invalid schedule rejects the reload
+	schedulerEnabled := newCfg.Scheduler.Enabled && !noScheduler && runner != nil
+	switch {
+	case sched != nil && runner != nil:
+		// Reload registers the job with these settings, so they are applied
+		// before it, but only once the new schedule is known to be valid
+		if err := scheduler.ValidateConfig(&newCfg.Scheduler); err != nil {
+			logger.Error("Failed to reload scheduler, keeping current configuration", zap.Error(err))
+			return sched
+		}
+		sched.SetLocation(newCfg.Location())
+		sched.SetRoundDecimals(newCfg.General.RoundDecimals)
+		sched.SetAlerts(newCfg.Alerts)
+		if err := sched.Reload(&newCfg.Scheduler, runner); err != nil {
+			logger.Error("Failed to reload scheduler, keeping current configuration", zap.Error(err))
+			return sched
+		}
+		sched.SetReports(newCfg.Reports, reportConnections(newCfg), speedtest.SpeedUnit(newCfg.General.SpeedUnit))
+	case sched != nil && runner == nil:
+		logger.Warn("No enabled connections after reload, stopping scheduler")
+		sched.Stop()
+		sched = nil
+	case sched == nil && schedulerEnabled:
+		newSched, err := scheduler.NewScheduler(&newCfg.Scheduler, runner, store, logger.Log)
+		if err != nil {
+			logger.Error("Failed to create scheduler, keeping current configuration", zap.Error(err))
+			return sched
+		}
+		newSched.SetOnResultSaved(server.InvalidateCache)
+		newSched.SetReadOnly(server.ReadOnly)
+		newSched.SetLocation(newCfg.Location())
+		newSched.SetRoundDecimals(newCfg.General.RoundDecimals)
+		newSched.SetAlerts(newCfg.Alerts)
+		newSched.SetRollup(newCfg.Storage.Rollup)
+		newSched.SetThinning(newCfg.Storage.Thinning)
+		newSched.SetReports(newCfg.Reports, reportConnections(newCfg), speedtest.SpeedUnit(newCfg.General.SpeedUnit))
+		if err := newSched.Start(); err != nil {
+			logger.Error("Failed to start scheduler, keeping current configuration", zap.Error(err))
+			return sched
+		}
+		sched = newSched
+	}
+
+	server.Reload(newCfg, runner)
+	SetConfig(newCfg)
+	api.SetMetricsConfig(newCfg.Prometheus)
+	deleteStaleMetrics(newCfg)
+
+	logger.Info("Configuration reloaded", zap.Int("changes", len(changes)))
+	return sched
+}
+
 // initPrometheusMetrics loads latest results from storage and initializes Prometheus metrics.
 func initPrometheusMetrics(ctx context.Context, store storage.Storage) {
 	// Load latest results for each connection
@@ -208,3 +363,36 @@ func initPrometheusMetrics(ctx context.Context, store storage.Storage) {
 		zap.Int("connections", len(results)),
 	)
 }
+
+// deleteStaleMetrics removes Prometheus series of connections that are no
+// longer configured, so decommissioned connections don't linger in Prometheus.
+func deleteStaleMetrics(cfg *config.Config) {
+	names := make([]string, 0, len(cfg.Connections))
+	for _, conn := range cfg.Connections {
+		names = append(names, conn.Name)
+	}
+	if cfg.Speedtest.Aggregate {
+		names = append(names, config.AggregateConnectionName)
+	}
+
+	if removed := api.DeleteStaleMetrics(names); len(removed) > 0 {
+		logger.Info("Deleted Prometheus metrics of removed connections",
+			zap.Strings("connections", removed),
+		)
+	}
+}
+
+// reportConnections returns the names of the connections in the reports:
+// the enabled connections, primary first, and the aggregate results.
+func reportConnections(cfg *config.Config) []string {
+	var names []string
+	for _, conn := range cfg.GetConnectionsPrimaryFirst() {
+		if conn.Enabled {
+			names = append(names, conn.Name)
+		}
+	}
+	if cfg.Speedtest.Aggregate {
+		names = append(names, config.AggregateConnectionName)
+	}
+	return names
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// readOnlySignals toggle the server's read-only mode.
+var readOnlySignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package cmd
+
+import "os"
+
+// readOnlySignals toggle the server's read-only mode. Windows has no SIGUSR1,
+// so it can only be toggled via the API.
+var readOnlySignals []os.Signal
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+var statusJSON bool
+
+// statusPeriod is the window used for the averages and uptime.
+const statusPeriod = 24 * time.Hour
+
+// maxSkippedRuns bounds the search for the next run outside the skip windows.
+const maxSkippedRuns = 1000
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show a summary of all connections",
+	Long: `Print a dashboard-style summary of all configured connections: the latest
+result, the average speeds and uptime of the last 24 hours, and the next
+scheduled run computed from the scheduler configuration.
+
+Examples:
+  # Show the summary
+  flowgauge status
+
+  # Output the summary as JSON
+  flowgauge status --json`,
+	RunE: runStatus,
+}
+
+// connectionStatus is the summary of a single connection.
+type connectionStatus struct {
+	Name    string              `json:"name"`
+	Enabled bool                `json:"enabled"`
+	Latest  *storage.TestResult `json:"latest,omitempty"`
+	Stats   *storage.Stats      `json:"stats"`
+}
+
+// statusReport is the output of the status command.
+type statusReport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Scheduler   bool               `json:"scheduler_enabled"`
+	Schedule    string             `json:"schedule,omitempty"`
+	NextRun     *time.Time         `json:"next_run,omitempty"`
+	Connections []connectionStatus `json:"connections"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+	if len(cfg.Connections) == 0 {
+		return fmt.Errorf("no connections found in configuration")
+	}
+
+	// Initialize storage
+	store, err := storage.NewStorage(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create storage: %w", err)
+	}
+	if err := store.Init(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	loc := cfg.Location()
+	now := time.Now().In(loc)
+
+	report := statusReport{
+		GeneratedAt: now,
+		Scheduler:   cfg.Scheduler.Enabled,
+	}
+	if cfg.Scheduler.Enabled {
+		report.Schedule = cfg.Scheduler.Schedule
+		next, err := nextScheduledRun(&cfg.Scheduler, now)
+		if err != nil {
+			return err
+		}
+		if !next.IsZero() {
+			next = next.In(loc)
+			report.NextRun = &next
+		}
+	}
+
+	latest, err := store.GetLatestResults(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest results: %w", err)
+	}
+	latestByName := make(map[string]storage.TestResult, len(latest))
+	for _, r := range latest {
+		latestByName[r.ConnectionName] = r
+	}
+
+	for _, conn := range cfg.Connections {
+		stats, err := store.GetStats(ctx, conn.Name, statusPeriod)
+		if err != nil {
+			return fmt.Errorf("failed to get stats for %s: %w", conn.Name, err)
+		}
+		status := connectionStatus{
+			Name:    conn.Name,
+			Enabled: conn.Enabled,
+			Stats:   stats,
+		}
+		if r, ok := latestByName[conn.Name]; ok {
+			r.InLocation(loc)
+			status.Latest = &r
+		}
+		report.Connections = append(report.Connections, status)
+	}
+
+	if statusJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printStatus(&report, cfg, speedtest.SpeedUnit(cfg.General.SpeedUnit))
+	return nil
+}
+
+// nextScheduledRun returns the next run of the schedule after now that does
+// not fall into a skip window, or the zero time if none was found.
+func nextScheduledRun(cfg *config.SchedulerConfig, now time.Time) (time.Time, error) {
+	schedule, err := cfg.ParseSchedule()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule: %w", err)
+	}
+	windows, err := cfg.ParseSkipWindows()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := now
+	for i := 0; i < maxSkippedRuns; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			return next, nil
+		}
+		skipped := false
+		for _, window := range windows {
+			if window.Contains(next.In(now.Location())) {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			return next, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+func printStatus(report *statusReport, cfg *config.Config, unit speedtest.SpeedUnit) {
+	fmt.Println()
+	fmt.Println("FlowGauge Status")
+	fmt.Println("================")
+	fmt.Println()
+
+	switch {
+	case !report.Scheduler:
+		fmt.Println("Scheduler: disabled")
+	case report.NextRun == nil:
+		fmt.Printf("Scheduler: %s (no upcoming run)\n", report.Schedule)
+	default:
+		fmt.Printf("Scheduler: %s (next run %s", report.Schedule, report.NextRun.Format("2006-01-02 15:04:05"))
+		if cfg.Scheduler.Jitter > 0 {
+			fmt.Printf(" + up to %s jitter", cfg.Scheduler.Jitter)
+		}
+		fmt.Println(")")
+	}
+	fmt.Println()
+
+	fmt.Printf("%-20s | %11s | %14s | %14s | %-19s | %14s | %14s | %8s\n",
+		"Connection", "Latency", "Download", "Upload", "Last Test", "Avg Down (24h)", "Avg Up (24h)", "Uptime")
+	fmt.Println("---------------------+-------------+----------------+----------------+---------------------+----------------+----------------+---------")
+
+	for _, s := range report.Connections {
+		name := s.Name
+		if !s.Enabled {
+			name += " (off)"
+		}
+		name = truncate(name, 20)
+
+		latency, download, upload, lastTest := "-", "-", "-", "never"
+		if r := s.Latest; r != nil {
+			lastTest = r.CreatedAt.Format("2006-01-02 15:04:05")
+			if r.IsError() {
+				latency = "ERROR"
+			} else {
+				latency = fmt.Sprintf("%8.2f ms", r.LatencyMs)
+				download = fmt.Sprintf("%10.2f %s", unit.Convert(r.DownloadMbps), unit.Label())
+				upload = fmt.Sprintf("%10.2f %s", unit.Convert(r.UploadMbps), unit.Label())
+			}
+		}
+
+		avgDownload, avgUpload, uptime := "-", "-", "-"
+		if s.Stats != nil && s.Stats.TestCount > 0 {
+			avgDownload = fmt.Sprintf("%10.2f %s", unit.Convert(s.Stats.AvgDownload), unit.Label())
+			avgUpload = fmt.Sprintf("%10.2f %s", unit.Convert(s.Stats.AvgUpload), unit.Label())
+			uptime = fmt.Sprintf("%7.1f%%", s.Stats.Uptime)
+		}
+
+		fmt.Printf("%-20s | %11s | %14s | %14s | %-19s | %14s | %14s | %8s\n",
+			name, latency, download, upload, lastTest, avgDownload, avgUpload, uptime)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false,
+		"output the summary as JSON")
+}
@@ -6,10 +6,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
+	"github.com/lan-dot-party/flowgauge/internal/api"
+	"github.com/lan-dot-party/flowgauge/internal/config"
 	"github.com/lan-dot-party/flowgauge/internal/logger"
 	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 	"github.com/lan-dot-party/flowgauge/internal/storage"
@@ -18,8 +21,14 @@ import (
 var (
 	testConnection string
 	testOnce       bool
+	testLoop       bool
+	testInterval   time.Duration
 	testJSON       bool
+	testFormat     string
 	testNoSave     bool
+	testServers    []int
+	testPromFile   string
+	testCompare    bool
 )
 
 // testCmd represents the test command
@@ -29,20 +38,44 @@ var testCmd = &cobra.Command{
 	Long: `Run a speedtest for one or all configured connections.
 
 Examples:
-  # Test all enabled connections
+  # Test all enabled connections once
   flowgauge test
 
+  # Keep testing every 15 minutes in the foreground (until Ctrl-C)
+  flowgauge test --loop --interval 15m
+
   # Test a specific connection
   flowgauge test --connection WAN1
 
   # Output results as JSON
   flowgauge test --json
+
+  # Output results in Prometheus text format or InfluxDB line protocol
+  flowgauge test --format prometheus
+  flowgauge test --format influx --no-save | curl --data-binary @- "http://influxdb:8086/api/v2/write?bucket=net&precision=ns"
   
   # Run test without saving to database
-  flowgauge test --no-save`,
+  flowgauge test --no-save
+
+  # Show the change against the previous stored result of each connection
+  flowgauge test --compare-last
+
+  # Write metrics for the node_exporter textfile collector
+  flowgauge test --prom-file /var/lib/node_exporter/textfile/flowgauge.prom
+
+  # Compare a connection against several servers (IDs from speedtest.net)
+  flowgauge test --connection WAN1 --servers 1234,5678,9012 --no-save`,
 	RunE: runTest,
 }
 
+// Output formats of the test command.
+const (
+	testFormatTable      = "table"
+	testFormatJSON       = "json"
+	testFormatPrometheus = "prometheus"
+	testFormatInflux     = "influx"
+)
+
 func runTest(cmd *cobra.Command, args []string) error {
 	cfg := GetConfig()
 	if cfg == nil {
@@ -55,6 +88,32 @@ func runTest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no enabled connections found in configuration")
 	}
 
+	if len(testServers) > 0 && testConnection == "" {
+		return fmt.Errorf("--servers requires --connection")
+	}
+
+	if testOnce && testLoop {
+		return fmt.Errorf("--once and --loop cannot be used together")
+	}
+	if cmd.Flags().Changed("interval") && !testLoop {
+		return fmt.Errorf("--interval requires --loop")
+	}
+	if testInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	if testJSON {
+		if cmd.Flags().Changed("format") && testFormat != testFormatJSON {
+			return fmt.Errorf("--json cannot be used with --format %s", testFormat)
+		}
+		testFormat = testFormatJSON
+	}
+	switch testFormat {
+	case testFormatTable, testFormatJSON, testFormatPrometheus, testFormatInflux:
+	default:
+		return fmt.Errorf("invalid --format: %q (must be table, json, prometheus or influx)", testFormat)
+	}
+
 	// Filter to specific connection if requested
 	if testConnection != "" {
 		conn := cfg.GetConnectionByName(testConnection)
@@ -74,9 +133,9 @@ func runTest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create speedtest runner: %w", err)
 	}
 
-	// Initialize storage if saving results
+	// Initialize storage if saving, comparing or selecting servers by history
 	var store storage.Storage
-	if !testNoSave {
+	if !testNoSave || testCompare || cfg.Speedtest.ServerSelection == speedtest.ServerSelectionHistory {
 		store, err = storage.NewStorage(cfg.Storage)
 		if err != nil {
 			return fmt.Errorf("failed to create storage: %w", err)
@@ -85,6 +144,7 @@ func runTest(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 		defer func() { _ = store.Close() }()
+		runner.SetServerHistory(store.GetServerDownloads)
 	}
 
 	// Setup context with cancellation
@@ -100,25 +160,87 @@ func runTest(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	if !testLoop {
+		return runTestCycle(ctx, cfg, runner, store, len(connections))
+	}
+
+	// Repeat the tests, starting a cycle every interval (or immediately, if
+	// the previous one took longer)
+	for {
+		start := time.Now()
+		if err := runTestCycle(ctx, cfg, runner, store, len(connections)); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Error("Speedtest cycle failed", zap.Error(err))
+		}
+
+		next := start.Add(testInterval)
+		if testFormat == testFormatTable {
+			fmt.Printf("\nNext run at %s (Ctrl-C to stop)\n", next.In(cfg.Location()).Format("2006-01-02 15:04:05"))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// runTestCycle runs the tests once, saves the results and prints them.
+func runTestCycle(ctx context.Context, cfg *config.Config, runner *speedtest.MultiWANRunner, store storage.Storage, connectionCount int) error {
+	// Fetch the previous results before the new ones are saved
+	var previous map[string]storage.TestResult
+	if testCompare {
+		latest, err := store.GetLatestResults(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load previous results: %w", err)
+		}
+		previous = make(map[string]storage.TestResult, len(latest))
+		for _, result := range latest {
+			previous[result.ConnectionName] = result
+		}
+	}
+
 	// Print header
-	if !testJSON {
+	if testFormat == testFormatTable {
 		fmt.Println()
 		fmt.Println("FlowGauge Speedtest")
 		fmt.Println("===================")
-		fmt.Printf("Testing %d connection(s)...\n\n", len(connections))
+		if len(testServers) > 0 {
+			fmt.Printf("Testing %s against %d server(s)...\n\n", testConnection, len(testServers))
+		} else {
+			fmt.Printf("Testing %d connection(s)...\n\n", connectionCount)
+		}
 	}
 
 	// Run tests
-	logger.Info("Starting speedtests", zap.Int("connections", len(connections)))
-	results, err := runner.RunAll(ctx)
+	var results []speedtest.Result
+	var err error
+	if len(testServers) > 0 {
+		logger.Info("Starting server comparison",
+			zap.String("connection", testConnection),
+			zap.Ints("servers", testServers),
+		)
+		results, err = runner.CompareServers(ctx, testConnection, testServers)
+	} else {
+		logger.Info("Starting speedtests", zap.Int("connections", connectionCount))
+		results, err = runner.RunAll(ctx)
+	}
 	if err != nil {
 		return fmt.Errorf("speedtest failed: %w", err)
 	}
 
+	for i := range results {
+		results[i].Source = speedtest.SourceCLI
+		speedtest.LogCompleted(logger.Log, &results[i])
+	}
+
 	// Save results to storage
-	if store != nil {
+	if store != nil && !testNoSave {
 		for _, result := range results {
-			dbResult := storage.FromSpeedtestResult(&result)
+			dbResult := storage.FromSpeedtestResult(&result, cfg.General.RoundDecimals)
 			if err := store.SaveResult(ctx, dbResult); err != nil {
 				logger.Warn("Failed to save result", 
 					zap.String("connection", result.ConnectionName),
@@ -133,25 +255,65 @@ func runTest(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Write metrics for the node_exporter textfile collector
+	promFile := cfg.Speedtest.PromFile
+	if testPromFile != "" {
+		promFile = testPromFile
+	}
+	if promFile != "" {
+		api.SetMetricsConfig(cfg.Prometheus)
+		api.UpdateMetrics(results)
+		if err := api.WriteMetricsFile(promFile); err != nil {
+			return fmt.Errorf("failed to write metrics file: %w", err)
+		}
+		logger.Debug("Metrics written", zap.String("path", promFile))
+	}
+
 	// Output results
-	if testJSON {
+	switch testFormat {
+	case testFormatJSON:
 		fmt.Println(speedtest.Results(results).ToJSON())
-	} else {
-		fmt.Println(speedtest.Results(results).PrintTable())
+	case testFormatPrometheus:
+		if promFile == "" {
+			api.SetMetricsConfig(cfg.Prometheus)
+			api.UpdateMetrics(results)
+		}
+		if err := api.WriteMetrics(os.Stdout); err != nil {
+			return err
+		}
+	case testFormatInflux:
+		fmt.Println(speedtest.Results(results).ToInflux())
+	default:
+		unit := speedtest.SpeedUnit(cfg.General.SpeedUnit)
+		if len(testServers) > 0 {
+			fmt.Println(speedtest.Results(results).PrintServerComparison(unit))
+		} else {
+			fmt.Println(speedtest.Results(results).PrintTable(unit))
+		}
 		fmt.Println()
 
-		// Summary
-		rs := speedtest.Results(results)
-		fmt.Printf("Summary: %d/%d tests successful\n", rs.SuccessCount(), len(results))
+		if testCompare {
+			printComparison(results, previous, unit, cfg.Location())
+			fmt.Println()
+		}
+
+		// Summary (over the connections themselves, not the aggregate)
+		rs := make(speedtest.Results, 0, len(results))
+		for _, result := range results {
+			if !result.IsAggregate() {
+				rs = append(rs, result)
+			}
+		}
+		fmt.Printf("Summary: %d/%d tests successful\n", rs.SuccessCount(), len(rs))
 		if rs.SuccessCount() > 0 {
-			fmt.Printf("Average: ↓ %.2f Mbps | ↑ %.2f Mbps | %.2f ms\n",
-				rs.AverageDownload(),
-				rs.AverageUpload(),
+			fmt.Printf("Average: ↓ %.2f %s | ↑ %.2f %s | %.2f ms\n",
+				unit.Convert(rs.AverageDownload()), unit.Label(),
+				unit.Convert(rs.AverageUpload()), unit.Label(),
 				rs.AverageLatency(),
 			)
 		}
 		
-		if store != nil {
+		if store != nil && !testNoSave {
 			fmt.Printf("\n✅ Results saved to database\n")
 		}
 	}
@@ -165,9 +327,83 @@ func init() {
 	testCmd.Flags().StringVarP(&testConnection, "connection", "C", "",
 		"test only a specific connection by name")
 	testCmd.Flags().BoolVar(&testOnce, "once", false,
-		"run test once and exit (default behavior)")
+		"run test once and exit (default behavior, opposite of --loop)")
+	testCmd.Flags().BoolVar(&testLoop, "loop", false,
+		"keep running the tests every --interval in the foreground (until Ctrl-C)")
+	testCmd.Flags().DurationVar(&testInterval, "interval", 30*time.Minute,
+		"time between the starts of two test cycles with --loop")
 	testCmd.Flags().BoolVar(&testJSON, "json", false,
-		"output results as JSON")
+		"output results as JSON (same as --format json)")
+	testCmd.Flags().StringVar(&testFormat, "format", testFormatTable,
+		"output format: table, json, prometheus (text exposition format) or influx (line protocol)")
 	testCmd.Flags().BoolVar(&testNoSave, "no-save", false,
 		"don't save results to database")
+	testCmd.Flags().IntSliceVar(&testServers, "servers", nil,
+		"compare the connection against these server IDs (requires --connection)")
+	testCmd.Flags().StringVar(&testPromFile, "prom-file", "",
+		"write metrics to this file in Prometheus text format (overrides speedtest.prom_file)")
+	testCmd.Flags().BoolVar(&testCompare, "compare-last", false,
+		"show the change against the previous stored result of each connection")
+
+	registerConnectionCompletion(testCmd)
+}
+
+// printComparison prints each result next to its change against the previous
+// stored result of the same connection.
+func printComparison(results []speedtest.Result, previous map[string]storage.TestResult, unit speedtest.SpeedUnit, loc *time.Location) {
+	fmt.Println("Compared to previous results:")
+	for _, result := range results {
+		prev, ok := previous[result.ConnectionName]
+		switch {
+		case result.IsError():
+			fmt.Printf("  %-20s test failed\n", result.ConnectionName)
+		case !ok:
+			fmt.Printf("  %-20s no previous result\n", result.ConnectionName)
+		default:
+			prevOK := !prev.IsError()
+			fmt.Printf("  %-20s download %s | upload %s | latency %s  (vs. %s)\n",
+				result.ConnectionName,
+				formatSpeedChange(result.DownloadMbps, prev.DownloadMbps, result.DownloadOK, prevOK && prev.DownloadOK, unit),
+				formatSpeedChange(result.UploadMbps, prev.UploadMbps, result.UploadOK, prevOK && prev.UploadOK, unit),
+				formatLatencyChange(result.LatencyMs, prev.LatencyMs, result.LatencyOK, prevOK && prev.LatencyOK),
+				prev.CreatedAt.In(loc).Format("2006-01-02 15:04"),
+			)
+		}
+	}
+}
+
+// formatSpeedChange formats a speed with its change against the previous
+// value, e.g. "245.67 Mbps (↑ 12.30)". The change is omitted if the
+// previous value is unavailable.
+func formatSpeedChange(current, previous float64, ok, prevOK bool, unit speedtest.SpeedUnit) string {
+	if !ok {
+		return "n/a"
+	}
+	value := fmt.Sprintf("%.2f %s", unit.Convert(current), unit.Label())
+	if !prevOK {
+		return value
+	}
+
+	delta := unit.Convert(current - previous)
+	switch {
+	case delta >= 0.005:
+		return fmt.Sprintf("%s (↑ %.2f)", value, delta)
+	case delta <= -0.005:
+		return fmt.Sprintf("%s (↓ %.2f)", value, -delta)
+	default:
+		return value + " (±0)"
+	}
+}
+
+// formatLatencyChange formats a latency with its change against the previous
+// value, e.g. "12.50 ms (+2.00)".
+func formatLatencyChange(current, previous float64, ok, prevOK bool) string {
+	if !ok {
+		return "n/a"
+	}
+	value := fmt.Sprintf("%.2f ms", current)
+	if !prevOK {
+		return value
+	}
+	return fmt.Sprintf("%s (%+.2f)", value, current-previous)
 }
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// apiKeyRoutes are the endpoints that can be read with a connection API key
+// ("{...}" matches a single path segment). All other endpoints require the
+// admin credentials.
+var apiKeyRoutes = []string{
+	"/api/v1/results",
+	"/api/v1/results/latest",
+	"/api/v1/results/{id}",
+	"/api/v1/connections",
+	"/api/v1/connections/{name}/stats",
+	"/api/v1/connections/{name}/availability",
+	"/api/v1/connections/{name}/trends",
+	"/api/v1/connections/{name}/failures",
+	"/api/v1/connections/{name}/events",
+}
+
+// connectionScope is the set of connections a request authenticated with an
+// API key may read. A nil scope (admin or no auth) allows all connections.
+type connectionScope map[string]bool
+
+type scopeContextKey struct{}
+
+// requestScope returns the connection scope of a request.
+func requestScope(r *http.Request) connectionScope {
+	scope, _ := r.Context().Value(scopeContextKey{}).(connectionScope)
+	return scope
+}
+
+// withScope returns r with the given connection scope.
+func withScope(r *http.Request, scope connectionScope) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), scopeContextKey{}, scope))
+}
+
+// allows returns true if the scope includes the connection.
+func (sc connectionScope) allows(name string) bool {
+	return sc == nil || sc[name]
+}
+
+// names returns the connections of the scope, sorted.
+func (sc connectionScope) names() []string {
+	names := make([]string, 0, len(sc))
+	for name := range sc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restrict limits a result filter to the scope. Returns false if the
+// filter can't match any connection of the scope.
+func (sc connectionScope) restrict(filter *storage.ResultFilter) bool {
+	if sc == nil {
+		return true
+	}
+	if filter.ConnectionName != "" && !sc[filter.ConnectionName] {
+		return false
+	}
+
+	if len(filter.ConnectionNames) == 0 {
+		filter.ConnectionNames = sc.names()
+		return true
+	}
+	var allowed []string
+	for _, name := range filter.ConnectionNames {
+		if sc[name] {
+			allowed = append(allowed, name)
+		}
+	}
+	filter.ConnectionNames = allowed
+	return len(allowed) > 0
+}
+
+// filterResults returns the results of connections in the scope.
+func (sc connectionScope) filterResults(results []storage.TestResult) []storage.TestResult {
+	if sc == nil {
+		return results
+	}
+	filtered := results[:0]
+	for _, result := range results {
+		if sc[result.ConnectionName] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// requestAPIKey returns the API key of a request from the X-API-Key header
+// or a bearer token, or "" if there is none.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// keyScope returns the connections whose api_keys include key, or nil if
+// the key is unknown. All keys are compared, in constant time each.
+func keyScope(connections []config.ConnectionConfig, key string) connectionScope {
+	var scope connectionScope
+	for _, conn := range connections {
+		for _, k := range conn.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				if scope == nil {
+					scope = make(connectionScope)
+				}
+				scope[conn.Name] = true
+			}
+		}
+	}
+	return scope
+}
+
+// isAPIKeyRoute returns true if an API key may be used for the request.
+func isAPIKeyRoute(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	for _, pattern := range apiKeyRoutes {
+		if matchRoute(pattern, r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute returns true if path matches a route pattern, where "{...}"
+// segments match any single non-empty segment.
+func matchRoute(pattern, path string) bool {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// connectionScopeMiddleware responds with 404 Not Found to requests for a
+// connection ({name}) outside of the request's scope, so API keys can't
+// tell other connections from nonexistent ones.
+func (s *Server) connectionScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requestScope(r).allows(chi.URLParam(r, "name")) {
+			s.writeError(w, http.StatusNotFound, "Connection not found")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// embeddedAssets contains local copies of the dashboard's third-party assets
+// (see assets/README.md), served under /assets/ with webserver.embed_assets.
+//
+//go:embed assets
+var embeddedAssets embed.FS
+
+// requiredAssets are the files the dashboard loads from /assets/.
+var requiredAssets = []string{"htmx.min.js", "chart.umd.min.js", "fonts.css"}
+
+// assetsCacheControl lets browsers reuse the assets for a day; they only
+// change with the FlowGauge version.
+const assetsCacheControl = "public, max-age=86400"
+
+// missingAssets returns the required assets that are not embedded in this
+// build, e.g. because "make assets" wasn't run before building.
+func missingAssets() []string {
+	var missing []string
+	for _, name := range requiredAssets {
+		if _, err := fs.Stat(embeddedAssets, "assets/"+name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// assetsHandler serves the embedded assets under /assets/.
+func assetsHandler() http.Handler {
+	sub, _ := fs.Sub(embeddedAssets, "assets")
+	files := http.StripPrefix("/assets/", http.FileServer(http.FS(sub)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", assetsCacheControl)
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ttlCache is a small concurrency-safe cache with a time-to-live.
+// It is used to collapse repeated dashboard queries from many viewers
+// into a single storage query per interval.
+type ttlCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+// cacheEntry holds a cached value and its expiry time.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// newTTLCache creates a new cache. A ttl <= 0 disables caching.
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns the cached value for key if present and not expired.
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// set stores a value for key and removes expired entries, so keys that
+// aren't requested again don't pile up.
+func (c *ttlCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{
+		value:   value,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// invalidate removes all cached entries.
+func (c *ttlCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cacheEntry)
+}
+
+// setTTL changes the time-to-live and removes all cached entries, which
+// were stored with the previous one.
+func (c *ttlCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.entries = make(map[string]cacheEntry)
+}
+
+// docsCacheControl lets browsers reuse the API docs page for a day; it only
+// changes with the FlowGauge version.
+const docsCacheControl = "max-age=86400"
+
+// writeCached writes body with the given Cache-Control header and an ETag of
+// its hash. Conditional requests whose If-None-Match matches the ETag get a
+// 304 Not Modified without the body.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType, cacheControl string, body []byte) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+	// ServeContent evaluates If-None-Match against the ETag
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// chartCacheControl returns the Cache-Control header of the chart data: it
+// may be reused as long as the server caches it, and must be revalidated
+// afterwards.
+func chartCacheControl(ttl time.Duration) string {
+	if ttl < time.Second {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("private, max-age=%d", int(ttl.Seconds()))
+}
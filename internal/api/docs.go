@@ -8,15 +8,12 @@ import (
 
 // handleAPIDocs serves the API documentation page.
 func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	
 	html := `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>FlowGauge API Documentation (Read-Only)</title>
+    <title>FlowGauge API Documentation</title>
     <style>
         :root {
             --bg-primary: #0d1117;
@@ -243,6 +240,15 @@ func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
             overflow: auto;
         }
         
+        .base-url a {
+            color: var(--accent);
+            text-decoration: none;
+        }
+        
+        .loading {
+            color: var(--text-secondary);
+        }
+        
         .status-ok { color: var(--accent-green); }
         .status-error { color: var(--accent-red); }
         
@@ -272,180 +278,12 @@ func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
         <div class="base-url">
             <span>Base URL:</span>
             <code id="baseUrl"></code>
+            <span>OpenAPI:</span>
+            <code><a href="/api/openapi.json">/api/openapi.json</a></code>
         </div>
         
-        <div class="endpoint-group">
-            <h2>🏥 Health</h2>
-            
-            <div class="endpoint" data-method="GET" data-path="/health">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/health</span>
-                    <span class="description">Health check endpoint</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns the server health status and version.</p>
-                    <h4>Response</h4>
-                    <pre class="response-box"><code>{"status": "ok", "version": "1.0.0"}</code></pre>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/health')">Try it</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-        </div>
-        
-        <div class="endpoint-group">
-            <h2>📊 Results</h2>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/results">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/results</span>
-                    <span class="description">List speedtest results</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns a list of speedtest results with optional filtering.</p>
-                    <h4>Query Parameters</h4>
-                    <table class="params-table">
-                        <tr><th>Name</th><th>Type</th><th>Description</th></tr>
-                        <tr><td class="param-name">connection</td><td class="param-type">string</td><td>Filter by connection name</td></tr>
-                        <tr><td class="param-name">since</td><td class="param-type">string</td><td>Filter results since (RFC3339 or duration like "24h")</td></tr>
-                        <tr><td class="param-name">until</td><td class="param-type">string</td><td>Filter results until (RFC3339)</td></tr>
-                        <tr><td class="param-name">limit</td><td class="param-type">integer</td><td>Maximum results (default: 100)</td></tr>
-                        <tr><td class="param-name">offset</td><td class="param-type">integer</td><td>Offset for pagination</td></tr>
-                    </table>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/api/v1/results?limit=5')">Try it</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/results/latest">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/results/latest</span>
-                    <span class="description">Get latest result per connection</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns the most recent speedtest result for each configured connection.</p>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/api/v1/results/latest')">Try it</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/results/{id}">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/results/{id}</span>
-                    <span class="description">Get a specific result</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns a single speedtest result by ID.</p>
-                    <h4>Path Parameters</h4>
-                    <table class="params-table">
-                        <tr><th>Name</th><th>Type</th><th>Description</th></tr>
-                        <tr><td class="param-name">id</td><td class="param-type">integer</td><td>Result ID</td></tr>
-                    </table>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/api/v1/results/1')">Try it (ID: 1)</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-        </div>
-        
-        <div class="endpoint-group">
-            <h2>🔌 Connections</h2>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/connections">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/connections</span>
-                    <span class="description">List all connections</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns all configured network connections.</p>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/api/v1/connections')">Try it</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/connections/{name}/stats">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/connections/{name}/stats</span>
-                    <span class="description">Get connection statistics</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns aggregated statistics for a specific connection.</p>
-                    <h4>Path Parameters</h4>
-                    <table class="params-table">
-                        <tr><th>Name</th><th>Type</th><th>Description</th></tr>
-                        <tr><td class="param-name">name</td><td class="param-type">string</td><td>Connection name</td></tr>
-                    </table>
-                    <h4>Query Parameters</h4>
-                    <table class="params-table">
-                        <tr><th>Name</th><th>Type</th><th>Description</th></tr>
-                        <tr><td class="param-name">period</td><td class="param-type">string</td><td>Time period (e.g., "24h", "7d", "30d")</td></tr>
-                    </table>
-                    <div class="try-it">
-                        <button onclick="tryEndpoint('GET', '/api/v1/connections/WAN1-Primary/stats?period=24h')">Try it</button>
-                        <div class="response-box" style="display:none">
-                            <div class="response-header">Response <span class="status"></span></div>
-                            <pre class="response-body"></pre>
-                        </div>
-                    </div>
-                </div>
-            </div>
-        </div>
-        
-        <div class="endpoint-group">
-            <h2>📈 Metrics</h2>
-            
-            <div class="endpoint" data-method="GET" data-path="/api/v1/metrics">
-                <div class="endpoint-header" onclick="toggleEndpoint(this)">
-                    <span class="method get">GET</span>
-                    <span class="path">/api/v1/metrics</span>
-                    <span class="description">Prometheus metrics</span>
-                </div>
-                <div class="endpoint-details">
-                    <p>Returns Prometheus-formatted metrics for monitoring integration.</p>
-                    <h4>Available Metrics</h4>
-                    <table class="params-table">
-                        <tr><th>Metric</th><th>Type</th><th>Description</th></tr>
-                        <tr><td class="param-name">flowgauge_download_speed_mbps</td><td class="param-type">gauge</td><td>Download speed in Mbps</td></tr>
-                        <tr><td class="param-name">flowgauge_upload_speed_mbps</td><td class="param-type">gauge</td><td>Upload speed in Mbps</td></tr>
-                        <tr><td class="param-name">flowgauge_latency_ms</td><td class="param-type">gauge</td><td>Latency in milliseconds</td></tr>
-                        <tr><td class="param-name">flowgauge_jitter_ms</td><td class="param-type">gauge</td><td>Jitter in milliseconds</td></tr>
-                        <tr><td class="param-name">flowgauge_tests_total</td><td class="param-type">counter</td><td>Total tests run</td></tr>
-                        <tr><td class="param-name">flowgauge_test_errors_total</td><td class="param-type">counter</td><td>Total test errors</td></tr>
-                    </table>
-                    <div class="try-it">
-                        <button onclick="window.open('/api/v1/metrics', '_blank')">Open Metrics</button>
-                    </div>
-                </div>
-            </div>
+        <div id="endpoints">
+            <p class="loading">Loading API specification...</p>
         </div>
     </div>
     
@@ -458,12 +296,96 @@ func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
         // Set base URL
         document.getElementById('baseUrl').textContent = window.location.origin;
         
-        function toggleEndpoint(header) {
-            header.parentElement.classList.toggle('open');
+        function escapeHTML(value) {
+            return String(value).replace(/[&<>"']/g, c => ({
+                '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+            }[c]));
         }
         
-        async function tryEndpoint(method, path) {
-            const button = event.target;
+        function paramsTable(title, params) {
+            if (params.length === 0) return '';
+            let html = '<h4>' + title + '</h4><table class="params-table">' +
+                '<tr><th>Name</th><th>Type</th><th>Description</th></tr>';
+            for (const p of params) {
+                html += '<tr><td class="param-name">' + escapeHTML(p.name) + '</td>' +
+                    '<td class="param-type">' + escapeHTML(p.schema.type) + '</td>' +
+                    '<td>' + escapeHTML(p.description || '') + '</td></tr>';
+            }
+            return html + '</table>';
+        }
+        
+        // Builds an example request from the parameter examples, or returns
+        // null if a path parameter has no example
+        function examplePath(path, params) {
+            const query = new URLSearchParams();
+            for (const p of params) {
+                if (p.in === 'path') {
+                    if (p.example === undefined) return null;
+                    path = path.replace('{' + p.name + '}', encodeURIComponent(p.example));
+                } else if (p.example !== undefined) {
+                    query.set(p.name, p.example);
+                }
+            }
+            const qs = query.toString();
+            return qs ? path + '?' + qs : path;
+        }
+        
+        function renderEndpoint(method, path, op) {
+            const params = op.parameters || [];
+            const example = method === 'get' ? examplePath(path, params) : null;
+            
+            const el = document.createElement('div');
+            el.className = 'endpoint';
+            let html = '<div class="endpoint-header">' +
+                '<span class="method ' + method + '">' + method.toUpperCase() + '</span>' +
+                '<span class="path">' + escapeHTML(path) + '</span>' +
+                '<span class="description">' + escapeHTML(op.summary) + '</span></div>' +
+                '<div class="endpoint-details"><p>' + escapeHTML(op.description) + '</p>' +
+                paramsTable('Path Parameters', params.filter(p => p.in === 'path')) +
+                paramsTable('Query Parameters', params.filter(p => p.in === 'query'));
+            if (example) {
+                html += '<div class="try-it"><button>Try it</button>' +
+                    '<div class="response-box" style="display:none">' +
+                    '<div class="response-header">Response <span class="status"></span></div>' +
+                    '<pre class="response-body"></pre></div></div>';
+            }
+            el.innerHTML = html + '</div>';
+            
+            el.querySelector('.endpoint-header').addEventListener('click', () => el.classList.toggle('open'));
+            if (example) {
+                el.querySelector('.try-it button').addEventListener('click', e => tryEndpoint('GET', example, e.target));
+            }
+            return el;
+        }
+        
+        // Render the endpoint list from the OpenAPI document
+        async function loadSpec() {
+            const container = document.getElementById('endpoints');
+            try {
+                const response = await fetch('/api/openapi.json');
+                if (!response.ok) throw new Error(response.status + ' ' + response.statusText);
+                const spec = await response.json();
+                
+                container.innerHTML = '';
+                for (const tag of spec.tags) {
+                    const group = document.createElement('div');
+                    group.className = 'endpoint-group';
+                    group.innerHTML = '<h2>' + escapeHTML((tag['x-icon'] ? tag['x-icon'] + ' ' : '') + tag.name) + '</h2>';
+                    for (const [path, item] of Object.entries(spec.paths)) {
+                        for (const [method, op] of Object.entries(item)) {
+                            if (op.tags.includes(tag.name)) {
+                                group.appendChild(renderEndpoint(method, path, op));
+                            }
+                        }
+                    }
+                    container.appendChild(group);
+                }
+            } catch (err) {
+                container.innerHTML = '<p class="status-error">Failed to load API specification: ' + escapeHTML(err.message) + '</p>';
+            }
+        }
+        
+        async function tryEndpoint(method, path, button) {
             const responseBox = button.parentElement.querySelector('.response-box');
             const statusEl = responseBox.querySelector('.status');
             const bodyEl = responseBox.querySelector('.response-body');
@@ -491,11 +413,13 @@ func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
                 bodyEl.textContent = err.message;
             }
         }
+        
+        loadSpec();
     </script>
 </body>
 </html>`
 
-	_, _ = w.Write([]byte(html))
+	writeCached(w, r, "text/html; charset=utf-8", docsCacheControl, []byte(html))
 }
 
 // handleAPIRedirect redirects /api to the docs.
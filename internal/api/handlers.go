@@ -8,44 +8,109 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 	"github.com/lan-dot-party/flowgauge/internal/storage"
 	"github.com/lan-dot-party/flowgauge/pkg/version"
 )
 
 // Response helpers
 
-type errorResponse struct {
-	Error   string `json:"error"`
-	Code    int    `json:"code"`
+// APIResponse is the envelope of all JSON API responses except /health.
+// Successful responses have status "ok" and the payload in Data; list
+// responses with pagination also set Meta. Failed requests have status
+// "error" and set Error instead of Data.
+type APIResponse[T any] struct {
+	Status  string        `json:"status"`
+	Data    T             `json:"data,omitzero"`
+	Meta    *responseMeta `json:"meta,omitempty"`
+	Error   *apiError     `json:"error,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+// responseMeta contains the pagination details of list responses.
+type responseMeta struct {
+	Total        int  `json:"total"`
+	Limit        int  `json:"limit"`
+	Offset       int  `json:"offset"`
+	LimitClamped bool `json:"limit_clamped,omitempty"`
+	// NextCursor is the before_id of the next page in cursor mode
+	// (omitted on the last page)
+	NextCursor *int64 `json:"next_cursor,omitempty"`
+}
+
+// apiError describes why a request failed.
+type apiError struct {
+	Code int `json:"code"`
+	// Reason is the HTTP status text, e.g. "Not Found"
+	Reason  string `json:"reason"`
 	Message string `json:"message,omitempty"`
 }
 
-type successResponse struct {
-	Status  string      `json:"status"`
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
+// okResponse wraps data in a successful response.
+func okResponse[T any](data T) APIResponse[T] {
+	return APIResponse[T]{Status: "ok", Data: data}
 }
 
 type healthResponse struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
+	// ReadOnly is true while the server is in read-only (maintenance) mode
+	ReadOnly bool `json:"read_only"`
 }
 
-type resultsResponse struct {
-	Results []storage.TestResult `json:"results"`
-	Meta    struct {
-		Total   int `json:"total"`
-		Limit   int `json:"limit"`
-		Offset  int `json:"offset"`
-	} `json:"meta"`
+type readOnlyResponse struct {
+	ReadOnly bool `json:"read_only"`
 }
 
 type connectionResponse struct {
-	Name     string `json:"name"`
-	SourceIP string `json:"source_ip,omitempty"`
-	DSCP     int    `json:"dscp"`
-	Enabled  bool   `json:"enabled"`
+	Name      string `json:"name"`
+	SourceIP  string `json:"source_ip,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	DSCP      int    `json:"dscp"`
+	// DSCPName is the common name of the DSCP value (empty for unnamed values)
+	DSCPName  string `json:"dscp_name,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	Group     string `json:"group,omitempty"`
+	DualStack bool   `json:"dual_stack,omitempty"`
+	Primary   bool   `json:"primary,omitempty"`
+	// ConfigHash is the hash stored with results produced under the current
+	// configuration, usable as the config_hash filter of /results
+	ConfigHash string `json:"config_hash"`
+}
+
+type groupResponse struct {
+	Name        string   `json:"name"`
+	Connections []string `json:"connections"`
+}
+
+type groupStatsResponse struct {
+	Group       string           `json:"group"`
+	Connections []*storage.Stats `json:"connections"`
+	*storage.Stats
+}
+
+type availabilityResponse struct {
+	ConnectionName string        `json:"connection_name"`
+	Availability   float64       `json:"availability"`
+	Uptime         float64       `json:"uptime_percent"`
+	TestCount      int           `json:"test_count"`
+	ErrorCount     int           `json:"error_count"`
+	Period         time.Duration `json:"period"`
+	Since          time.Time     `json:"since"`
+	Until          time.Time     `json:"until"`
+}
+
+type trendsResponse struct {
+	ConnectionName string                `json:"connection_name"`
+	GroupBy        string                `json:"group_by"`
+	Timezone       string                `json:"timezone"`
+	Period         time.Duration         `json:"period"`
+	Since          time.Time             `json:"since"`
+	Until          time.Time             `json:"until"`
+	Buckets        []storage.TrendBucket `json:"buckets"`
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -57,20 +122,58 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{})
 }
 
 func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	s.writeJSON(w, status, errorResponse{
-		Error:   http.StatusText(status),
-		Code:    status,
-		Message: message,
+	s.writeJSON(w, status, APIResponse[any]{
+		Status: "error",
+		Error: &apiError{
+			Code:    status,
+			Reason:  http.StatusText(status),
+			Message: message,
+		},
 	})
 }
 
 // Handlers
 
+// timeParam parses the time of a since or until query parameter with
+// config.ParseTimeOrDuration, taking dates in the configured timezone.
+// Returns the zero time if the parameter is absent.
+func (s *Server) timeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return config.ParseTimeOrDuration(v, s.currentConfig().Location())
+}
+
+// localizeResults converts result timestamps to the configured timezone.
+func (s *Server) localizeResults(results []storage.TestResult) {
+	loc := s.currentConfig().Location()
+	for i := range results {
+		results[i].InLocation(loc)
+	}
+}
+
 // handleHealth returns the server health status.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, healthResponse{
-		Status:  "ok",
-		Version: version.GetShortVersion(),
+		Status:   "ok",
+		Version:  version.GetShortVersion(),
+		ReadOnly: s.ReadOnly(),
+	})
+}
+
+// handleHealthReady reports whether the server accepts new tests. It returns
+// 503 while read-only mode is enabled, so load balancers and probes can take
+// the instance out of rotation during maintenance.
+func (s *Server) handleHealthReady(w http.ResponseWriter, r *http.Request) {
+	status, code := "ok", http.StatusOK
+	if s.ReadOnly() {
+		status, code = "read_only", http.StatusServiceUnavailable
+	}
+	s.writeJSON(w, code, healthResponse{
+		Status:   status,
+		Version:  version.GetShortVersion(),
+		ReadOnly: code != http.StatusOK,
 	})
 }
 
@@ -83,64 +186,170 @@ func (s *Server) handleGetResults(w http.ResponseWriter, r *http.Request) {
 		filter.ConnectionName = conn
 	}
 
-	if since := r.URL.Query().Get("since"); since != "" {
-		if t, err := time.Parse(time.RFC3339, since); err == nil {
-			filter.Since = t
-		} else if d, err := time.ParseDuration(since); err == nil {
-			filter.Since = time.Now().Add(-d)
+	// Map group to its connection names (groups only exist in the config)
+	if group := r.URL.Query().Get("group"); group != "" {
+		for _, conn := range s.currentConfig().GetConnectionsByGroup(group) {
+			filter.ConnectionNames = append(filter.ConnectionNames, conn.Name)
+		}
+		if len(filter.ConnectionNames) == 0 {
+			s.writeError(w, http.StatusNotFound, "Group not found")
+			return
 		}
 	}
 
-	if until := r.URL.Query().Get("until"); until != "" {
-		if t, err := time.Parse(time.RFC3339, until); err == nil {
-			filter.Until = t
+	var err error
+	if filter.Since, err = s.timeParam(r, "since"); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid since: "+err.Error())
+		return
+	}
+	if filter.Until, err = s.timeParam(r, "until"); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid until: "+err.Error())
+		return
+	}
+
+	if e := r.URL.Query().Get("error"); e != "" {
+		failed, err := strconv.ParseBool(e)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid error filter (must be true or false)")
+			return
+		}
+		filter.ErrorOnly = &failed
+	}
+
+	if hash := r.URL.Query().Get("config_hash"); hash != "" {
+		filter.ConfigHash = hash
+	}
+
+	if source := r.URL.Query().Get("source"); source != "" {
+		if !speedtest.IsValidSource(source) {
+			s.writeError(w, http.StatusBadRequest, "Invalid source (must be scheduled, api or cli)")
+			return
 		}
+		filter.Source = source
 	}
 
 	if limit := r.URL.Query().Get("limit"); limit != "" {
 		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
 			filter.Limit = l
 		}
-	} else {
+	}
+	if filter.Limit <= 0 {
 		filter.Limit = 100 // Default limit
 	}
 
+	// Enforce the configured maximum
+	limitClamped := false
+	if maxLimit := s.currentConfig().Webserver.MaxResultsLimit; maxLimit > 0 && filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+		limitClamped = true
+	}
+
 	if offset := r.URL.Query().Get("offset"); offset != "" {
 		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
 			filter.Offset = o
 		}
 	}
 
-	results, err := s.storage.GetResults(r.Context(), filter)
+	// Keyset pagination: stable across pages while new results are inserted
+	var beforeID int64
+	cursorMode := r.URL.Query().Has("before_id")
+	if cursorMode {
+		id, err := strconv.ParseInt(r.URL.Query().Get("before_id"), 10, 64)
+		if err != nil || id < 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid before_id")
+			return
+		}
+		if filter.Offset > 0 {
+			s.writeError(w, http.StatusBadRequest, "before_id cannot be combined with offset")
+			return
+		}
+		beforeID = id
+	}
+
+	var results []storage.TestResult
+	switch {
+	case !requestScope(r).restrict(&filter):
+		// None of the requested connections may be read with the API key
+	case cursorMode:
+		results, err = s.storage.GetResultsCursor(r.Context(), filter, beforeID)
+	default:
+		results, err = s.storage.GetResults(r.Context(), filter)
+	}
 	if err != nil {
 		s.logger.Error("Failed to get results", zap.Error(err))
 		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve results")
 		return
 	}
 
-	response := resultsResponse{
-		Results: results,
+	if results == nil {
+		results = []storage.TestResult{}
+	}
+	s.localizeResults(results)
+
+	response := okResponse(results)
+	response.Meta = &responseMeta{
+		Total:        len(results),
+		Limit:        filter.Limit,
+		Offset:       filter.Offset,
+		LimitClamped: limitClamped,
+	}
+	if cursorMode && len(results) == filter.Limit {
+		next := results[len(results)-1].ID
+		response.Meta.NextCursor = &next
 	}
-	response.Meta.Total = len(results)
-	response.Meta.Limit = filter.Limit
-	response.Meta.Offset = filter.Offset
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-// handleGetLatestResults returns the most recent result for each connection.
+// handleGetLatestResults returns the most recent result for each connection,
+// or the single most recent result overall when global=true.
 func (s *Server) handleGetLatestResults(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("global") == "true" {
+		s.handleGetLatestResultGlobal(w, r)
+		return
+	}
+
 	results, err := s.storage.GetLatestResults(r.Context())
 	if err != nil {
 		s.logger.Error("Failed to get latest results", zap.Error(err))
 		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve latest results")
 		return
 	}
+	results = requestScope(r).filterResults(results)
+	if results == nil {
+		results = []storage.TestResult{}
+	}
+	s.localizeResults(results)
 
-	s.writeJSON(w, http.StatusOK, successResponse{
-		Status: "ok",
-		Data:   results,
-	})
+	s.writeJSON(w, http.StatusOK, okResponse(results))
+}
+
+// handleGetLatestResultGlobal returns the single most recent result across all connections.
+func (s *Server) handleGetLatestResultGlobal(w http.ResponseWriter, r *http.Request) {
+	var result *storage.TestResult
+	var err error
+	if scope := requestScope(r); scope != nil {
+		// The most recent result of the API key's connections
+		var results []storage.TestResult
+		results, err = s.storage.GetResults(r.Context(), storage.ResultFilter{ConnectionNames: scope.names(), Limit: 1})
+		if len(results) > 0 {
+			result = &results[0]
+		}
+	} else {
+		result, err = s.storage.GetLatestResult(r.Context())
+	}
+	if err != nil {
+		s.logger.Error("Failed to get latest result", zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve latest result")
+		return
+	}
+	if result == nil {
+		s.writeError(w, http.StatusNotFound, "No results found")
+		return
+	}
+	result.InLocation(s.currentConfig().Location())
+
+	s.writeJSON(w, http.StatusOK, okResponse(result))
 }
 
 // handleGetResult returns a single result by ID.
@@ -153,33 +362,45 @@ func (s *Server) handleGetResult(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := s.storage.GetResult(r.Context(), id)
-	if err != nil {
+	if err != nil || !requestScope(r).allows(result.ConnectionName) {
 		s.writeError(w, http.StatusNotFound, "Result not found")
 		return
 	}
+	result.InLocation(s.currentConfig().Location())
 
-	s.writeJSON(w, http.StatusOK, successResponse{
-		Status: "ok",
-		Data:   result,
-	})
+	s.writeJSON(w, http.StatusOK, okResponse(result))
 }
 
 // handleGetConnections returns all configured connections.
 func (s *Server) handleGetConnections(w http.ResponseWriter, r *http.Request) {
-	connections := make([]connectionResponse, 0, len(s.fullConfig.Connections))
-	for _, conn := range s.fullConfig.Connections {
+	cfg := s.currentConfig()
+	scope := requestScope(r)
+	connections := make([]connectionResponse, 0, len(cfg.Connections))
+	for _, conn := range cfg.GetConnectionsPrimaryFirst() {
+		if !scope.allows(conn.Name) {
+			continue
+		}
 		connections = append(connections, connectionResponse{
-			Name:     conn.Name,
-			SourceIP: conn.SourceIP,
-			DSCP:     conn.DSCP,
-			Enabled:  conn.Enabled,
+			Name:      conn.Name,
+			SourceIP:  conn.SourceIP,
+			Interface: conn.Interface,
+			DSCP:      conn.DSCP,
+			DSCPName:  config.DSCPNames[conn.DSCP],
+			Enabled:   conn.Enabled,
+			Group:     conn.Group,
+			DualStack: conn.DualStack,
+			Primary:   conn.Primary,
+
+			ConfigHash: config.ConnectionHash(conn, cfg.Speedtest),
 		})
 	}
 
-	s.writeJSON(w, http.StatusOK, successResponse{
-		Status: "ok",
-		Data:   connections,
-	})
+	s.writeJSON(w, http.StatusOK, okResponse(connections))
+}
+
+// handleGetDSCPNames returns the common names of DSCP values, keyed by value.
+func (s *Server) handleGetDSCPNames(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, okResponse(config.DSCPNames))
 }
 
 // handleGetConnectionStats returns statistics for a specific connection.
@@ -190,12 +411,15 @@ func (s *Server) handleGetConnectionStats(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Parse period (default 24h)
+	// Parse period (default 24h), accepting days and weeks
 	period := 24 * time.Hour
 	if p := r.URL.Query().Get("period"); p != "" {
-		if d, err := time.ParseDuration(p); err == nil {
-			period = d
+		d, err := config.ParseDuration(p)
+		if err != nil || d == 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid period")
+			return
 		}
+		period = d
 	}
 
 	stats, err := s.storage.GetStats(r.Context(), name, period)
@@ -204,10 +428,319 @@ func (s *Server) handleGetConnectionStats(w http.ResponseWriter, r *http.Request
 		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve statistics")
 		return
 	}
+	stats.InLocation(s.currentConfig().Location())
 
-	s.writeJSON(w, http.StatusOK, successResponse{
-		Status: "ok",
-		Data:   stats,
-	})
+	s.writeJSON(w, http.StatusOK, okResponse(stats))
+}
+
+// handleGetConnectionAvailability returns the share of successful tests for a connection.
+func (s *Server) handleGetConnectionAvailability(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "Connection name required")
+		return
+	}
+
+	// Parse period (default 30d), accepting days for SLA-style windows
+	period := availabilityPeriod
+	if p := r.URL.Query().Get("period"); p != "" {
+		d, err := config.ParseDuration(p)
+		if err != nil || d == 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid period")
+			return
+		}
+		period = d
+	}
+
+	stats, err := s.storage.GetStats(r.Context(), name, period)
+	if err != nil {
+		s.logger.Error("Failed to get stats", zap.String("connection", name), zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve availability")
+		return
+	}
+	stats.InLocation(s.currentConfig().Location())
+
+	s.writeJSON(w, http.StatusOK, okResponse(availabilityResponse{
+		ConnectionName: stats.ConnectionName,
+		Availability:   stats.Availability,
+		Uptime:         stats.Uptime,
+		TestCount:      stats.TestCount,
+		ErrorCount:     stats.ErrorCount,
+		Period:         stats.Period,
+		Since:          stats.Since,
+		Until:          stats.Until,
+	}))
+}
+
+// trendsPeriod is the default period of the trends endpoint.
+const trendsPeriod = 30 * 24 * time.Hour
+
+// handleGetConnectionTrends returns a connection's statistics bucketed by hour
+// of day or day of week, e.g. to reveal recurring peak-hour congestion.
+func (s *Server) handleGetConnectionTrends(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "Connection name required")
+		return
+	}
+
+	groupBy := storage.TrendHourOfDay
+	if g := r.URL.Query().Get("group_by"); g != "" {
+		if !storage.IsValidTrendGrouping(g) {
+			s.writeError(w, http.StatusBadRequest, "Invalid group_by (must be hour_of_day or day_of_week)")
+			return
+		}
+		groupBy = g
+	}
+
+	// Parse period (default 30d), accepting days
+	period := trendsPeriod
+	if p := r.URL.Query().Get("period"); p != "" {
+		d, err := config.ParseDuration(p)
+		if err != nil || d == 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid period")
+			return
+		}
+		period = d
+	}
+
+	loc := s.currentConfig().Location()
+	until := time.Now().In(loc)
+	buckets, err := s.storage.GetTrends(r.Context(), name, groupBy, period, loc)
+	if err != nil {
+		s.logger.Error("Failed to get trends", zap.String("connection", name), zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve trends")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, okResponse(trendsResponse{
+		ConnectionName: name,
+		GroupBy:        groupBy,
+		Timezone:       loc.String(),
+		Period:         period,
+		Since:          until.Add(-period),
+		Until:          until,
+		Buckets:        buckets,
+	}))
 }
 
+// failuresLimit is the default number of results of the failures endpoint.
+const failuresLimit = 20
+
+// handleGetConnectionFailures returns the most recent failed tests of a
+// connection, to diagnose intermittent problems.
+func (s *Server) handleGetConnectionFailures(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "Connection name required")
+		return
+	}
+
+	failed := true
+	filter := storage.ResultFilter{
+		ConnectionName: name,
+		ErrorOnly:      &failed,
+		Limit:          failuresLimit,
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = l
+	}
+
+	// Enforce the configured maximum
+	limitClamped := false
+	if maxLimit := s.currentConfig().Webserver.MaxResultsLimit; maxLimit > 0 && filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+		limitClamped = true
+	}
+
+	results, err := s.storage.GetResults(r.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to get failures", zap.String("connection", name), zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve failures")
+		return
+	}
+	if results == nil {
+		results = []storage.TestResult{}
+	}
+	s.localizeResults(results)
+
+	response := okResponse(results)
+	response.Meta = &responseMeta{
+		Total:        len(results),
+		Limit:        filter.Limit,
+		LimitClamped: limitClamped,
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// eventsLimit is the default number of events of the events endpoint.
+const eventsLimit = 50
+
+// handleGetConnectionEvents returns the health state changes of a
+// connection, newest first, as an outage timeline.
+func (s *Server) handleGetConnectionEvents(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "Connection name required")
+		return
+	}
+
+	limit := eventsLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	// Enforce the configured maximum
+	limitClamped := false
+	if maxLimit := s.currentConfig().Webserver.MaxResultsLimit; maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+		limitClamped = true
+	}
+
+	since, err := s.timeParam(r, "since")
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid since: "+err.Error())
+		return
+	}
+
+	events, err := s.storage.GetConnectionEvents(r.Context(), name, since, limit)
+	if err != nil {
+		s.logger.Error("Failed to get connection events", zap.String("connection", name), zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve connection events")
+		return
+	}
+	loc := s.currentConfig().Location()
+	for i := range events {
+		events[i].InLocation(loc)
+	}
+
+	response := okResponse(events)
+	response.Meta = &responseMeta{
+		Total:        len(events),
+		Limit:        limit,
+		LimitClamped: limitClamped,
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetGroups returns all connection groups and their members.
+func (s *Server) handleGetGroups(w http.ResponseWriter, r *http.Request) {
+	cfg := s.currentConfig()
+	groups := make([]groupResponse, 0)
+	for _, group := range cfg.GetGroups() {
+		resp := groupResponse{Name: group}
+		for _, conn := range cfg.GetConnectionsByGroup(group) {
+			resp.Connections = append(resp.Connections, conn.Name)
+		}
+		groups = append(groups, resp)
+	}
+
+	s.writeJSON(w, http.StatusOK, okResponse(groups))
+}
+
+// handleGetGroupStats returns statistics aggregated across all connections in a group.
+func (s *Server) handleGetGroupStats(w http.ResponseWriter, r *http.Request) {
+	group := chi.URLParam(r, "group")
+	connections := s.currentConfig().GetConnectionsByGroup(group)
+	if len(connections) == 0 {
+		s.writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	// Parse period (default 24h), accepting days and weeks
+	period := 24 * time.Hour
+	if p := r.URL.Query().Get("period"); p != "" {
+		d, err := config.ParseDuration(p)
+		if err != nil || d == 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid period")
+			return
+		}
+		period = d
+	}
+
+	loc := s.currentConfig().Location()
+	perConnection := make([]*storage.Stats, 0, len(connections))
+	for _, conn := range connections {
+		stats, err := s.storage.GetStats(r.Context(), conn.Name, period)
+		if err != nil {
+			s.logger.Error("Failed to get stats", zap.String("connection", conn.Name), zap.Error(err))
+			s.writeError(w, http.StatusInternalServerError, "Failed to retrieve statistics")
+			return
+		}
+		stats.InLocation(loc)
+		perConnection = append(perConnection, stats)
+	}
+
+	s.writeJSON(w, http.StatusOK, okResponse(groupStatsResponse{
+		Group:       group,
+		Connections: perConnection,
+		Stats:       storage.CombineStats(group, perConnection),
+	}))
+}
+
+// handleGetConfig returns the effective configuration with secrets redacted.
+// Keys match the YAML configuration file. Only available when authentication
+// is enabled, so the configuration is never exposed anonymously.
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := s.currentConfig()
+	if cfg.Webserver.Auth == nil || cfg.Webserver.Auth.Username == "" {
+		s.writeError(w, http.StatusForbidden, "Config endpoint requires webserver authentication to be enabled")
+		return
+	}
+
+	// Round-trip through YAML so the JSON uses the config file's keys and
+	// durations are rendered as strings (e.g. "1m0s")
+	data, err := yaml.Marshal(cfg.Sanitize())
+	if err != nil {
+		s.logger.Error("Failed to marshal config", zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve configuration")
+		return
+	}
+	var sanitized map[string]interface{}
+	if err := yaml.Unmarshal(data, &sanitized); err != nil {
+		s.logger.Error("Failed to convert config", zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve configuration")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, okResponse(sanitized))
+}
+
+// handleGetReadOnly returns whether read-only mode is enabled.
+func (s *Server) handleGetReadOnly(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, okResponse(&readOnlyResponse{ReadOnly: s.ReadOnly()}))
+}
+
+// handleSetReadOnly enables or disables read-only mode. Only available when
+// authentication is enabled, so maintenance can't be toggled anonymously.
+func (s *Server) handleSetReadOnly(w http.ResponseWriter, r *http.Request) {
+	cfg := s.currentConfig()
+	if cfg.Webserver.Auth == nil || cfg.Webserver.Auth.Username == "" {
+		s.writeError(w, http.StatusForbidden, "Read-only endpoint requires webserver authentication to be enabled")
+		return
+	}
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid enabled parameter (must be true or false)")
+		return
+	}
+
+	s.SetReadOnly(enabled)
+	s.logger.Info("Read-only mode changed via API",
+		zap.Bool("read_only", enabled),
+		zap.String("remote", r.RemoteAddr),
+	)
+
+	s.writeJSON(w, http.StatusOK, okResponse(&readOnlyResponse{ReadOnly: s.ReadOnly()}))
+}
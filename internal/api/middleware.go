@@ -3,6 +3,8 @@ package api
 import (
 	"crypto/subtle"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -31,11 +33,85 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// basicAuthMiddleware implements HTTP Basic Authentication.
-func (s *Server) basicAuthMiddleware(next http.Handler) http.Handler {
+// redirectMiddleware redirects requests for another host than
+// webserver.canonical_host, and plaintext requests if webserver.force_https
+// is enabled, to the canonical URL. The health endpoints are exempt, so probes
+// by IP address keep working. Checked per request so the settings can change
+// on config reload.
+func (s *Server) redirectMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for health endpoint
-		if r.URL.Path == "/health" {
+		cfg := s.currentConfig().Webserver
+		if isHealthPath(r.URL.Path) || (cfg.CanonicalHost == "" && !cfg.ForceHTTPS) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, host := requestScheme(r), r.Host
+		target := url.URL{Scheme: scheme, Host: host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+		if cfg.ForceHTTPS {
+			target.Scheme = "https"
+		}
+		if cfg.CanonicalHost != "" {
+			target.Host = cfg.CanonicalHost
+		}
+		if target.Scheme == scheme && strings.EqualFold(target.Host, host) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// 301 turns other methods into GET in most clients, 308 keeps them
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, target.String(), status)
+	})
+}
+
+// requestScheme returns "https" if the request was made over TLS, directly
+// or to a reverse proxy that sets X-Forwarded-Proto, and "http" otherwise.
+// Of chained proxies, the first (client-facing) one counts.
+func requestScheme(r *http.Request) string {
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	if r.TLS != nil || strings.EqualFold(strings.TrimSpace(proto), "https") {
+		return "https"
+	}
+	return "http"
+}
+
+// authMiddleware implements HTTP Basic Authentication, and authentication
+// with connection API keys, which limit the request to the key's connections
+// and the read endpoints in apiKeyRoutes.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.currentConfig()
+
+		if key := requestAPIKey(r); key != "" {
+			scope := keyScope(cfg.Connections, key)
+			if scope == nil {
+				s.logger.Warn("Authentication failed: unknown API key",
+					zap.String("remote", r.RemoteAddr),
+				)
+				s.unauthorized(w)
+				return
+			}
+			if !isAPIKeyRoute(r) {
+				s.writeError(w, http.StatusForbidden, "API keys can't access this endpoint")
+				return
+			}
+			next.ServeHTTP(w, withScope(r, scope))
+			return
+		}
+
+		// Skip auth if not configured
+		auth := cfg.Webserver.Auth
+		if auth == nil || auth.Username == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Skip auth for health endpoints
+		if isHealthPath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -47,8 +123,8 @@ func (s *Server) basicAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Constant-time comparison to prevent timing attacks
-		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(s.config.Auth.Username)) == 1
-		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(s.config.Auth.Password)) == 1
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(auth.Username)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(auth.Password)) == 1
 
 		if !userMatch || !passMatch {
 			s.logger.Warn("Authentication failed",
@@ -63,10 +139,39 @@ func (s *Server) basicAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// allowTriggersMiddleware rejects requests that start or cancel tests unless
+// webserver.allow_triggers is enabled. Checked per request so the setting
+// can change on config reload.
+func (s *Server) allowTriggersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.currentConfig().Webserver.AllowTriggers {
+			s.writeError(w, http.StatusForbidden, "Triggering tests is disabled (set webserver.allow_triggers to enable it)")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readOnlyMiddleware rejects requests that start tests while read-only mode
+// is enabled.
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.ReadOnly() {
+			s.writeError(w, http.StatusServiceUnavailable, "Server is in read-only mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // unauthorized sends a 401 response with WWW-Authenticate header.
 func (s *Server) unauthorized(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="FlowGauge API"`)
 	http.Error(w, "Unauthorized", http.StatusUnauthorized)
 }
 
-
+// isHealthPath reports whether path is one of the health endpoints, which are
+// served without authentication or redirects.
+func isHealthPath(path string) bool {
+	return path == "/health" || path == "/health/ready"
+}
@@ -0,0 +1,617 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+	"github.com/lan-dot-party/flowgauge/pkg/version"
+)
+
+// openAPIVersion is the OpenAPI version of the generated document.
+const openAPIVersion = "3.0.3"
+
+// apiTag groups operations in the OpenAPI document and on the docs page.
+type apiTag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Icon is shown next to the group heading on the docs page
+	Icon string `json:"x-icon,omitempty"`
+}
+
+// apiParam describes a path or query parameter of an operation.
+type apiParam struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // JSON schema type
+	Description string
+	// Example is also used for the docs page's "Try it" request
+	Example string
+}
+
+// apiOperation describes a route for the OpenAPI document.
+type apiOperation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Params      []apiParam
+
+	// Response is a value of the response body type; its schema is derived
+	// from the struct's JSON tags. Data operations wrap it in APIResponse.
+	Response interface{}
+	// Envelope wraps Response in the APIResponse envelope
+	Envelope bool
+	// Paginated adds the envelope's meta object (list responses)
+	Paginated bool
+	// ContentType of the response (default application/json)
+	ContentType string
+	// Status is the success status code (default 200)
+	Status int
+	// Errors lists the documented error status codes
+	Errors []int
+	// Public operations don't require authentication
+	Public bool
+}
+
+// apiTags defines the operation groups in display order.
+var apiTags = []apiTag{
+	{Name: "Health", Description: "Service health", Icon: "🏥"},
+	{Name: "Results", Description: "Stored speedtest results", Icon: "📊"},
+	{Name: "Connections", Description: "Configured connections, statistics and manual tests", Icon: "🔌"},
+	{Name: "Groups", Description: "Connection groups", Icon: "🏷️"},
+	{Name: "Configuration", Description: "Effective configuration", Icon: "⚙️"},
+	{Name: "Maintenance", Description: "Read-only mode for maintenance windows", Icon: "🛠️"},
+	{Name: "Metrics", Description: "Prometheus metrics", Icon: "📈"},
+}
+
+// connectionNameParam is the {name} path parameter of connection routes.
+var connectionNameParam = apiParam{
+	Name: "name", In: "path", Type: "string", Description: "Connection name", Example: "WAN1-Primary",
+}
+
+// apiOperations lists all documented routes. Keep in sync with setupRouter.
+var apiOperations = []apiOperation{
+	{
+		Method: http.MethodGet, Path: "/health", Tag: "Health",
+		Summary:     "Health check endpoint",
+		Description: "Returns the server health status, version and whether read-only mode is enabled.",
+		Response:    healthResponse{},
+		Public:      true,
+	},
+	{
+		Method: http.MethodGet, Path: "/health/ready", Tag: "Health",
+		Summary:     "Readiness check endpoint",
+		Description: "Returns whether the server accepts new tests. Returns 503 Service Unavailable with status \"read_only\" while read-only mode is enabled.",
+		Response:    healthResponse{},
+		Public:      true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/results", Tag: "Results",
+		Summary:     "List speedtest results",
+		Description: "Returns a list of speedtest results with optional filtering and pagination.",
+		Params: []apiParam{
+			{Name: "connection", In: "query", Type: "string", Description: "Filter by connection name"},
+			{Name: "group", In: "query", Type: "string", Description: "Filter by connection group"},
+			{Name: "since", In: "query", Type: "string", Description: `Filter results since (RFC3339, date like "2024-01-02", or duration like "24h" or "7d")`},
+			{Name: "until", In: "query", Type: "string", Description: "Filter results until (same formats as since)"},
+			{Name: "error", In: "query", Type: "boolean", Description: `"true" returns only failed tests, "false" only successful ones (default: all)`},
+			{Name: "config_hash", In: "query", Type: "string", Description: "Filter by the configuration hash of the results (see config_hash of /api/v1/connections)"},
+			{Name: "source", In: "query", Type: "string", Description: "Filter by what started the tests: scheduled, api or cli"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Maximum results (default: 100, capped at webserver.max_results_limit)", Example: "5"},
+			{Name: "offset", In: "query", Type: "integer", Description: "Offset for pagination"},
+			{Name: "before_id", In: "query", Type: "integer", Description: "Cursor pagination: results with a lower ID, ordered by ID (0 = first page; see meta.next_cursor)"},
+		},
+		Response:  []storage.TestResult{},
+		Envelope:  true,
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/results/latest", Tag: "Results",
+		Summary:     "Get latest result per connection",
+		Description: "Returns the most recent speedtest result for each configured connection. With global=true, data is a single result object instead of a list.",
+		Params: []apiParam{
+			{Name: "global", In: "query", Type: "boolean", Description: `If "true", return only the single most recent result across all connections`},
+		},
+		Response: []storage.TestResult{},
+		Envelope: true,
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/results/{id}", Tag: "Results",
+		Summary:     "Get a specific result",
+		Description: "Returns a single speedtest result by ID.",
+		Params: []apiParam{
+			{Name: "id", In: "path", Type: "integer", Description: "Result ID", Example: "1"},
+		},
+		Response: storage.TestResult{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections", Tag: "Connections",
+		Summary:     "List all connections",
+		Description: "Returns all configured network connections.",
+		Response:    []connectionResponse{},
+		Envelope:    true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/dscp", Tag: "Connections",
+		Summary:     "List DSCP names",
+		Description: "Returns the common names of DSCP values (e.g. 46 = EF), keyed by DSCP value. Values without a name are not included.",
+		Response:    map[string]string{},
+		Envelope:    true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/stats", Tag: "Connections",
+		Summary:     "Get connection statistics",
+		Description: "Returns aggregated statistics for a specific connection.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "period", In: "query", Type: "string", Description: `Time period (e.g., "24h", "7d", "2w"; default "24h")`, Example: "24h"},
+		},
+		Response: storage.Stats{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/availability", Tag: "Connections",
+		Summary:     "Get connection availability",
+		Description: "Returns the share of successful tests (availability ratio and uptime percentage) for a connection over a rolling window.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "period", In: "query", Type: "string", Description: `Time period (e.g., "24h", "7d", "30d"; default "30d")`, Example: "30d"},
+		},
+		Response: availabilityResponse{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/trends", Tag: "Connections",
+		Summary:     "Get connection trends",
+		Description: "Returns average, minimum and maximum values of a connection bucketed by hour of day (0-23) or day of week (0 = Sunday), in the configured timezone. Reveals recurring patterns such as peak-hour congestion.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "group_by", In: "query", Type: "string", Description: `Bucketing: "hour_of_day" or "day_of_week" (default "hour_of_day")`, Example: "hour_of_day"},
+			{Name: "period", In: "query", Type: "string", Description: `Time period (e.g., "7d", "30d"; default "30d")`, Example: "30d"},
+		},
+		Response: trendsResponse{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/failures", Tag: "Connections",
+		Summary:     "Get recent failures",
+		Description: "Returns the most recent failed speedtest results (with a non-empty error) of a connection, newest first.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 20, capped at webserver.max_results_limit)", Example: "20"},
+		},
+		Response: []storage.TestResult{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/events", Tag: "Connections",
+		Summary:     "Get health events",
+		Description: "Returns the changes of a connection's health state (up, degraded or down) recorded by the scheduler, newest first. A connection is down when a test fails, degraded when some of its phases fail, and up otherwise.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "since", In: "query", Type: "string", Description: "Events since (RFC3339, date like 2024-01-02, or duration like 24h or 7d)", Example: "7d"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of events (default 50, capped at webserver.max_results_limit)", Example: "50"},
+		},
+		Response: []storage.ConnectionEvent{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/connections/{name}/test", Tag: "Connections",
+		Summary:     "Trigger a speedtest",
+		Description: "Starts a speedtest for an enabled connection in the background. If webserver.trigger_concurrency tests are already running, the test is queued and started once a slot is free. Returns 429 Too Many Requests if a test for this connection is already running or queued, or the queue is full. Requires webserver.allow_triggers (403 Forbidden otherwise). Returns 503 Service Unavailable in read-only mode.",
+		Params: []apiParam{
+			connectionNameParam,
+			{Name: "phases", In: "query", Type: "string", Description: "Comma-separated phases to run: latency, download, upload (default: all)"},
+			{Name: "latency", In: "query", Type: "boolean", Description: "Enable or disable the latency phase"},
+			{Name: "download", In: "query", Type: "boolean", Description: "Enable or disable the download phase"},
+			{Name: "upload", In: "query", Type: "boolean", Description: "Enable or disable the upload phase"},
+		},
+		Response: triggerState{},
+		Envelope: true,
+		Status:   http.StatusAccepted,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/connections/{name}/test", Tag: "Connections",
+		Summary:     "Get triggered test status",
+		Description: "Returns the state of the most recently triggered test for a connection, including the result once finished.",
+		Params:      []apiParam{connectionNameParam},
+		Response:    triggerState{},
+		Envelope:    true,
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/connections/{name}/test/cancel", Tag: "Connections",
+		Summary:     "Cancel a triggered speedtest",
+		Description: "Aborts the running triggered test of a connection, or removes it from the queue. A running test stops asynchronously and its result is not saved. Requires webserver.allow_triggers (403 Forbidden otherwise).",
+		Params:      []apiParam{connectionNameParam},
+		Response:    triggerState{},
+		Envelope:    true,
+		Errors:      []int{http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/triggers", Tag: "Connections",
+		Summary:     "Get the triggered test queue",
+		Description: "Returns the concurrency and depth of the triggered test queue, the number of running triggered tests and the connections waiting in the queue.",
+		Response:    triggerQueueResponse{},
+		Envelope:    true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/servers", Tag: "Connections",
+		Summary:     "List speedtest servers",
+		Description: "Fetches the speedtest server list, sorted by distance, with the latency of each server measured while fetching it (omitted if the server didn't respond). With connection, the list is fetched through the connection's source binding and DSCP value. Lists are cached for 5 minutes per connection. Returns 502 Bad Gateway if the list can't be fetched.",
+		Params: []apiParam{
+			{Name: "connection", In: "query", Type: "string", Description: "Fetch the list through this connection (default: without a source binding)"},
+		},
+		Response: []speedtest.ServerInfo{},
+		Envelope: true,
+		Errors:   []int{http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/groups", Tag: "Groups",
+		Summary:     "List connection groups",
+		Description: "Returns all connection groups and their member connections.",
+		Response:    []groupResponse{},
+		Envelope:    true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/groups/{group}/stats", Tag: "Groups",
+		Summary:     "Get aggregated group statistics",
+		Description: "Returns statistics aggregated across all connections in a group, plus per-connection statistics.",
+		Params: []apiParam{
+			{Name: "group", In: "path", Type: "string", Description: "Group name"},
+			{Name: "period", In: "query", Type: "string", Description: `Time period (e.g., "24h", "7d"; default "24h")`},
+		},
+		Response: groupStatsResponse{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/config", Tag: "Configuration",
+		Summary:     "Get sanitized configuration",
+		Description: "Returns the effective configuration with passwords replaced by ***. Keys match the YAML configuration file. Only available when authentication is enabled.",
+		Response:    map[string]interface{}{},
+		Envelope:    true,
+		Errors:      []int{http.StatusForbidden},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/read-only", Tag: "Maintenance",
+		Summary:     "Get read-only mode",
+		Description: "Returns whether read-only mode is enabled. While enabled, triggering tests returns 503 and scheduled runs are skipped; reads are served as usual.",
+		Response:    readOnlyResponse{},
+		Envelope:    true,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/read-only", Tag: "Maintenance",
+		Summary:     "Enable or disable read-only mode",
+		Description: "Enables or disables read-only mode for maintenance (e.g. of the database). Enabling it drops queued triggered tests; running tests are finished. Only available when authentication is enabled. Can also be toggled by sending SIGUSR1 to the server.",
+		Params: []apiParam{
+			{Name: "enabled", In: "query", Type: "boolean", Description: "true to enable, false to disable read-only mode", Example: "true"},
+		},
+		Response: readOnlyResponse{},
+		Envelope: true,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/metrics", Tag: "Metrics",
+		Summary:     "Prometheus metrics",
+		Description: "Returns Prometheus-formatted metrics: flowgauge_download_speed_mbps, flowgauge_upload_speed_mbps, flowgauge_latency_ms, flowgauge_jitter_ms, flowgauge_tests_total, flowgauge_test_errors_total and flowgauge_availability_ratio.",
+		ContentType: "text/plain",
+		Response:    "",
+	},
+}
+
+// undocumentedRoutes returns the API routes of r that are missing from apiOperations.
+func undocumentedRoutes(r chi.Routes) []string {
+	documented := make(map[string]bool, len(apiOperations))
+	for _, op := range apiOperations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	var missing []string
+	_ = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/v1/") && !documented[method+" "+route] {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	return missing
+}
+
+// handleOpenAPI serves the OpenAPI document describing the API.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	auth := s.currentConfig().Webserver.Auth
+	s.writeJSON(w, http.StatusOK, buildOpenAPI(auth != nil && auth.Username != ""))
+}
+
+// buildOpenAPI generates the OpenAPI document from apiOperations.
+// Response schemas are derived from the response structs' JSON tags.
+func buildOpenAPI(authEnabled bool) map[string]interface{} {
+	schemas := newSchemaBuilder()
+	errorSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string", "example": "error"},
+			"error":  schemas.schema(reflect.TypeOf(apiError{})),
+		},
+		"required": []string{"status", "error"},
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range apiOperations {
+		item, ok := paths[op.Path]
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.spec(schemas, errorSchema, authEnabled)
+	}
+
+	components := map[string]interface{}{
+		"schemas": schemas.components,
+	}
+	doc := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "FlowGauge API",
+			"description": "REST API for speedtest results, connection statistics, manual tests and Prometheus metrics.",
+			"version":     version.GetShortVersion(),
+		},
+		"tags":       apiTags,
+		"paths":      paths,
+		"components": components,
+	}
+
+	if authEnabled {
+		components["securitySchemes"] = map[string]interface{}{
+			"basicAuth": map[string]interface{}{"type": "http", "scheme": "basic"},
+			"apiKey":    map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+		}
+		doc["security"] = []map[string][]string{{"basicAuth": {}}}
+	}
+
+	return doc
+}
+
+// spec returns the OpenAPI operation object.
+func (op apiOperation) spec(schemas *schemaBuilder, errorSchema map[string]interface{}, authEnabled bool) map[string]interface{} {
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body := schemas.schema(reflect.TypeOf(op.Response))
+	if op.Envelope {
+		properties := map[string]interface{}{
+			"status":  map[string]interface{}{"type": "string", "example": "ok"},
+			"data":    body,
+			"message": map[string]interface{}{"type": "string"},
+		}
+		required := []string{"status", "data"}
+		if op.Paginated {
+			properties["meta"] = schemas.schema(reflect.TypeOf(responseMeta{}))
+			required = append(required, "meta")
+		}
+		body = map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	}
+
+	responses := map[string]interface{}{
+		strconv.Itoa(status): map[string]interface{}{
+			"description": http.StatusText(status),
+			"content": map[string]interface{}{
+				contentType: map[string]interface{}{"schema": body},
+			},
+		},
+	}
+	errors := op.Errors
+	if authEnabled && !op.Public {
+		errors = append(append([]int(nil), errors...), http.StatusUnauthorized)
+	}
+	errors = append(errors, http.StatusInternalServerError)
+	for _, code := range errors {
+		responses[strconv.Itoa(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": errorSchema},
+			},
+		}
+	}
+
+	spec := map[string]interface{}{
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"description": op.Description,
+		"operationId": operationID(op.Method, op.Path),
+		"responses":   responses,
+	}
+	if len(op.Params) > 0 {
+		params := make([]map[string]interface{}, 0, len(op.Params))
+		for _, p := range op.Params {
+			param := map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.In == "path",
+				"schema":      map[string]interface{}{"type": p.Type},
+			}
+			if p.Example != "" {
+				param["example"] = p.Example
+			}
+			params = append(params, param)
+		}
+		spec["parameters"] = params
+	}
+	if op.Public && authEnabled {
+		spec["security"] = []map[string][]string{}
+	}
+	if op.Method == http.MethodGet && slices.Contains(apiKeyRoutes, op.Path) && authEnabled {
+		// Connection API keys see only their connections
+		spec["security"] = []map[string][]string{{"basicAuth": {}}, {"apiKey": {}}}
+	}
+
+	return spec
+}
+
+// operationID derives a unique operation ID such as "getConnectionsNameStats".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		part = strings.Trim(part, "{}")
+		if part == "" || part == "api" || part == "v1" {
+			continue
+		}
+		b.WriteString(exportName(part))
+	}
+	return b.String()
+}
+
+// exportName upper-cases the first letter of a name.
+func exportName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return name
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaBuilder derives JSON schemas from Go types, collecting named
+// structs as reusable components.
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]interface{})}
+}
+
+// schema returns the JSON schema for t. Named structs are referenced as components.
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "Duration in nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := exportName(t.Name())
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = map[string]interface{}{} // placeholder for recursive types
+			b.components[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		// interface{} and other types accept any value
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema returns an object schema with a property per JSON field.
+// Embedded structs without a JSON name are flattened like encoding/json does.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := b.structSchema(embedded)
+				for k, v := range inner["properties"].(map[string]interface{}) {
+					properties[k] = v
+				}
+				if req, ok := inner["required"].([]string); ok {
+					required = append(required, req...)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
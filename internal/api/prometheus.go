@@ -1,23 +1,38 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
 
+	"github.com/lan-dot-party/flowgauge/internal/config"
 	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 )
 
+// availabilityPeriod is the rolling window of the availability gauge and
+// the default period of the availability endpoint.
+const availabilityPeriod = 30 * 24 * time.Hour
+
 var (
-	// Speedtest metrics
+	// Speedtest metrics. The family label is "ipv4" or "ipv6" for
+	// dual-stack connections and empty otherwise.
 	downloadSpeed = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "flowgauge",
 			Name:      "download_speed_mbps",
 			Help:      "Download speed in Mbps",
 		},
-		[]string{"connection", "server"},
+		[]string{"connection", "server", "family"},
 	)
 
 	uploadSpeed = prometheus.NewGaugeVec(
@@ -26,7 +41,7 @@ var (
 			Name:      "upload_speed_mbps",
 			Help:      "Upload speed in Mbps",
 		},
-		[]string{"connection", "server"},
+		[]string{"connection", "server", "family"},
 	)
 
 	latency = prometheus.NewGaugeVec(
@@ -35,7 +50,7 @@ var (
 			Name:      "latency_ms",
 			Help:      "Latency in milliseconds",
 		},
-		[]string{"connection", "server"},
+		[]string{"connection", "server", "family"},
 	)
 
 	jitter = prometheus.NewGaugeVec(
@@ -44,7 +59,16 @@ var (
 			Name:      "jitter_ms",
 			Help:      "Jitter in milliseconds",
 		},
-		[]string{"connection", "server"},
+		[]string{"connection", "server", "family"},
+	)
+
+	connectTime = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "flowgauge",
+			Name:      "connect_ms",
+			Help:      "TCP connect time to the speedtest server in milliseconds",
+		},
+		[]string{"connection", "server", "family"},
 	)
 
 	testTimestamp = prometheus.NewGaugeVec(
@@ -82,27 +106,227 @@ var (
 		},
 		[]string{"connection"},
 	)
+
+	testAttempts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "flowgauge",
+			Name:      "test_attempts",
+			Help:      "Number of attempts the last speedtest took",
+		},
+		[]string{"connection"},
+	)
+
+	testBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "flowgauge",
+			Name:      "test_bytes_total",
+			Help:      "Total number of bytes transferred by speedtests",
+		},
+		[]string{"connection", "direction"},
+	)
+
+	dscpApplied = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "flowgauge",
+			Name:      "dscp_applied",
+			Help:      "Whether DSCP marking was applied in the last speedtest (1) or not (0), for connections with DSCP configured",
+		},
+		[]string{"connection"},
+	)
+
+	availabilityRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "flowgauge",
+			Name:      "availability_ratio",
+			Help:      "Ratio of successful speedtests over the last 30 days (0-1)",
+		},
+		[]string{"connection"},
+	)
 )
 
+// connectionMetrics are all metric vectors with a "connection" label.
+var connectionMetrics = []interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}{
+	downloadSpeed,
+	uploadSpeed,
+	latency,
+	jitter,
+	connectTime,
+	testTimestamp,
+	testDuration,
+	testErrors,
+	testsTotal,
+	testAttempts,
+	testBytesTotal,
+	dscpApplied,
+	availabilityRatio,
+}
+
+// metricConnections tracks the connections that have metric series, so
+// series of removed connections can be deleted.
+var (
+	metricConnections   = make(map[string]bool)
+	metricConnectionsMu sync.Mutex
+)
+
+// measurementMetrics are the gauges holding the measured values of the last
+// successful test, which are cleared on errors with prometheus.clear_on_error.
+var measurementMetrics = []*prometheus.GaugeVec{
+	downloadSpeed,
+	uploadSpeed,
+	latency,
+	jitter,
+	connectTime,
+}
+
+// metricsConfig holds the prometheus settings, and clearedConnections the
+// connections (and address families) whose measurement gauges are currently
+// cleared.
+var (
+	metricsConfig      config.PrometheusConfig
+	clearedConnections = make(map[string]bool)
+	metricsConfigMu    sync.Mutex
+)
+
+// Histograms of the measured values, so quantiles can be computed across
+// scrapes. Their buckets are configurable, so they are replaced by
+// SetMetricsConfig when the buckets change (guarded by metricsConfigMu).
+// The throughput histograms are nil unless prometheus.throughput_buckets
+// is set.
+var (
+	latencyHistogram  *prometheus.HistogramVec
+	downloadHistogram *prometheus.HistogramVec
+	uploadHistogram   *prometheus.HistogramVec
+)
+
+// SetMetricsConfig applies the prometheus settings to subsequent metric updates.
+// Changing the buckets of a histogram resets its series.
+func SetMetricsConfig(cfg config.PrometheusConfig) {
+	metricsConfigMu.Lock()
+	defer metricsConfigMu.Unlock()
+
+	if !slices.Equal(cfg.LatencyBuckets, metricsConfig.LatencyBuckets) {
+		latencyHistogram = replaceHistogram(latencyHistogram, "latency_histogram_ms",
+			"Latency distribution in milliseconds", cfg.LatencyBuckets)
+	}
+	if !slices.Equal(cfg.ThroughputBuckets, metricsConfig.ThroughputBuckets) {
+		downloadHistogram = replaceHistogram(downloadHistogram, "download_speed_histogram_mbps",
+			"Download speed distribution in Mbps", cfg.ThroughputBuckets)
+		uploadHistogram = replaceHistogram(uploadHistogram, "upload_speed_histogram_mbps",
+			"Upload speed distribution in Mbps", cfg.ThroughputBuckets)
+	}
+	metricsConfig = cfg
+}
+
+// replaceHistogram unregisters old (if not nil) and registers a new histogram
+// with the given buckets, or returns nil if there are none.
+func replaceHistogram(old *prometheus.HistogramVec, name, help string, buckets []float64) *prometheus.HistogramVec {
+	for _, registerer := range metricRegistries {
+		if old != nil {
+			registerer.Unregister(old)
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	histogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "flowgauge",
+			Name:      name,
+			Help:      help,
+			Buckets:   buckets,
+		},
+		[]string{"connection", "family"},
+	)
+	for _, registerer := range metricRegistries {
+		registerer.MustRegister(histogram)
+	}
+	return histogram
+}
+
+// textfileRegistry contains only the FlowGauge metrics (no Go runtime or
+// process metrics), so textfile output doesn't clash with node_exporter.
+var textfileRegistry = prometheus.NewRegistry()
+
+// metricRegistries are the registries all metrics are registered with.
+var metricRegistries = []prometheus.Registerer{prometheus.DefaultRegisterer, textfileRegistry}
+
 func init() {
 	// Register all metrics
-	prometheus.MustRegister(
-		downloadSpeed,
-		uploadSpeed,
-		latency,
-		jitter,
-		testTimestamp,
-		testDuration,
-		testErrors,
-		testsTotal,
-	)
+	for _, registerer := range metricRegistries {
+		registerer.MustRegister(
+			downloadSpeed,
+			uploadSpeed,
+			latency,
+			jitter,
+			connectTime,
+			testTimestamp,
+			testDuration,
+			testErrors,
+			testsTotal,
+			testAttempts,
+			testBytesTotal,
+			dscpApplied,
+			availabilityRatio,
+		)
+	}
+
+	// Default buckets until SetMetricsConfig applies the configured ones
+	SetMetricsConfig(config.PrometheusConfig{LatencyBuckets: config.DefaultLatencyBuckets()})
+}
+
+// WriteMetricsFile writes the current metric values to path in the Prometheus
+// text exposition format, for the node_exporter textfile collector. The file
+// is replaced atomically (written to a temporary file, then renamed).
+func WriteMetricsFile(path string) error {
+	return prometheus.WriteToTextfile(path, textfileRegistry)
+}
+
+// WriteMetrics writes the same metrics as WriteMetricsFile to w, e.g. to
+// print them on stdout.
+func WriteMetrics(w io.Writer) error {
+	families, err := textfileRegistry.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return fmt.Errorf("failed to encode metrics: %w", err)
+		}
+	}
+	return nil
 }
 
 // handlePrometheusMetrics exposes Prometheus metrics.
 func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	s.updateAvailabilityMetrics(r.Context())
 	promhttp.Handler().ServeHTTP(w, r)
 }
 
+// updateAvailabilityMetrics refreshes the availability gauge from storage
+// for all enabled connections. It is computed on scrape because it covers
+// a rolling window rather than the latest result.
+func (s *Server) updateAvailabilityMetrics(ctx context.Context) {
+	availabilityRatio.Reset()
+	for _, conn := range s.currentConfig().GetEnabledConnections() {
+		stats, err := s.storage.GetStats(ctx, conn.Name, availabilityPeriod)
+		if err != nil {
+			s.logger.Warn("Failed to get availability",
+				zap.String("connection", conn.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+		if stats.TestCount == 0 {
+			continue
+		}
+		availabilityRatio.WithLabelValues(conn.Name).Set(stats.Availability)
+	}
+}
+
 // UpdateMetrics updates Prometheus metrics for multiple results.
 // Exported so it can be called from the scheduler.
 func UpdateMetrics(results []speedtest.Result) {
@@ -117,22 +341,178 @@ func UpdateMetricsForResult(result *speedtest.Result) {
 	labels := prometheus.Labels{
 		"connection": result.ConnectionName,
 		"server":     result.ServerName,
+		"family":     result.IPFamily,
 	}
 
+	metricConnectionsMu.Lock()
+	metricConnections[result.ConnectionName] = true
+	metricConnectionsMu.Unlock()
+
 	testsTotal.WithLabelValues(result.ConnectionName).Inc()
 
+	// Failed tests are included, as retries before a failure matter as well
+	if result.Attempts > 0 {
+		testAttempts.WithLabelValues(result.ConnectionName).Set(float64(result.Attempts))
+	}
+
+	// Failed tests count as well, they may have transferred data before failing
+	if result.BytesDownloaded > 0 {
+		testBytesTotal.WithLabelValues(result.ConnectionName, "download").Add(float64(result.BytesDownloaded))
+	}
+	if result.BytesUploaded > 0 {
+		testBytesTotal.WithLabelValues(result.ConnectionName, "upload").Add(float64(result.BytesUploaded))
+	}
+
+	// Only connections that should be marked, so a 0 always means a problem
+	if result.DSCP > 0 {
+		applied := 0.0
+		if result.DSCPApplied {
+			applied = 1
+		}
+		dscpApplied.WithLabelValues(result.ConnectionName).Set(applied)
+	}
+
 	if result.IsError() {
 		testErrors.WithLabelValues(result.ConnectionName).Inc()
+		clearMeasurementMetrics(result)
 		return
 	}
 
-	downloadSpeed.With(labels).Set(result.DownloadMbps)
-	uploadSpeed.With(labels).Set(result.UploadMbps)
-	latency.With(labels).Set(result.LatencyMs)
-	jitter.With(labels).Set(result.JitterMs)
+	restoreMeasurementMetrics(result)
+
+	// Keep the previous value for phases that failed instead of reporting zero
+	if result.DownloadOK {
+		downloadSpeed.With(labels).Set(result.DownloadMbps)
+	}
+	if result.UploadOK {
+		uploadSpeed.With(labels).Set(result.UploadMbps)
+	}
+	if result.LatencyOK {
+		latency.With(labels).Set(result.LatencyMs)
+		jitter.With(labels).Set(result.JitterMs)
+	}
+	if result.ConnectMs > 0 {
+		connectTime.With(labels).Set(result.ConnectMs)
+	}
+	observeHistograms(result)
 
 	testTimestamp.WithLabelValues(result.ConnectionName).Set(float64(result.Timestamp.Unix()))
 	testDuration.WithLabelValues(result.ConnectionName).Set(result.Duration)
 }
 
+// observeHistograms adds the values of a test's successful phases to the
+// histograms.
+func observeHistograms(result *speedtest.Result) {
+	metricsConfigMu.Lock()
+	defer metricsConfigMu.Unlock()
 
+	observations := []struct {
+		histogram *prometheus.HistogramVec
+		ok        bool
+		value     float64
+	}{
+		{latencyHistogram, result.LatencyOK, result.LatencyMs},
+		{downloadHistogram, result.DownloadOK, result.DownloadMbps},
+		{uploadHistogram, result.UploadOK, result.UploadMbps},
+	}
+	for _, o := range observations {
+		if o.histogram != nil && o.ok {
+			o.histogram.WithLabelValues(result.ConnectionName, result.IPFamily).Observe(o.value)
+		}
+	}
+}
+
+// clearMeasurementMetrics replaces the measurement gauges of a failed test's
+// connection with a single series set to the configured clear value, so
+// dashboards don't show the last good value while the connection is down.
+// Does nothing unless prometheus.clear_on_error is enabled.
+func clearMeasurementMetrics(result *speedtest.Result) {
+	metricsConfigMu.Lock()
+	defer metricsConfigMu.Unlock()
+
+	if !metricsConfig.ClearOnError {
+		return
+	}
+	value := math.NaN()
+	if metricsConfig.ClearValue == "zero" {
+		value = 0
+	}
+
+	labels := prometheus.Labels{
+		"connection": result.ConnectionName,
+		"server":     result.ServerName,
+		"family":     result.IPFamily,
+	}
+	for _, gauge := range measurementMetrics {
+		gauge.DeletePartialMatch(measurementMatch(result))
+		gauge.With(labels).Set(value)
+	}
+	clearedConnections[measurementKey(result)] = true
+}
+
+// restoreMeasurementMetrics removes the cleared series of a connection before
+// the values of a successful test are set, so they don't linger next to them.
+func restoreMeasurementMetrics(result *speedtest.Result) {
+	metricsConfigMu.Lock()
+	defer metricsConfigMu.Unlock()
+
+	key := measurementKey(result)
+	if !clearedConnections[key] {
+		return
+	}
+	for _, gauge := range measurementMetrics {
+		gauge.DeletePartialMatch(measurementMatch(result))
+	}
+	delete(clearedConnections, key)
+}
+
+// measurementMatch matches the measurement series of a result's connection
+// and address family, so clearing one family of a dual-stack connection
+// keeps the other.
+func measurementMatch(result *speedtest.Result) prometheus.Labels {
+	return prometheus.Labels{"connection": result.ConnectionName, "family": result.IPFamily}
+}
+
+// measurementKey identifies a connection and address family in clearedConnections.
+func measurementKey(result *speedtest.Result) string {
+	return result.ConnectionName + "/" + result.IPFamily
+}
+
+// deleteHistogramSeries deletes the histogram series of a connection.
+func deleteHistogramSeries(connection string) {
+	metricsConfigMu.Lock()
+	defer metricsConfigMu.Unlock()
+
+	for _, histogram := range []*prometheus.HistogramVec{latencyHistogram, downloadHistogram, uploadHistogram} {
+		if histogram != nil {
+			histogram.DeletePartialMatch(prometheus.Labels{"connection": connection})
+		}
+	}
+}
+
+// DeleteStaleMetrics deletes the metric series of all connections that are
+// not in the given list, e.g. after a connection was removed from the
+// configuration. Returns the names of the removed connections.
+func DeleteStaleMetrics(connections []string) []string {
+	keep := make(map[string]bool, len(connections))
+	for _, name := range connections {
+		keep[name] = true
+	}
+
+	metricConnectionsMu.Lock()
+	defer metricConnectionsMu.Unlock()
+
+	var removed []string
+	for name := range metricConnections {
+		if keep[name] {
+			continue
+		}
+		for _, vec := range connectionMetrics {
+			vec.DeletePartialMatch(prometheus.Labels{"connection": name})
+		}
+		deleteHistogramSeries(name)
+		delete(metricConnections, name)
+		removed = append(removed, name)
+	}
+	return removed
+}
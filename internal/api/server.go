@@ -3,8 +3,13 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -18,6 +23,10 @@ import (
 	"github.com/lan-dot-party/flowgauge/pkg/version"
 )
 
+// writeTimeoutMargin is added to the request timeout for the server's write
+// timeout, so the timeout response of a cancelled request can still be sent.
+const writeTimeoutMargin = 5 * time.Second
+
 // Server represents the HTTP web server (Dashboard + API).
 type Server struct {
 	config     *config.WebserverConfig
@@ -27,6 +36,27 @@ type Server struct {
 	logger     *zap.Logger
 	router     chi.Router
 	httpServer *http.Server
+	cache      *ttlCache
+	// serverLists caches fetched speedtest server lists by connection name
+	serverLists *ttlCache
+
+	// mu guards fullConfig and runner, which can be swapped on config reload
+	mu sync.RWMutex
+
+	// triggers tracks manually triggered tests by connection name
+	triggers   map[string]*triggerState
+	triggersMu sync.Mutex
+	// triggerQueue holds triggered tests waiting for a free slot, oldest first
+	triggerQueue []*triggerState
+	// activeTriggers is the number of workers running triggered tests
+	activeTriggers int
+	// background tracks the goroutines of triggered tests, so shutdown can
+	// wait for their results to be saved
+	background sync.WaitGroup
+
+	// readOnly rejects triggered tests and pauses the scheduler during
+	// maintenance, while reads keep being served
+	readOnly atomic.Bool
 }
 
 // NewServer creates a new API server instance.
@@ -35,12 +65,21 @@ func NewServer(cfg *config.Config, store storage.Storage, runner *speedtest.Mult
 		logger = zap.NewNop()
 	}
 
+	if cfg.Webserver.EmbedAssets {
+		if missing := missingAssets(); len(missing) > 0 {
+			return nil, fmt.Errorf("webserver.embed_assets is enabled, but this build lacks the assets %v (run \"make assets\" before building)", missing)
+		}
+	}
+
 	s := &Server{
-		config:     &cfg.Webserver,
-		fullConfig: cfg,
-		storage:    store,
-		runner:     runner,
-		logger:     logger,
+		config:      &cfg.Webserver,
+		fullConfig:  cfg,
+		storage:     store,
+		runner:      runner,
+		logger:      logger,
+		cache:       newTTLCache(cfg.Webserver.DashboardCacheTTL),
+		serverLists: newTTLCache(serverListTTL),
+		triggers:    make(map[string]*triggerState),
 	}
 
 	s.setupRouter()
@@ -56,80 +95,272 @@ func (s *Server) setupRouter() {
 	r.Use(chimiddleware.RealIP)
 	r.Use(s.loggingMiddleware)
 	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.Timeout(60 * time.Second))
+
+	// Canonical host and HTTPS redirects, before credentials are checked
+	r.Use(s.redirectMiddleware)
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID"},
 		ExposedHeaders:   []string{"X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Basic Auth (if configured)
-	if s.config.Auth != nil && s.config.Auth.Username != "" {
-		r.Use(s.basicAuthMiddleware)
-	}
+	// Basic Auth and API keys (only enforced if configured, checked per
+	// request so auth settings can change on config reload)
+	r.Use(s.authMiddleware)
 
-	// Health check (no auth required)
-	r.Get("/health", s.handleHealth)
+	// Endpoints are cancelled after the request timeout
+	r.Group(func(r chi.Router) {
+		r.Use(chimiddleware.Timeout(s.requestTimeout()))
 
-	// Dashboard (Web UI)
-	r.Get("/", s.handleDashboard)
-	r.Get("/dashboard", s.handleDashboard)
-	r.Get("/dashboard/cards", s.handleDashboardPartial)
-	r.Get("/dashboard/connection/{name}/chart", s.handleConnectionChartData)
+		// Health check (no auth required)
+		r.Get("/health", s.handleHealth)
+		r.Get("/health/ready", s.handleHealthReady)
 
-	// API Documentation
-	r.Get("/api", s.handleAPIRedirect)
-	r.Get("/api/", s.handleAPIDocs)
+		// Dashboard (Web UI)
+		r.Get("/", s.handleDashboard)
+		r.Get("/dashboard", s.handleDashboard)
+		r.Get("/dashboard/cards", s.handleDashboardPartial)
+		r.Get("/dashboard/connection/{name}/chart", s.handleConnectionChartData)
+		r.Get("/assets/*", assetsHandler().ServeHTTP)
 
-	// API v1 routes (Read-Only)
-	r.Route("/api/v1", func(r chi.Router) {
-		// Results
-		r.Get("/results", s.handleGetResults)
-		r.Get("/results/latest", s.handleGetLatestResults)
-		r.Get("/results/{id}", s.handleGetResult)
+		// API Documentation
+		r.Get("/api", s.handleAPIRedirect)
+		r.Get("/api/", s.handleAPIDocs)
+		r.Get("/api/openapi.json", s.handleOpenAPI)
 
-		// Connections
-		r.Get("/connections", s.handleGetConnections)
-		r.Get("/connections/{name}/stats", s.handleGetConnectionStats)
+		// API v1 routes
+		r.Route("/api/v1", func(r chi.Router) {
+			// Results
+			r.Get("/results", s.handleGetResults)
+			r.Get("/results/latest", s.handleGetLatestResults)
+			r.Get("/results/{id}", s.handleGetResult)
 
-		// Metrics
-		r.Get("/metrics", s.handlePrometheusMetrics)
+			// Connections
+			r.Get("/connections", s.handleGetConnections)
+			r.Group(func(r chi.Router) {
+				// Connections outside an API key's scope are not found
+				r.Use(s.connectionScopeMiddleware)
+				r.Get("/connections/{name}/stats", s.handleGetConnectionStats)
+				r.Get("/connections/{name}/availability", s.handleGetConnectionAvailability)
+				r.Get("/connections/{name}/trends", s.handleGetConnectionTrends)
+				r.Get("/connections/{name}/failures", s.handleGetConnectionFailures)
+				r.Get("/connections/{name}/events", s.handleGetConnectionEvents)
+			})
+			r.Get("/dscp", s.handleGetDSCPNames)
+
+			// Groups
+			r.Get("/groups", s.handleGetGroups)
+			r.Get("/groups/{group}/stats", s.handleGetGroupStats)
+
+			// Manual test trigger (starting and cancelling requires
+			// webserver.allow_triggers)
+			r.With(s.allowTriggersMiddleware, s.readOnlyMiddleware).Post("/connections/{name}/test", s.handleTriggerTest)
+			r.Get("/connections/{name}/test", s.handleGetTriggerStatus)
+			r.With(s.allowTriggersMiddleware).Post("/connections/{name}/test/cancel", s.handleCancelTriggeredTest)
+			r.Get("/triggers", s.handleGetTriggerQueue)
+
+			// Speedtest servers
+			r.Get("/servers", s.handleGetServers)
+
+			// Maintenance
+			r.Get("/read-only", s.handleGetReadOnly)
+			r.Post("/read-only", s.handleSetReadOnly)
+
+			// Configuration (sanitized, requires auth)
+			r.Get("/config", s.handleGetConfig)
+
+			// Metrics
+			r.Get("/metrics", s.handlePrometheusMetrics)
+		})
 	})
 
 	s.router = r
+
+	for _, route := range undocumentedRoutes(r) {
+		s.logger.Warn("API route missing from OpenAPI document", zap.String("route", route))
+	}
+}
+
+// requestTimeout returns the configured request timeout, falling back to the
+// default if it is unset.
+func (s *Server) requestTimeout() time.Duration {
+	if s.config.RequestTimeout <= 0 {
+		return config.DefaultRequestTimeout
+	}
+	return s.config.RequestTimeout
 }
 
 // Start starts the HTTP server.
 func (s *Server) Start() error {
+	// Serve HTTP/1.1 and HTTP/2. Unencrypted HTTP/2 (h2c, prior knowledge)
+	// lets clients such as load balancers multiplex the dashboard's many
+	// chart requests over a single connection.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
 	s.httpServer = &http.Server{
-		Addr:         s.config.Listen,
-		Handler:      s.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 60 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:           s.config.Listen,
+		Handler:        s.router,
+		ReadTimeout:    15 * time.Second,
+		WriteTimeout:   s.requestTimeout() + writeTimeoutMargin,
+		IdleTimeout:    s.config.IdleTimeout,
+		MaxHeaderBytes: s.config.MaxHeaderBytes,
+		Protocols:      protocols,
+	}
+	s.httpServer.SetKeepAlivesEnabled(!s.config.DisableKeepAlives)
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
 	}
 
 	s.logger.Info("Starting web server",
 		zap.String("listen", s.config.Listen),
 		zap.String("version", version.GetShortVersion()),
+		zap.Bool("keep_alives", !s.config.DisableKeepAlives),
+		zap.Duration("idle_timeout", s.config.IdleTimeout),
 	)
 
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %w", err)
 	}
 
 	return nil
 }
 
-// Shutdown gracefully shuts down the server.
+// listen opens the listener for webserver.listen: a TCP address, or a Unix
+// domain socket for "unix:" addresses. The socket file is removed again when
+// the server shuts down.
+func (s *Server) listen() (net.Listener, error) {
+	path, ok := s.config.UnixSocketPath()
+	if !ok {
+		listener, err := net.Listen("tcp", s.config.Listen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
+		}
+		return listener, nil
+	}
+
+	mode, err := s.config.ParseSocketMode()
+	if err != nil {
+		return nil, err
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set permissions of unix socket %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a server that didn't
+// shut down cleanly. Sockets that still accept connections (another running
+// instance) and files that aren't sockets are left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check unix socket %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unix socket path %s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("unix socket %s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts down the server. After the listener is closed,
+// it waits for running triggered tests to save their results until ctx is done.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down web server")
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	s.waitForTriggeredTests(ctx)
+	return err
+}
+
+// Reload applies a new configuration and runner without restarting the listener.
+// Listen address changes require a restart and are ignored here.
+func (s *Server) Reload(cfg *config.Config, runner *speedtest.MultiWANRunner) {
+	if cfg.Webserver.EmbedAssets {
+		if missing := missingAssets(); len(missing) > 0 {
+			s.logger.Warn("webserver.embed_assets is enabled, but this build lacks assets; the dashboard won't load them",
+				zap.Strings("missing", missing))
+		}
+	}
+
+	s.mu.Lock()
+	s.fullConfig = cfg
+	s.runner = runner
+	s.mu.Unlock()
+
+	// The chart data's Cache-Control max-age follows the new TTL, so the
+	// server-side cache must too
+	s.cache.setTTL(cfg.Webserver.DashboardCacheTTL)
+	s.serverLists.invalidate()
+}
+
+// currentConfig returns the active configuration.
+func (s *Server) currentConfig() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fullConfig
+}
+
+// SetReadOnly enables or disables read-only mode. While enabled, triggering
+// tests returns 503 and scheduled runs are skipped (see ReadOnly); reads are
+// served as usual. Enabling it drops triggered tests that are still queued,
+// running tests are finished.
+func (s *Server) SetReadOnly(enabled bool) {
+	if s.readOnly.Swap(enabled) == enabled {
+		return
+	}
+
+	if enabled {
+		s.triggersMu.Lock()
+		for len(s.triggerQueue) > 0 {
+			s.dequeueTriggerLocked(s.triggerQueue[0], "read-only mode enabled")
+		}
+		s.triggersMu.Unlock()
+		s.logger.Info("Read-only mode enabled")
+	} else {
+		s.logger.Info("Read-only mode disabled")
+	}
+
+	s.cache.invalidate()
+}
+
+// ReadOnly returns true while read-only mode is enabled.
+func (s *Server) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// InvalidateCache drops all cached dashboard data.
+// Should be called whenever a new result has been saved.
+func (s *Server) InvalidateCache() {
+	s.cache.invalidate()
 }
 
 // Router returns the chi router (useful for testing).
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serverListTTL is how long fetched server lists are reused per connection,
+// so a server picker doesn't query speedtest.net on every request.
+const serverListTTL = 5 * time.Minute
+
+// handleGetServers returns the speedtest server list, fetched through the
+// connection given by the connection query parameter (optional).
+func (s *Server) handleGetServers(w http.ResponseWriter, r *http.Request) {
+	runner := s.currentRunner()
+	if runner == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Speedtest runner not available")
+		return
+	}
+
+	name := r.URL.Query().Get("connection")
+	if name != "" && !hasConnection(runner, name) {
+		s.writeError(w, http.StatusNotFound, "Connection not found")
+		return
+	}
+
+	if cached, ok := s.serverLists.get(name); ok {
+		s.writeJSON(w, http.StatusOK, okResponse(cached))
+		return
+	}
+
+	servers, err := runner.FetchServers(r.Context(), name)
+	if err != nil {
+		s.logger.Error("Failed to fetch speedtest servers", zap.String("connection", name), zap.Error(err))
+		s.writeError(w, http.StatusBadGateway, "Failed to fetch speedtest servers")
+		return
+	}
+	s.serverLists.set(name, servers)
+
+	s.writeJSON(w, http.StatusOK, okResponse(servers))
+}
@@ -0,0 +1,461 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// triggerTimeout is the maximum duration of a manually triggered test.
+const triggerTimeout = 10 * time.Minute
+
+// triggerSaveTimeout bounds saving a triggered test's result. It has its own
+// context, so a test that ran into triggerTimeout is still recorded.
+const triggerSaveTimeout = 30 * time.Second
+
+// triggerState tracks a manually triggered test for a connection.
+type triggerState struct {
+	Connection string `json:"connection"`
+	Running    bool   `json:"running"`
+	// Queued is true while the test waits for a free slot
+	Queued bool `json:"queued,omitempty"`
+	// QueuePosition is the 1-based position in the queue while queued
+	QueuePosition int      `json:"queue_position,omitempty"`
+	Phases        []string `json:"phases"`
+	// StartedAt is when the test started, or was triggered while queued
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Result     *storage.TestResult `json:"result,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	// Cancelled is true if the test was cancelled via the API
+	Cancelled bool `json:"cancelled,omitempty"`
+
+	// cancel aborts the running test
+	cancel context.CancelFunc
+	// runner and opts are used to start the test once it leaves the queue
+	runner *speedtest.MultiWANRunner
+	opts   speedtest.RunOptions
+}
+
+// triggerQueueResponse describes the queue of triggered tests.
+type triggerQueueResponse struct {
+	// Concurrency is the number of triggered tests that run at the same time
+	Concurrency int `json:"concurrency"`
+	// QueueDepth is the maximum number of waiting tests
+	QueueDepth int `json:"queue_depth"`
+	// Running is the number of triggered tests currently running
+	Running int `json:"running"`
+	// Queued lists the connections of waiting tests, next first
+	Queued []string `json:"queued"`
+}
+
+// inLocation converts the state's timestamps to the given location.
+func (t *triggerState) inLocation(loc *time.Location) {
+	t.StartedAt = t.StartedAt.In(loc)
+	if t.FinishedAt != nil {
+		finished := t.FinishedAt.In(loc)
+		t.FinishedAt = &finished
+	}
+	if t.Result != nil {
+		result := *t.Result
+		result.InLocation(loc)
+		t.Result = &result
+	}
+}
+
+// currentRunner returns the active speedtest runner (may be nil).
+func (s *Server) currentRunner() *speedtest.MultiWANRunner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runner
+}
+
+// handleTriggerTest starts a speedtest for a connection in the background.
+func (s *Server) handleTriggerTest(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	runner := s.currentRunner()
+	if runner == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Speedtest runner not available")
+		return
+	}
+
+	if !hasConnection(runner, name) {
+		s.writeError(w, http.StatusNotFound, "Connection not found or disabled")
+		return
+	}
+
+	opts, err := parseRunOptions(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid test phases: "+err.Error())
+		return
+	}
+
+	cfg := s.currentConfig().Webserver
+	s.triggersMu.Lock()
+	if state, ok := s.triggers[name]; ok && (state.Running || state.Queued) {
+		s.triggersMu.Unlock()
+		s.writeError(w, http.StatusTooManyRequests, "A test for this connection is already running or queued")
+		return
+	}
+	state := &triggerState{
+		Connection: name,
+		Phases:     opts.Phases(),
+		StartedAt:  time.Now(),
+		runner:     runner,
+		opts:       opts,
+	}
+
+	// Start right away if a slot is free, otherwise wait in the queue
+	var ctx context.Context
+	if s.activeTriggers < cfg.TriggerConcurrency {
+		s.activeTriggers++
+		ctx = s.startTriggerLocked(state)
+	} else if len(s.triggerQueue) < cfg.TriggerQueueDepth {
+		state.Queued = true
+		s.triggerQueue = append(s.triggerQueue, state)
+	} else {
+		s.triggersMu.Unlock()
+		s.writeError(w, http.StatusTooManyRequests, "Too many triggered tests, the queue is full")
+		return
+	}
+	s.triggers[name] = state
+	current := s.snapshotTriggerLocked(state)
+	if ctx != nil {
+		s.background.Add(1)
+		go s.runTriggerWorker(ctx, state)
+	}
+	s.triggersMu.Unlock()
+	current.inLocation(s.currentConfig().Location())
+
+	s.logger.Info("Manually triggered speedtest",
+		zap.String("connection", name),
+		zap.Strings("phases", opts.Phases()),
+		zap.Int("queue_position", current.QueuePosition),
+		zap.String("remote", r.RemoteAddr),
+	)
+
+	response := okResponse(current)
+	response.Message = "Test started"
+	if current.Queued {
+		response.Message = fmt.Sprintf("Test queued at position %d", current.QueuePosition)
+	}
+	s.writeJSON(w, http.StatusAccepted, response)
+}
+
+// startTriggerLocked marks a triggered test as running and returns the
+// context it runs with. Must be called with triggersMu held.
+func (s *Server) startTriggerLocked(state *triggerState) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), triggerTimeout)
+	state.cancel = cancel
+	state.Running = true
+	state.Queued = false
+	state.StartedAt = time.Now()
+	return ctx
+}
+
+// snapshotTriggerLocked returns a copy of the state with its current queue
+// position. Must be called with triggersMu held.
+func (s *Server) snapshotTriggerLocked(state *triggerState) triggerState {
+	current := *state
+	for i, queued := range s.triggerQueue {
+		if queued == state {
+			current.QueuePosition = i + 1
+			break
+		}
+	}
+	return current
+}
+
+// runTriggerWorker runs a triggered test and then the queued tests, one
+// after another, until the queue is empty.
+func (s *Server) runTriggerWorker(ctx context.Context, state *triggerState) {
+	defer s.background.Done()
+
+	for state != nil {
+		s.runTriggeredTest(ctx, state.runner, state.Connection, state.opts)
+		state.cancel()
+		ctx, state = s.nextTrigger()
+	}
+}
+
+// nextTrigger starts the next queued test, or releases the worker's slot if
+// the queue is empty or the concurrency was lowered by a config reload.
+func (s *Server) nextTrigger() (context.Context, *triggerState) {
+	s.triggersMu.Lock()
+	defer s.triggersMu.Unlock()
+
+	if len(s.triggerQueue) == 0 || s.activeTriggers > s.currentConfig().Webserver.TriggerConcurrency {
+		s.activeTriggers--
+		return nil, nil
+	}
+
+	state := s.triggerQueue[0]
+	s.triggerQueue = s.triggerQueue[1:]
+	s.logger.Info("Starting queued speedtest", zap.String("connection", state.Connection))
+	return s.startTriggerLocked(state), state
+}
+
+// handleGetTriggerQueue returns the number of running triggered tests and
+// the connections waiting in the queue.
+func (s *Server) handleGetTriggerQueue(w http.ResponseWriter, r *http.Request) {
+	cfg := s.currentConfig().Webserver
+	response := triggerQueueResponse{
+		Concurrency: cfg.TriggerConcurrency,
+		QueueDepth:  max(cfg.TriggerQueueDepth, 0),
+		Queued:      []string{},
+	}
+
+	s.triggersMu.Lock()
+	for _, state := range s.triggers {
+		if state.Running {
+			response.Running++
+		}
+	}
+	for _, state := range s.triggerQueue {
+		response.Queued = append(response.Queued, state.Connection)
+	}
+	s.triggersMu.Unlock()
+
+	s.writeJSON(w, http.StatusOK, okResponse(response))
+}
+
+// handleGetTriggerStatus returns the state of the last triggered test for a connection.
+func (s *Server) handleGetTriggerStatus(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	s.triggersMu.Lock()
+	state, ok := s.triggers[name]
+	var current triggerState
+	if ok {
+		current = s.snapshotTriggerLocked(state)
+	}
+	s.triggersMu.Unlock()
+
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "No test has been triggered for this connection")
+		return
+	}
+	current.inLocation(s.currentConfig().Location())
+
+	s.writeJSON(w, http.StatusOK, okResponse(current))
+}
+
+// handleCancelTriggeredTest cancels the running or queued triggered test of a
+// connection. A running test aborts asynchronously; its result is not saved.
+func (s *Server) handleCancelTriggeredTest(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	s.triggersMu.Lock()
+	state, ok := s.triggers[name]
+	if !ok || (!state.Running && !state.Queued) {
+		s.triggersMu.Unlock()
+		s.writeError(w, http.StatusNotFound, "No test is running or queued for this connection")
+		return
+	}
+	state.Cancelled = true
+	if state.Queued {
+		s.dequeueTriggerLocked(state, "test cancelled")
+	} else {
+		state.cancel()
+	}
+	current := *state
+	s.triggersMu.Unlock()
+	current.inLocation(s.currentConfig().Location())
+
+	s.logger.Info("Cancelled triggered speedtest",
+		zap.String("connection", name),
+		zap.String("remote", r.RemoteAddr),
+	)
+
+	response := okResponse(current)
+	response.Message = "Test cancelled"
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// runTriggeredTest executes the test, saves the result and updates the trigger state.
+// Results of tests cancelled via the API are discarded.
+func (s *Server) runTriggeredTest(ctx context.Context, runner *speedtest.MultiWANRunner, name string, opts speedtest.RunOptions) {
+	result, err := runner.RunConnection(ctx, name, opts)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		s.logger.Info("Triggered speedtest aborted", zap.String("connection", name))
+		finished := time.Now()
+		s.triggersMu.Lock()
+		state := s.triggers[name]
+		state.Running = false
+		state.FinishedAt = &finished
+		state.Error = "test cancelled"
+		s.triggersMu.Unlock()
+		return
+	}
+	if err != nil {
+		if result == nil {
+			result = &speedtest.Result{
+				ConnectionName: name,
+				Timestamp:      time.Now(),
+			}
+		}
+		if result.Error == "" {
+			result.Error = err.Error()
+		}
+	}
+
+	result.Source = speedtest.SourceAPI
+	speedtest.LogCompleted(s.logger, result)
+	UpdateMetricsForResult(result)
+
+	dbResult := storage.FromSpeedtestResult(result, s.currentConfig().General.RoundDecimals)
+	saveCtx, cancel := context.WithTimeout(context.Background(), triggerSaveTimeout)
+	saveErr := s.storage.SaveResult(saveCtx, dbResult)
+	cancel()
+	if saveErr != nil {
+		s.logger.Error("Failed to save triggered speedtest result",
+			zap.String("connection", name),
+			zap.Error(saveErr),
+		)
+	} else {
+		s.cache.invalidate()
+	}
+
+	finished := time.Now()
+	s.triggersMu.Lock()
+	state := s.triggers[name]
+	state.Running = false
+	state.FinishedAt = &finished
+	state.Result = dbResult
+	state.Error = result.Error
+	if saveErr != nil && state.Error == "" {
+		state.Error = "failed to save result"
+	}
+	s.triggersMu.Unlock()
+}
+
+// dequeueTriggerLocked removes a waiting test from the queue and finishes it
+// with the given error. Must be called with triggersMu held.
+func (s *Server) dequeueTriggerLocked(state *triggerState, reason string) {
+	for i, queued := range s.triggerQueue {
+		if queued == state {
+			s.triggerQueue = append(s.triggerQueue[:i], s.triggerQueue[i+1:]...)
+			break
+		}
+	}
+	finished := time.Now()
+	state.Queued = false
+	state.FinishedAt = &finished
+	state.Error = reason
+}
+
+// runningTests returns the number of triggered tests that are still running.
+func (s *Server) runningTests() int {
+	s.triggersMu.Lock()
+	defer s.triggersMu.Unlock()
+
+	running := 0
+	for _, state := range s.triggers {
+		if state.Running {
+			running++
+		}
+	}
+	return running
+}
+
+// waitForTriggeredTests waits until all triggered tests have finished and
+// saved their results, or until ctx is done.
+func (s *Server) waitForTriggeredTests(ctx context.Context) {
+	// Queued tests would otherwise start one after another during shutdown
+	s.triggersMu.Lock()
+	for len(s.triggerQueue) > 0 {
+		s.dequeueTriggerLocked(s.triggerQueue[0], "server shutting down")
+	}
+	s.triggersMu.Unlock()
+
+	running := s.runningTests()
+	if running == 0 {
+		return
+	}
+	s.logger.Info("Waiting for triggered speedtests to finish", zap.Int("tests", running))
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("Triggered speedtests finished", zap.Int("tests", running))
+	case <-ctx.Done():
+		s.logger.Warn("Shutdown timeout reached, abandoning triggered speedtests",
+			zap.Int("tests", s.runningTests()),
+		)
+	}
+}
+
+// parseRunOptions builds the test phases for a triggered run from the query.
+// "phases" selects a comma-separated list of phases; "latency", "download"
+// and "upload" enable or disable single phases. All phases run by default.
+func parseRunOptions(r *http.Request) (speedtest.RunOptions, error) {
+	query := r.URL.Query()
+	opts := speedtest.DefaultRunOptions()
+
+	if phases := query.Get("phases"); phases != "" {
+		opts = speedtest.RunOptions{}
+		for _, phase := range strings.Split(phases, ",") {
+			switch strings.TrimSpace(phase) {
+			case speedtest.PhaseLatency:
+				opts.Latency = true
+			case speedtest.PhaseDownload:
+				opts.Download = true
+			case speedtest.PhaseUpload:
+				opts.Upload = true
+			default:
+				return opts, fmt.Errorf("unknown phase %q (must be latency, download, or upload)", phase)
+			}
+		}
+	}
+
+	toggles := []struct {
+		name    string
+		enabled *bool
+	}{
+		{speedtest.PhaseLatency, &opts.Latency},
+		{speedtest.PhaseDownload, &opts.Download},
+		{speedtest.PhaseUpload, &opts.Upload},
+	}
+	for _, toggle := range toggles {
+		value := query.Get(toggle.name)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid value for %s: %q", toggle.name, value)
+		}
+		*toggle.enabled = enabled
+	}
+
+	if len(opts.Phases()) == 0 {
+		return opts, fmt.Errorf("at least one phase must be enabled")
+	}
+	return opts, nil
+}
+
+// hasConnection reports whether the runner tests the named connection.
+func hasConnection(runner *speedtest.MultiWANRunner, name string) bool {
+	for _, conn := range runner.GetConnections() {
+		if conn.Name == name {
+			return true
+		}
+	}
+	return false
+}
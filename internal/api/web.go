@@ -10,6 +10,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 	"github.com/lan-dot-party/flowgauge/internal/storage"
 	"github.com/lan-dot-party/flowgauge/pkg/version"
 )
@@ -19,6 +21,20 @@ type DashboardData struct {
 	Version     string
 	Connections []ConnectionData
 	LastUpdate  string
+	TimeZone    string // IANA name for client-side formatting; empty means browser local time
+	SpeedUnit   string // Display label for speeds (e.g. "Mbps")
+	Groups      []string
+	Group       string
+	ChartWindow string // Mini chart range as a duration string (e.g. "2h")
+	// AllowTriggers shows the "Run Test" buttons (webserver.allow_triggers)
+	AllowTriggers bool
+	// ReadOnly shows the maintenance badge and hides the "Run Test" buttons
+	ReadOnly bool
+	// EmbedAssets loads scripts and fonts from /assets/ instead of CDNs
+	EmbedAssets bool
+	// Theme is the theme the page is rendered in (config.ThemeDark or
+	// config.ThemeLight)
+	Theme string
 }
 
 // ConnectionData contains connection info with latest result and chart data.
@@ -27,25 +43,58 @@ type ConnectionData struct {
 	SourceIP     string
 	DSCP         int
 	Enabled      bool
+	Group        string
+	Primary      bool
 	LatestResult *storage.TestResult
 	ChartData    ChartData
 }
 
-// ChartData contains data for the charts.
+// ChartData contains data for the charts. Values are nil (null in JSON,
+// a gap in the chart) for points without a successful measurement.
 type ChartData struct {
-	Labels   []string  `json:"labels"`
-	Download []float64 `json:"download"`
-	Upload   []float64 `json:"upload"`
-	Latency  []float64 `json:"latency"`
+	Labels   []string   `json:"labels"`
+	Download []*float64 `json:"download"`
+	Upload   []*float64 `json:"upload"`
+	Latency  []*float64 `json:"latency"`
+}
+
+// templateFuncs returns the functions available to dashboard templates.
+func (s *Server) templateFuncs() template.FuncMap {
+	unit := speedtest.SpeedUnit(s.currentConfig().General.SpeedUnit)
+	return template.FuncMap{
+		"json":  jsonFunc,
+		"speed": unit.Convert,
+	}
+}
+
+// themeCookie holds the theme picked with the dashboard's toggle.
+const themeCookie = "flowgauge_theme"
+
+// dashboardTheme returns the theme to render the dashboard in: the one the
+// visitor picked, which the toggle stores in a cookie, or webserver.theme.
+// Rendering it on the server avoids a flash of the other theme on load.
+func (s *Server) dashboardTheme(r *http.Request) string {
+	if c, err := r.Cookie(themeCookie); err == nil {
+		if c.Value == config.ThemeDark || c.Value == config.ThemeLight {
+			return c.Value
+		}
+	}
+	return s.currentConfig().Webserver.Theme
 }
 
 // handleDashboard serves the main dashboard page.
 func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
-	data := s.getDashboardData(r.Context(), 2*time.Hour) // Default: 2h for mini charts
-	
-	funcMap := template.FuncMap{
-		"json": jsonFunc,
+	group := r.URL.Query().Get("group")
+	if !s.hasGroup(group) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
 	}
+	window := s.currentConfig().Webserver.Dashboard.ChartWindow
+	data := s.getDashboardData(r.Context(), window, group)
+	// Per visitor, so not part of the cached data
+	data.Theme = s.dashboardTheme(r)
+	
+	funcMap := s.templateFuncs()
 	
 	tmpl := template.Must(template.New("dashboard").Funcs(funcMap).Parse(dashboardTemplate))
 	
@@ -58,11 +107,15 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 
 // handleDashboardPartial returns dashboard cards as HTML (for HTMX updates).
 func (s *Server) handleDashboardPartial(w http.ResponseWriter, r *http.Request) {
-	data := s.getDashboardData(r.Context(), 2*time.Hour)
-	
-	funcMap := template.FuncMap{
-		"json": jsonFunc,
+	group := r.URL.Query().Get("group")
+	if !s.hasGroup(group) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
 	}
+	window := s.currentConfig().Webserver.Dashboard.ChartWindow
+	data := s.getDashboardData(r.Context(), window, group)
+	
+	funcMap := s.templateFuncs()
 	
 	tmpl := template.Must(template.New("cards").Funcs(funcMap).Parse(dashboardCardsTemplate))
 	
@@ -72,68 +125,222 @@ func (s *Server) handleDashboardPartial(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// hasGroup returns true if group is empty (all connections) or the group of
+// a configured connection. Unknown groups would only add empty dashboards to
+// the cache.
+func (s *Server) hasGroup(group string) bool {
+	return group == "" || len(s.currentConfig().GetConnectionsByGroup(group)) > 0
+}
+
 // handleConnectionChartData returns chart data for a specific connection.
 func (s *Server) handleConnectionChartData(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	connectionName := chi.URLParam(r, "name")
+	// Only configured connections are cached, so a name can't grow the cache
+	if s.currentConfig().GetConnectionByName(connectionName) == nil {
+		s.writeError(w, http.StatusNotFound, "Connection not found")
+		return
+	}
 	
 	// Parse duration from query param (default: 24h for modal)
 	durationStr := r.URL.Query().Get("duration")
 	duration := 24 * time.Hour
 	if durationStr != "" {
-		if d, err := time.ParseDuration(durationStr); err == nil {
+		if d, err := config.ParseDuration(durationStr); err == nil && d > 0 {
 			duration = d
 		}
 	}
 	
 	chartData := s.getConnectionChartData(ctx, connectionName, duration)
 	
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(chartData); err != nil {
+	body, err := json.Marshal(chartData)
+	if err != nil {
 		s.logger.Error("Failed to encode chart data", zap.Error(err))
+		s.writeError(w, http.StatusInternalServerError, "failed to encode chart data")
+		return
 	}
+
+	// Polling dashboards revalidate with If-None-Match and get a 304 while
+	// the data is unchanged
+	ttl := s.currentConfig().Webserver.DashboardCacheTTL
+	writeCached(w, r, "application/json", chartCacheControl(ttl), body)
 }
 
 // getConnectionChartData fetches chart data for a specific connection.
+// Results are cached for the configured dashboard cache TTL.
 func (s *Server) getConnectionChartData(ctx context.Context, connectionName string, duration time.Duration) ChartData {
+	cacheKey := "chart:" + connectionName + ":" + duration.String()
+	if cached, ok := s.cache.get(cacheKey); ok {
+		return cached.(ChartData)
+	}
+
+	// Fetch the whole range (up to the storage ceiling) and downsample below,
+	// so long ranges aren't truncated to the most recent results
 	filter := storage.ResultFilter{
 		ConnectionName: connectionName,
 		Since:          time.Now().Add(-duration),
-		Limit:          200,
 	}
 	
 	results, _ := s.storage.GetResults(ctx, filter)
-	
-	chartData := ChartData{
-		Labels:   make([]string, 0, len(results)),
-		Download: make([]float64, 0, len(results)),
-		Upload:   make([]float64, 0, len(results)),
-		Latency:  make([]float64, 0, len(results)),
+	cfg := s.currentConfig()
+	loc := cfg.Location()
+	unit := speedtest.SpeedUnit(cfg.General.SpeedUnit)
+
+	labelFormat := "15:04"
+	if duration > 24*time.Hour {
+		labelFormat = "01/02 15:04"
 	}
-	
-	// Reverse order for chronological display
+
+	// Reverse order for chronological display, skipping failed tests
+	successful := make([]storage.TestResult, 0, len(results))
 	for i := len(results) - 1; i >= 0; i-- {
-		r := results[i]
-		if r.Error == "" {
-			chartData.Labels = append(chartData.Labels, r.CreatedAt.Local().Format("15:04"))
-			chartData.Download = append(chartData.Download, r.DownloadMbps)
-			chartData.Upload = append(chartData.Upload, r.UploadMbps)
-			chartData.Latency = append(chartData.Latency, r.LatencyMs)
+		if results[i].Error == "" {
+			successful = append(successful, results[i])
 		}
 	}
+	var points []chartPoint
+	if limit := chartPointLimit(cfg.Webserver.Dashboard, duration); len(successful) > limit {
+		points = downsampleResults(successful, chartBucketSize(duration, limit))
+	} else {
+		points = make([]chartPoint, len(successful))
+		for i, r := range successful {
+			points[i].start = r.CreatedAt
+			points[i].add(r)
+		}
+	}
+	
+	chartData := ChartData{
+		Labels:   make([]string, 0, len(points)),
+		Download: make([]*float64, 0, len(points)),
+		Upload:   make([]*float64, 0, len(points)),
+		Latency:  make([]*float64, 0, len(points)),
+	}
+	for _, p := range points {
+		chartData.Labels = append(chartData.Labels, p.start.In(loc).Format(labelFormat))
+		chartData.Download = append(chartData.Download, p.download.value(unit.Convert))
+		chartData.Upload = append(chartData.Upload, p.upload.value(unit.Convert))
+		chartData.Latency = append(chartData.Latency, p.latency.value(nil))
+	}
 	
+	s.cache.set(cacheKey, chartData)
 	return chartData
 }
 
+// chartPointLimit returns the number of chart points above which results are
+// averaged into time buckets. It scales with the duration and is clamped to
+// the configured bounds.
+func chartPointLimit(cfg config.DashboardConfig, duration time.Duration) int {
+	limit := int(duration.Hours() * float64(cfg.ChartPointsPerHour))
+	return max(cfg.MinChartPoints, min(limit, cfg.MaxChartPoints))
+}
+
+// chartBucketSizes are the bucket sizes used for downsampling, smallest first.
+var chartBucketSizes = []time.Duration{
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// chartBucketSize returns the smallest bucket size that splits duration into
+// at most limit buckets (e.g. hourly for 7 days at 200 points).
+func chartBucketSize(duration time.Duration, limit int) time.Duration {
+	for _, size := range chartBucketSizes {
+		if duration/size <= time.Duration(limit) {
+			return size
+		}
+	}
+	return chartBucketSizes[len(chartBucketSizes)-1]
+}
+
+// chartMetric sums the successful measurements of a metric in a chart point.
+type chartMetric struct {
+	sum   float64
+	count int
+}
+
+// value returns the average converted with convert (if set), or nil
+// without successful measurements.
+func (m chartMetric) value(convert func(float64) float64) *float64 {
+	if m.count == 0 {
+		return nil
+	}
+	v := m.sum / float64(m.count)
+	if convert != nil {
+		v = convert(v)
+	}
+	return &v
+}
+
+// chartPoint is a result or a bucket of results in a chart. Phases that
+// failed store 0, so each metric only counts results whose phase succeeded.
+type chartPoint struct {
+	start                     time.Time
+	download, upload, latency chartMetric
+}
+
+// add adds the successful measurements of a result to the point.
+func (p *chartPoint) add(r storage.TestResult) {
+	if r.DownloadOK {
+		p.download.sum += r.DownloadMbps
+		p.download.count++
+	}
+	if r.UploadOK {
+		p.upload.sum += r.UploadMbps
+		p.upload.count++
+	}
+	if r.LatencyOK {
+		p.latency.sum += r.LatencyMs
+		p.latency.count++
+	}
+}
+
+// downsampleResults averages chronologically ordered results within time
+// buckets of the given size. Each returned point is timestamped with the
+// start of its bucket.
+func downsampleResults(results []storage.TestResult, bucket time.Duration) []chartPoint {
+	var points []chartPoint
+
+	for _, r := range results {
+		start := r.CreatedAt.Truncate(bucket)
+		if len(points) == 0 || !points[len(points)-1].start.Equal(start) {
+			points = append(points, chartPoint{start: start})
+		}
+		points[len(points)-1].add(r)
+	}
+
+	return points
+}
+
 // getDashboardData collects all data needed for the dashboard.
-func (s *Server) getDashboardData(ctx context.Context, chartDuration time.Duration) DashboardData {
+// If group is set, only connections in that group are included.
+// Results are cached for the configured dashboard cache TTL.
+func (s *Server) getDashboardData(ctx context.Context, chartDuration time.Duration, group string) DashboardData {
+	cacheKey := "dashboard:" + chartDuration.String() + ":" + group
+	if cached, ok := s.cache.get(cacheKey); ok {
+		return cached.(DashboardData)
+	}
+
+	cfg := s.currentConfig()
+	loc := cfg.Location()
 	data := DashboardData{
 		Version:    version.GetShortVersion(),
-		LastUpdate: time.Now().Local().Format("15:04:05"),
+		LastUpdate: time.Now().In(loc).Format("15:04:05"),
+		SpeedUnit:  speedtest.SpeedUnit(cfg.General.SpeedUnit).Label(),
+		Groups:     cfg.GetGroups(),
+		Group:      group,
+
+		ChartWindow: chartDuration.String(),
+
+		AllowTriggers: cfg.Webserver.AllowTriggers,
+		ReadOnly:      s.ReadOnly(),
+		EmbedAssets:   cfg.Webserver.EmbedAssets,
+	}
+	if loc != time.Local {
+		data.TimeZone = loc.String()
 	}
 	
 	// Get latest results
 	latestResults, _ := s.storage.GetLatestResults(ctx)
+	s.localizeResults(latestResults)
 	
 	// Build map for quick lookup
 	latestMap := make(map[string]*storage.TestResult)
@@ -141,13 +348,18 @@ func (s *Server) getDashboardData(ctx context.Context, chartDuration time.Durati
 		latestMap[latestResults[i].ConnectionName] = &latestResults[i]
 	}
 	
-	// Build connection data with chart data for each
-	for _, conn := range s.fullConfig.Connections {
+	// Build connection data with chart data for each, the primary first
+	for _, conn := range cfg.GetConnectionsPrimaryFirst() {
+		if group != "" && conn.Group != group {
+			continue
+		}
 		connData := ConnectionData{
 			Name:      conn.Name,
 			SourceIP:  conn.SourceIP,
 			DSCP:      conn.DSCP,
 			Enabled:   conn.Enabled,
+			Group:     conn.Group,
+			Primary:   conn.Primary,
 			ChartData: s.getConnectionChartData(ctx, conn.Name, chartDuration),
 		}
 		if result, ok := latestMap[conn.Name]; ok {
@@ -156,25 +368,29 @@ func (s *Server) getDashboardData(ctx context.Context, chartDuration time.Durati
 		data.Connections = append(data.Connections, connData)
 	}
 	
+	s.cache.set(cacheKey, data)
 	return data
 }
 
 const dashboardCardsTemplate = `
 {{range $idx, $conn := .Connections}}
-<div class="connection-card {{if not $conn.Enabled}}disabled{{end}}" data-connection="{{$conn.Name}}">
+<div class="connection-card {{if not $conn.Enabled}}disabled{{end}}{{if $conn.Primary}} primary{{end}}" data-connection="{{$conn.Name}}">
     <div class="card-header">
-        <span class="connection-name">{{$conn.Name}}</span>
-        {{if $conn.Enabled}}<span class="status-badge active">Active</span>{{else}}<span class="status-badge">Disabled</span>{{end}}
+        <span class="connection-name">{{$conn.Name}}{{if $conn.Primary}} <span class="primary-badge" title="Primary connection">Primary</span>{{end}}</span>
+        <div class="card-actions">
+            {{if and $conn.Enabled $.AllowTriggers (not $.ReadOnly)}}<button class="run-test-btn" onclick="runTest(this, '{{$conn.Name}}')" title="Run a speedtest now">▶ Run Test</button>{{end}}
+            {{if $conn.Enabled}}<span class="status-badge active">Active</span>{{else}}<span class="status-badge">Disabled</span>{{end}}
+        </div>
     </div>
     {{if $conn.LatestResult}}
     <div class="metrics-row">
         <div class="metric">
-            <span class="metric-value download">{{printf "%.1f" $conn.LatestResult.DownloadMbps}}</span>
-            <span class="metric-label">↓ Mbps</span>
+            <span class="metric-value download">{{printf "%.1f" (speed $conn.LatestResult.DownloadMbps)}}</span>
+            <span class="metric-label">↓ {{$.SpeedUnit}}</span>
         </div>
         <div class="metric">
-            <span class="metric-value upload">{{printf "%.1f" $conn.LatestResult.UploadMbps}}</span>
-            <span class="metric-label">↑ Mbps</span>
+            <span class="metric-value upload">{{printf "%.1f" (speed $conn.LatestResult.UploadMbps)}}</span>
+            <span class="metric-label">↑ {{$.SpeedUnit}}</span>
         </div>
         <div class="metric">
             <span class="metric-value latency">{{printf "%.0f" $conn.LatestResult.LatencyMs}}</span>
@@ -189,7 +405,7 @@ const dashboardCardsTemplate = `
     </div>
     <div class="card-footer">
         <span class="server-info">{{$conn.LatestResult.ServerName}}</span>
-        <span class="timestamp">{{$conn.LatestResult.CreatedAt.Local.Format "15:04"}}</span>
+        <span class="timestamp">{{$conn.LatestResult.CreatedAt.Format "15:04"}}</span>
     </div>
     {{else}}
     <div class="card-body empty">
@@ -201,16 +417,38 @@ const dashboardCardsTemplate = `
 `
 
 const dashboardTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="en" data-theme="{{.Theme}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>FlowGauge Dashboard</title>
+    <script>
+        // Store the theme in a cookie, so the server renders the page in it,
+        // and in localStorage, which restores the cookie if it was cleared
+        function setTheme(theme) {
+            document.documentElement.dataset.theme = theme;
+            document.cookie = 'flowgauge_theme=' + theme + '; path=/; max-age=31536000; SameSite=Lax';
+            try { localStorage.setItem('flowgauge_theme', theme); } catch (e) {}
+        }
+        (function() {
+            let saved = null;
+            try { saved = localStorage.getItem('flowgauge_theme'); } catch (e) {}
+            if ((saved === 'dark' || saved === 'light') && !document.cookie.split('; ').includes('flowgauge_theme=' + saved)) {
+                setTheme(saved);
+            }
+        })();
+    </script>
+    {{if .EmbedAssets}}
+    <script src="/assets/htmx.min.js"></script>
+    <script src="/assets/chart.umd.min.js"></script>
+    <link href="/assets/fonts.css" rel="stylesheet">
+    {{else}}
     <script src="https://unpkg.com/htmx.org@1.9.10"></script>
     <script src="https://cdn.jsdelivr.net/npm/chart.js@4.4.1/dist/chart.umd.min.js"></script>
     <link rel="preconnect" href="https://fonts.googleapis.com">
     <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
     <link href="https://fonts.googleapis.com/css2?family=JetBrains+Mono:wght@400;500;700&family=Space+Grotesk:wght@400;500;600;700&display=swap" rel="stylesheet">
+    {{end}}
     <style>
         :root {
             --bg-dark: #0a0a0f;
@@ -231,6 +469,21 @@ const dashboardTemplate = `<!DOCTYPE html>
             --latency-color: #f59e0b;
             --glow-green: 0 0 20px rgba(16, 185, 129, 0.3);
             --glow-cyan: 0 0 20px rgba(6, 182, 212, 0.3);
+            --chart-grid: rgba(39, 39, 42, 0.5);
+        }
+        
+        [data-theme="light"] {
+            --bg-dark: #f4f4f5;
+            --bg-card: #ffffff;
+            --bg-card-hover: #fafafa;
+            --bg-modal: rgba(24, 24, 27, 0.6);
+            --text-primary: #18181b;
+            --text-secondary: #52525b;
+            --text-muted: #71717a;
+            --border: #d4d4d8;
+            --glow-green: 0 0 12px rgba(16, 185, 129, 0.2);
+            --glow-cyan: 0 0 12px rgba(6, 182, 212, 0.2);
+            --chart-grid: rgba(212, 212, 216, 0.8);
         }
         
         * { margin: 0; padding: 0; box-sizing: border-box; }
@@ -298,6 +551,40 @@ const dashboardTemplate = `<!DOCTYPE html>
             font-size: 0.875rem;
         }
         
+        .read-only-badge {
+            padding: 0.25rem 0.75rem;
+            border-radius: 2rem;
+            font-size: 0.75rem;
+            font-weight: 600;
+            background: rgba(245, 158, 11, 0.15);
+            color: var(--accent-amber);
+        }
+        
+        .group-filter {
+            background: var(--bg-card);
+            color: var(--text-primary);
+            border: 1px solid var(--border);
+            border-radius: 0.5rem;
+            padding: 0.4rem 0.75rem;
+            font-family: inherit;
+            font-size: 0.875rem;
+            cursor: pointer;
+        }
+        
+        .theme-toggle {
+            background: var(--bg-card);
+            color: var(--text-primary);
+            border: 1px solid var(--border);
+            border-radius: 0.5rem;
+            padding: 0.4rem 0.6rem;
+            font-size: 0.875rem;
+            cursor: pointer;
+        }
+        
+        .theme-toggle:hover {
+            background: var(--bg-card-hover);
+        }
+        
         .update-indicator {
             display: flex;
             align-items: center;
@@ -343,6 +630,25 @@ const dashboardTemplate = `<!DOCTYPE html>
             opacity: 0.4;
         }
         
+        .connection-card.primary {
+            border-color: var(--accent-violet);
+            box-shadow: 0 0 0 1px var(--accent-violet);
+        }
+        
+        .primary-badge {
+            padding: 0.15rem 0.5rem;
+            margin-left: 0.5rem;
+            border-radius: 2rem;
+            font-family: 'Space Grotesk', sans-serif;
+            font-size: 0.65rem;
+            font-weight: 600;
+            text-transform: uppercase;
+            letter-spacing: 0.05em;
+            vertical-align: middle;
+            background: rgba(139, 92, 246, 0.15);
+            color: var(--accent-violet);
+        }
+        
         .card-header {
             padding: 1rem 1.5rem;
             display: flex;
@@ -375,6 +681,80 @@ const dashboardTemplate = `<!DOCTYPE html>
             box-shadow: inset 0 0 10px rgba(16, 185, 129, 0.1);
         }
         
+        .card-actions {
+            display: flex;
+            align-items: center;
+            gap: 0.5rem;
+        }
+        
+        .run-test-btn {
+            padding: 0.25rem 0.75rem;
+            border-radius: 2rem;
+            border: 1px solid var(--border);
+            background: transparent;
+            color: var(--text-secondary);
+            font-family: inherit;
+            font-size: 0.7rem;
+            font-weight: 600;
+            text-transform: uppercase;
+            letter-spacing: 0.05em;
+            cursor: pointer;
+            transition: all 0.2s ease;
+        }
+        
+        .run-test-btn:hover:not(:disabled) {
+            border-color: var(--accent-cyan);
+            color: var(--text-primary);
+        }
+        
+        .run-test-btn:disabled {
+            cursor: wait;
+            opacity: 0.7;
+        }
+        
+        .spinner {
+            display: inline-block;
+            width: 0.7rem;
+            height: 0.7rem;
+            margin-right: 0.35rem;
+            border: 2px solid var(--border);
+            border-top-color: var(--accent-cyan);
+            border-radius: 50%;
+            vertical-align: -0.1rem;
+            animation: spin 0.8s linear infinite;
+        }
+        
+        @keyframes spin {
+            to { transform: rotate(360deg); }
+        }
+        
+        .toast {
+            position: fixed;
+            bottom: 2rem;
+            right: 2rem;
+            padding: 0.875rem 1.25rem;
+            border-radius: 0.75rem;
+            background: var(--bg-card);
+            border: 1px solid var(--border);
+            color: var(--text-primary);
+            font-size: 0.875rem;
+            box-shadow: 0 10px 30px rgba(0, 0, 0, 0.4);
+            opacity: 0;
+            transform: translateY(10px);
+            transition: all 0.3s ease;
+            pointer-events: none;
+            z-index: 2000;
+        }
+        
+        .toast.visible {
+            opacity: 1;
+            transform: translateY(0);
+        }
+        
+        .toast.error {
+            border-color: var(--accent-rose);
+        }
+        
         .metrics-row {
             display: grid;
             grid-template-columns: repeat(3, 1fr);
@@ -635,33 +1015,44 @@ const dashboardTemplate = `<!DOCTYPE html>
                 <span class="version">v{{.Version}}</span>
             </div>
             <div class="header-info">
+                {{if .ReadOnly}}<span class="read-only-badge" title="Maintenance: tests are paused, data may be outdated">Read-only</span>{{end}}
+                {{if .Groups}}
+                <select class="group-filter" onchange="filterGroup(this.value)">
+                    <option value="">All groups</option>
+                    {{range .Groups}}<option value="{{.}}" {{if eq . $.Group}}selected{{end}}>{{.}}</option>{{end}}
+                </select>
+                {{end}}
                 <div class="update-indicator">
                     <span class="pulse"></span>
                     <span>Live</span>
                 </div>
                 <span id="last-update">{{.LastUpdate}}</span>
+                <button class="theme-toggle" onclick="toggleTheme()" title="Switch between dark and light theme">◐</button>
             </div>
         </header>
         
         <div id="connections" class="connections-grid" 
-             hx-get="/dashboard/cards" 
+             hx-get="/dashboard/cards{{if .Group}}?group={{.Group}}{{end}}" 
              hx-trigger="every 30s"
              hx-swap="innerHTML">
             {{range $idx, $conn := .Connections}}
-            <div class="connection-card {{if not $conn.Enabled}}disabled{{end}}" data-connection="{{$conn.Name}}">
+            <div class="connection-card {{if not $conn.Enabled}}disabled{{end}}{{if $conn.Primary}} primary{{end}}" data-connection="{{$conn.Name}}">
                 <div class="card-header">
-                    <span class="connection-name">{{$conn.Name}}</span>
-                    {{if $conn.Enabled}}<span class="status-badge active">Active</span>{{else}}<span class="status-badge">Disabled</span>{{end}}
+                    <span class="connection-name">{{$conn.Name}}{{if $conn.Primary}} <span class="primary-badge" title="Primary connection">Primary</span>{{end}}</span>
+                    <div class="card-actions">
+                        {{if and $conn.Enabled $.AllowTriggers (not $.ReadOnly)}}<button class="run-test-btn" onclick="runTest(this, '{{$conn.Name}}')" title="Run a speedtest now">▶ Run Test</button>{{end}}
+                        {{if $conn.Enabled}}<span class="status-badge active">Active</span>{{else}}<span class="status-badge">Disabled</span>{{end}}
+                    </div>
                 </div>
                 {{if $conn.LatestResult}}
                 <div class="metrics-row">
                     <div class="metric">
-                        <span class="metric-value download">{{printf "%.1f" $conn.LatestResult.DownloadMbps}}</span>
-                        <span class="metric-label">↓ Mbps</span>
+                        <span class="metric-value download">{{printf "%.1f" (speed $conn.LatestResult.DownloadMbps)}}</span>
+                        <span class="metric-label">↓ {{$.SpeedUnit}}</span>
                     </div>
                     <div class="metric">
-                        <span class="metric-value upload">{{printf "%.1f" $conn.LatestResult.UploadMbps}}</span>
-                        <span class="metric-label">↑ Mbps</span>
+                        <span class="metric-value upload">{{printf "%.1f" (speed $conn.LatestResult.UploadMbps)}}</span>
+                        <span class="metric-label">↑ {{$.SpeedUnit}}</span>
                     </div>
                     <div class="metric">
                         <span class="metric-value latency">{{printf "%.0f" $conn.LatestResult.LatencyMs}}</span>
@@ -676,7 +1067,7 @@ const dashboardTemplate = `<!DOCTYPE html>
                 </div>
                 <div class="card-footer">
                     <span class="server-info">{{$conn.LatestResult.ServerName}}</span>
-                    <span class="timestamp">{{$conn.LatestResult.CreatedAt.Local.Format "15:04"}}</span>
+                    <span class="timestamp">{{$conn.LatestResult.CreatedAt.Format "15:04"}}</span>
                 </div>
                 {{else}}
                 <div class="card-body empty">
@@ -694,6 +1085,9 @@ const dashboardTemplate = `<!DOCTYPE html>
         </footer>
     </div>
     
+    <!-- Toast notifications -->
+    <div id="toast" class="toast"></div>
+
     <!-- Modal for expanded chart -->
     <div id="chart-modal" class="modal" onclick="closeModal(event)">
         <div class="modal-content" onclick="event.stopPropagation()">
@@ -716,11 +1110,11 @@ const dashboardTemplate = `<!DOCTYPE html>
                 <div class="chart-legend">
                     <div class="legend-item">
                         <span class="legend-dot download"></span>
-                        <span>Download (Mbps)</span>
+                        <span>Download ({{.SpeedUnit}})</span>
                     </div>
                     <div class="legend-item">
                         <span class="legend-dot upload"></span>
-                        <span>Upload (Mbps)</span>
+                        <span>Upload ({{.SpeedUnit}})</span>
                     </div>
                     <div class="legend-item">
                         <span class="legend-dot latency"></span>
@@ -796,6 +1190,19 @@ const dashboardTemplate = `<!DOCTYPE html>
         {{end}}
         {{end}}
         
+        // Returns the value of a theme's CSS variable, e.g. for chart colors
+        function themeColor(name) {
+            return getComputedStyle(document.documentElement).getPropertyValue(name).trim();
+        }
+        
+        function toggleTheme() {
+            setTheme(document.documentElement.dataset.theme === 'light' ? 'dark' : 'light');
+            // Redraw an open chart in the colors of the new theme
+            if (modalChart && currentConnection) {
+                loadModalChart(currentConnection, currentDuration);
+            }
+        }
+        
         // Modal chart
         let modalChart = null;
         let currentConnection = null;
@@ -830,7 +1237,7 @@ const dashboardTemplate = `<!DOCTYPE html>
                         labels: data.labels,
                         datasets: [
                             {
-                                label: 'Download (Mbps)',
+                                label: 'Download ({{.SpeedUnit}})',
                                 data: data.download,
                                 borderColor: '#10b981',
                                 backgroundColor: 'rgba(16, 185, 129, 0.1)',
@@ -839,7 +1246,7 @@ const dashboardTemplate = `<!DOCTYPE html>
                                 yAxisID: 'y'
                             },
                             {
-                                label: 'Upload (Mbps)',
+                                label: 'Upload ({{.SpeedUnit}})',
                                 data: data.upload,
                                 borderColor: '#06b6d4',
                                 backgroundColor: 'rgba(6, 182, 212, 0.1)',
@@ -865,10 +1272,10 @@ const dashboardTemplate = `<!DOCTYPE html>
                         plugins: {
                             legend: { display: false },
                             tooltip: {
-                                backgroundColor: '#12121a',
-                                titleColor: '#e4e4e7',
-                                bodyColor: '#a1a1aa',
-                                borderColor: '#27272a',
+                                backgroundColor: themeColor('--bg-card'),
+                                titleColor: themeColor('--text-primary'),
+                                bodyColor: themeColor('--text-secondary'),
+                                borderColor: themeColor('--border'),
                                 borderWidth: 1,
                                 padding: 12,
                                 displayColors: true
@@ -876,24 +1283,24 @@ const dashboardTemplate = `<!DOCTYPE html>
                         },
                         scales: {
                             x: {
-                                grid: { color: 'rgba(39, 39, 42, 0.5)' },
-                                ticks: { color: '#71717a', maxTicksLimit: 12 }
+                                grid: { color: themeColor('--chart-grid') },
+                                ticks: { color: themeColor('--text-muted'), maxTicksLimit: 12 }
                             },
                             y: {
                                 type: 'linear',
                                 display: true,
                                 position: 'left',
-                                title: { display: true, text: 'Speed (Mbps)', color: '#71717a' },
-                                grid: { color: 'rgba(39, 39, 42, 0.5)' },
-                                ticks: { color: '#71717a' }
+                                title: { display: true, text: 'Speed ({{.SpeedUnit}})', color: themeColor('--text-muted') },
+                                grid: { color: themeColor('--chart-grid') },
+                                ticks: { color: themeColor('--text-muted') }
                             },
                             y1: {
                                 type: 'linear',
                                 display: true,
                                 position: 'right',
-                                title: { display: true, text: 'Latency (ms)', color: '#71717a' },
+                                title: { display: true, text: 'Latency (ms)', color: themeColor('--text-muted') },
                                 grid: { drawOnChartArea: false },
-                                ticks: { color: '#71717a' }
+                                ticks: { color: themeColor('--text-muted') }
                             }
                         }
                     }
@@ -915,6 +1322,90 @@ const dashboardTemplate = `<!DOCTYPE html>
             });
         });
         
+        // Group filter
+        function filterGroup(group) {
+            const url = new URL(window.location.href);
+            if (group) {
+                url.searchParams.set('group', group);
+            } else {
+                url.searchParams.delete('group');
+            }
+            window.location.href = url.toString();
+        }
+
+        // Toast notifications
+        let toastTimer = null;
+        function showToast(message, isError) {
+            const toast = document.getElementById('toast');
+            toast.textContent = message;
+            toast.classList.toggle('error', !!isError);
+            toast.classList.add('visible');
+            clearTimeout(toastTimer);
+            toastTimer = setTimeout(() => toast.classList.remove('visible'), 4000);
+        }
+
+        // Manually trigger a speedtest for a connection
+        async function runTest(button, connectionName) {
+            const label = button.innerHTML;
+            const testUrl = '/api/v1/connections/' + encodeURIComponent(connectionName) + '/test';
+            const restore = () => { button.disabled = false; button.innerHTML = label; };
+
+            button.disabled = true;
+            button.innerHTML = '<span class="spinner"></span>Running';
+
+            try {
+                const response = await fetch(testUrl, { method: 'POST' });
+                if (response.status === 401) {
+                    showToast('Authentication required to run tests', true);
+                    restore();
+                    return;
+                }
+                if (response.status === 429) {
+                    const body = await response.json().catch(() => ({}));
+                    showToast((body.error && body.error.message) || ('A test for ' + connectionName + ' is already running'), true);
+                    restore();
+                    return;
+                }
+                if (!response.ok) {
+                    const body = await response.json().catch(() => ({}));
+                    showToast((body.error && body.error.message) || ('Failed to start test (' + response.status + ')'), true);
+                    restore();
+                    return;
+                }
+
+                const started = (await response.json()).data;
+                if (started.queued) {
+                    button.innerHTML = '<span class="spinner"></span>Queued';
+                    showToast('Speedtest for ' + connectionName + ' queued at position ' + started.queue_position);
+                } else {
+                    showToast('Speedtest started for ' + connectionName);
+                }
+
+                // Poll until the test has finished
+                while (true) {
+                    await new Promise(resolve => setTimeout(resolve, 3000));
+                    const statusResponse = await fetch(testUrl);
+                    if (!statusResponse.ok) continue;
+                    const status = (await statusResponse.json()).data;
+                    if (status.queued) continue;
+                    button.innerHTML = '<span class="spinner"></span>Running';
+                    if (status.running) continue;
+
+                    if (status.error) {
+                        showToast('Test for ' + connectionName + ' failed: ' + status.error, true);
+                    } else {
+                        showToast('Test for ' + connectionName + ' completed');
+                    }
+                    const cards = document.getElementById('connections');
+                    htmx.ajax('GET', cards.getAttribute('hx-get'), { target: '#connections', swap: 'innerHTML' });
+                    return;
+                }
+            } catch (e) {
+                showToast('Failed to run test: ' + e.message, true);
+                restore();
+            }
+        }
+
         // Close modal on Escape key
         document.addEventListener('keydown', function(e) {
             if (e.key === 'Escape') closeModal();
@@ -922,7 +1413,7 @@ const dashboardTemplate = `<!DOCTYPE html>
         
         // Update timestamp on HTMX refresh
         document.body.addEventListener('htmx:afterSwap', function(evt) {
-            document.getElementById('last-update').textContent = new Date().toLocaleTimeString('de-DE', {hour: '2-digit', minute: '2-digit', second: '2-digit'});
+            document.getElementById('last-update').textContent = new Date().toLocaleTimeString('de-DE', {hour: '2-digit', minute: '2-digit', second: '2-digit'{{if .TimeZone}}, timeZone: '{{.TimeZone}}'{{end}}});
             // Reinitialize mini charts after HTMX swap
             setTimeout(() => location.reload(), 100); // Simple reload for now
         });
@@ -931,7 +1422,7 @@ const dashboardTemplate = `<!DOCTYPE html>
         setInterval(async () => {
             for (const [name, chart] of Object.entries(miniCharts)) {
                 try {
-                    const response = await fetch('/dashboard/connection/' + encodeURIComponent(name) + '/chart?duration=2h');
+                    const response = await fetch('/dashboard/connection/' + encodeURIComponent(name) + '/chart?duration={{.ChartWindow}}');
                     const data = await response.json();
                     
                     chart.data.labels = data.labels;
@@ -11,6 +11,9 @@ type Config struct {
 	Connections []ConnectionConfig `yaml:"connections"`
 	Scheduler   SchedulerConfig    `yaml:"scheduler"`
 	Speedtest   SpeedtestConfig    `yaml:"speedtest"`
+	Alerts      AlertsConfig       `yaml:"alerts"`
+	Reports     ReportsConfig      `yaml:"reports"`
+	Prometheus  PrometheusConfig   `yaml:"prometheus"`
 }
 
 // GeneralConfig contains general application settings.
@@ -19,14 +22,111 @@ type GeneralConfig struct {
 	LogLevel string `yaml:"log_level"`
 	// DataDir is the directory for storing application data
 	DataDir string `yaml:"data_dir"`
+	// Timezone is used to display timestamps: an IANA name (e.g. "Europe/Berlin"), "UTC" or "local"
+	Timezone string `yaml:"timezone"`
+	// SpeedUnit sets the display unit for speeds: mbps, gbps or mbytes (MB/s)
+	SpeedUnit string `yaml:"speed_unit"`
+	// RoundDecimals is the number of decimals stored results are rounded to
+	// (0 = default, negative disables rounding)
+	RoundDecimals int `yaml:"round_decimals"`
+	// AllowUnknownKeys accepts config keys that don't match a setting instead
+	// of rejecting them (e.g. for settings shared with other tools)
+	AllowUnknownKeys bool `yaml:"allow_unknown_keys,omitempty"`
 }
 
 // StorageConfig defines the storage backend settings.
 type StorageConfig struct {
-	// Type is the storage backend: sqlite or postgres
+	// Type is the storage backend: sqlite, postgres or memory
 	Type     string         `yaml:"type"`
 	SQLite   SQLiteConfig   `yaml:"sqlite"`
 	Postgres PostgresConfig `yaml:"postgres"`
+	Memory   MemoryConfig   `yaml:"memory"`
+	// Backends are additional backends that saved results are also written to
+	// (e.g. a central database). Reads always use the primary backend above.
+	Backends []StorageBackendConfig `yaml:"backends,omitempty"`
+	// BackupBeforeMigrate copies SQLite databases to <path>.bak before
+	// schema migrations and before old results are deleted. PostgreSQL
+	// databases are not copied; back them up with pg_dump.
+	BackupBeforeMigrate bool `yaml:"backup_before_migrate"`
+	// SlowQueryThreshold is the duration above which storage queries are
+	// logged as slow (negative disables the logging)
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// Rollup aggregates old results into hourly rollups for long-term retention
+	Rollup RollupConfig `yaml:"rollup"`
+	// Thinning progressively reduces the resolution of older results
+	Thinning ThinningConfig `yaml:"thinning"`
+}
+
+// RollupConfig defines the compaction of old results: results older than
+// RawRetention are aggregated per connection and hour into a rollup table and
+// deleted. Statistics of older periods are computed from the rollups.
+type RollupConfig struct {
+	// Enabled controls whether the rollup job runs
+	Enabled bool `yaml:"enabled"`
+	// Schedule is the cron expression of the rollup job
+	Schedule string `yaml:"schedule"`
+	// RawRetention is how long results are kept before they are rolled up,
+	// as a duration accepted by ParseDuration (e.g. "30d")
+	RawRetention string `yaml:"raw_retention"`
+	// Retention is how long rollups are kept, as a duration accepted by
+	// ParseDuration (e.g. "730d"; "0" keeps them forever)
+	Retention string `yaml:"retention"`
+}
+
+// RawRetentionDuration returns RawRetention as a duration.
+func (c RollupConfig) RawRetentionDuration() (time.Duration, error) {
+	return ParseDuration(c.RawRetention)
+}
+
+// RetentionDuration returns Retention as a duration (0 = forever).
+func (c RollupConfig) RetentionDuration() (time.Duration, error) {
+	return ParseDuration(c.Retention)
+}
+
+// ThinningConfig defines the decimation of older results: of the results
+// older than the age of a tier, every KeepEvery-th result per connection and
+// IP family is kept and the others are deleted. Tiers apply on top of each
+// other, so each tier thins the results kept by the previous one further.
+type ThinningConfig struct {
+	// Enabled controls whether the thinning job runs
+	Enabled bool `yaml:"enabled"`
+	// Schedule is the cron expression of the thinning job
+	Schedule string `yaml:"schedule"`
+	// Tiers are the thinning steps, ordered by increasing age
+	Tiers []ThinningTier `yaml:"tiers"`
+}
+
+// ThinningTier is a step of the thinning of older results.
+type ThinningTier struct {
+	// After is the age from which results are thinned, as a duration
+	// accepted by ParseDuration (e.g. "1h" or "7d")
+	After string `yaml:"after"`
+	// KeepEvery keeps one in KeepEvery results (at least 2)
+	KeepEvery int `yaml:"keep_every"`
+}
+
+// AfterDuration returns After as a duration.
+func (t ThinningTier) AfterDuration() (time.Duration, error) {
+	return ParseDuration(t.After)
+}
+
+// StorageBackendConfig defines an additional storage backend.
+type StorageBackendConfig struct {
+	// Type is the storage backend: sqlite, postgres or memory
+	Type     string         `yaml:"type"`
+	SQLite   SQLiteConfig   `yaml:"sqlite"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Memory   MemoryConfig   `yaml:"memory"`
+}
+
+// Primary returns the primary backend's settings.
+func (c StorageConfig) Primary() StorageBackendConfig {
+	return StorageBackendConfig{
+		Type:     c.Type,
+		SQLite:   c.SQLite,
+		Postgres: c.Postgres,
+		Memory:   c.Memory,
+	}
 }
 
 // SQLiteConfig contains SQLite-specific settings.
@@ -43,16 +143,84 @@ type PostgresConfig struct {
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	SSLMode  string `yaml:"ssl_mode"`
+	// BufferSize is the maximum number of results held in memory while the
+	// database is unreachable, saved once it is back; the oldest are dropped
+	// beyond it (negative disables buffering)
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// MemoryConfig contains settings of the in-memory backend, which keeps
+// results only until the process exits (e.g. for demos and CI).
+type MemoryConfig struct {
+	// MaxResults is the maximum number of results kept; the oldest are
+	// dropped first (0 = unlimited)
+	MaxResults int `yaml:"max_results"`
 }
 
 // WebserverConfig defines the web server settings (Dashboard + API).
 type WebserverConfig struct {
 	// Enabled controls whether the web server is started
 	Enabled bool `yaml:"enabled"`
-	// Listen is the address and port to bind to (e.g., "0.0.0.0:8080")
+	// Listen is the address and port to bind to (e.g., "0.0.0.0:8080"),
+	// or a Unix domain socket path prefixed with "unix:" (e.g., "unix:/run/flowgauge.sock")
 	Listen string `yaml:"listen"`
+	// SocketMode are the octal permissions of the Unix domain socket (e.g., "0660")
+	SocketMode string `yaml:"socket_mode,omitempty"`
 	// Auth contains optional authentication settings
 	Auth *AuthConfig `yaml:"auth,omitempty"`
+	// DashboardCacheTTL is how long dashboard data is cached (negative disables caching)
+	DashboardCacheTTL time.Duration `yaml:"dashboard_cache_ttl"`
+	// Dashboard contains the dashboard chart settings
+	Dashboard DashboardConfig `yaml:"dashboard"`
+	// MaxResultsLimit is the maximum number of results returned by a single API request
+	MaxResultsLimit int `yaml:"max_results_limit"`
+	// MaxHeaderBytes is the maximum size of request headers
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// IdleTimeout is how long idle keep-alive connections are kept open
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// DisableKeepAlives closes connections after each request
+	DisableKeepAlives bool `yaml:"disable_keep_alives"`
+	// RequestTimeout cancels requests that take longer
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// TriggerConcurrency is the number of triggered tests that run at the same time
+	TriggerConcurrency int `yaml:"trigger_concurrency"`
+	// TriggerQueueDepth is the number of triggered tests that wait for a free slot;
+	// further triggers are rejected (negative disables queueing)
+	TriggerQueueDepth int `yaml:"trigger_queue_depth"`
+	// AllowTriggers enables the endpoints that start and cancel tests.
+	// Disabled by default, so an exposed dashboard can't be used to run tests.
+	AllowTriggers bool `yaml:"allow_triggers"`
+	// EmbedAssets serves the dashboard's scripts and fonts from the binary
+	// under /assets/ instead of loading them from public CDNs
+	EmbedAssets bool `yaml:"embed_assets,omitempty"`
+	// Theme is the dashboard theme for visitors who haven't picked one with
+	// the dashboard's toggle: ThemeDark or ThemeLight
+	Theme string `yaml:"theme"`
+	// CanonicalHost is the host (optionally with port) the web server should
+	// be reached by; requests for other hosts, e.g. by IP, are redirected to
+	// it (empty disables the redirect)
+	CanonicalHost string `yaml:"canonical_host,omitempty"`
+	// ForceHTTPS redirects plaintext requests to HTTPS. TLS is terminated by
+	// a reverse proxy, which must set X-Forwarded-Proto for HTTPS requests.
+	ForceHTTPS bool `yaml:"force_https,omitempty"`
+}
+
+// Dashboard themes (webserver.theme).
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+)
+
+// DashboardConfig defines how much data the dashboard charts show.
+type DashboardConfig struct {
+	// ChartWindow is the time range shown by the mini charts on the connection cards
+	ChartWindow time.Duration `yaml:"chart_window"`
+	// ChartPointsPerHour is the number of chart points per hour of the shown range.
+	// Results beyond this density are averaged into time buckets.
+	ChartPointsPerHour int `yaml:"chart_points_per_hour"`
+	// MinChartPoints and MaxChartPoints bound the point limit of a single chart
+	MinChartPoints int `yaml:"min_chart_points"`
+	MaxChartPoints int `yaml:"max_chart_points"`
 }
 
 // AuthConfig contains optional Basic Auth settings for the API.
@@ -67,10 +235,27 @@ type ConnectionConfig struct {
 	Name string `yaml:"name"`
 	// SourceIP is the local IP address to bind to for this test
 	SourceIP string `yaml:"source_ip"`
+	// Interface is a network interface name (e.g. "eth1") whose current address is
+	// used as source IP, for WANs with dynamic addresses. SourceIP takes precedence.
+	Interface string `yaml:"interface,omitempty"`
 	// DSCP is the Differentiated Services Code Point value (0-63)
 	DSCP int `yaml:"dscp"`
 	// Enabled controls whether this connection is tested
 	Enabled bool `yaml:"enabled"`
+	// Group is an optional group name (e.g. a site) used for filtering and grouped stats
+	Group string `yaml:"group,omitempty"`
+	// Primary marks the main WAN (at most one connection), which is listed
+	// first and highlighted on the dashboard
+	Primary bool `yaml:"primary,omitempty"`
+	// DualStack tests the connection once over IPv4 and once over IPv6 per
+	// run, storing a result for each address family
+	DualStack bool `yaml:"dual_stack,omitempty"`
+	// Timeout overrides speedtest.timeout for this connection (0 = use the global timeout)
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// APIKeys grant read access to this connection's results and stats via
+	// the API (X-API-Key header or bearer token). A key listed for several
+	// connections grants access to all of them.
+	APIKeys []string `yaml:"api_keys,omitempty"`
 }
 
 // SchedulerConfig defines the automatic test scheduling.
@@ -79,20 +264,140 @@ type SchedulerConfig struct {
 	Enabled bool `yaml:"enabled"`
 	// Schedule is a cron expression (e.g., "*/30 * * * *" for every 30 minutes)
 	Schedule string `yaml:"schedule"`
+	// SkipWindows lists daily time ranges (e.g., "02:00-04:00") in which scheduled tests are skipped
+	SkipWindows []string `yaml:"skip_windows,omitempty"`
+	// Jitter delays each scheduled run by a random duration up to this value (0 = disabled)
+	Jitter time.Duration `yaml:"jitter,omitempty"`
+	// CatchUp runs the tests once at startup if a scheduled run was missed
+	// while FlowGauge wasn't running (e.g. the machine was asleep)
+	CatchUp bool `yaml:"catch_up,omitempty"`
+	// CatchUpThreshold is how long past its scheduled time a run counts as missed
+	CatchUpThreshold time.Duration `yaml:"catch_up_threshold,omitempty"`
+	// AtomicSaves saves the results of a run in one transaction, so they
+	// are either all saved or none (by default, each result is saved on its
+	// own, so one failed save doesn't lose the others)
+	AtomicSaves bool `yaml:"atomic_saves,omitempty"`
+}
+
+// AlertsConfig defines notifications for sustained degradation of a connection.
+// A scheduled result breaches if the test failed or a measured value is
+// outside a threshold; a threshold of 0 is not checked.
+type AlertsConfig struct {
+	// Enabled controls whether scheduled results are checked for degradation
+	Enabled bool `yaml:"enabled"`
+	// Consecutive is the number of consecutive breaching results after which an alert fires
+	Consecutive int `yaml:"consecutive"`
+	// MinDownloadMbps is the lowest acceptable download speed
+	MinDownloadMbps float64 `yaml:"min_download_mbps,omitempty"`
+	// MinUploadMbps is the lowest acceptable upload speed
+	MinUploadMbps float64 `yaml:"min_upload_mbps,omitempty"`
+	// MaxLatencyMs is the highest acceptable latency
+	MaxLatencyMs float64 `yaml:"max_latency_ms,omitempty"`
+	// BaselineFraction is the lowest acceptable download and upload as a
+	// fraction (e.g. 0.7) of the connection's baseline, the median of its
+	// results at the same hour of day over the last 7 days (0 = not checked)
+	BaselineFraction float64 `yaml:"baseline_fraction,omitempty"`
+	// WebhookURL receives alert and recovery notifications as JSON POST requests
+	// (optional, notifications are always logged)
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+}
+
+// ReportsConfig defines periodic summary reports: the statistics of every
+// enabled connection over a period, compared with the period before it.
+type ReportsConfig struct {
+	// Enabled controls whether reports are generated
+	Enabled bool `yaml:"enabled"`
+	// Schedule is the cron expression of the report (e.g. "0 8 * * 1" for
+	// Monday mornings)
+	Schedule string `yaml:"schedule"`
+	// Period is the period covered by a report, as a duration accepted by
+	// ParseDuration (e.g. "7d")
+	Period string `yaml:"period"`
+	// Format is the format of the rendered report: markdown or html
+	Format string `yaml:"format"`
+	// Directory receives each report as a file (optional)
+	Directory string `yaml:"directory,omitempty"`
+	// WebhookURL receives each report as a JSON POST request (optional)
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+}
+
+// PeriodDuration returns Period as a duration.
+func (c ReportsConfig) PeriodDuration() (time.Duration, error) {
+	return ParseDuration(c.Period)
+}
+
+// PrometheusConfig contains settings of the Prometheus metrics.
+type PrometheusConfig struct {
+	// ClearOnError resets the speed and latency gauges of a connection when a
+	// test fails, instead of keeping the last successful values
+	ClearOnError bool `yaml:"clear_on_error"`
+	// ClearValue is the value cleared gauges are set to: nan or zero
+	ClearValue string `yaml:"clear_value"`
+	// LatencyBuckets are the upper bounds (in ms) of the latency histogram
+	LatencyBuckets []float64 `yaml:"latency_buckets"`
+	// ThroughputBuckets are the upper bounds (in Mbps) of the download and
+	// upload histograms (empty = no throughput histograms)
+	ThroughputBuckets []float64 `yaml:"throughput_buckets,omitempty"`
 }
 
 // SpeedtestConfig contains speedtest-specific settings.
 type SpeedtestConfig struct {
 	// ServerIDs is a list of specific speedtest server IDs to use (empty = auto-select)
 	ServerIDs []int `yaml:"server_ids"`
+	// ServerSelection controls how servers are auto-selected: latency (lowest
+	// latency) or history (best historical download among the servers within
+	// SelectionLatencyMargin of the lowest latency)
+	ServerSelection string `yaml:"server_selection"`
+	// SelectionLatencyMargin is how much higher than the lowest latency a
+	// server's latency may be to be considered by the history selection
+	SelectionLatencyMargin time.Duration `yaml:"selection_latency_margin"`
+	// ServerCacheTTL is how long the fetched server list is reused by
+	// following tests (negative disables the cache)
+	ServerCacheTTL time.Duration `yaml:"server_cache_ttl"`
 	// Timeout is the maximum duration for a single test
 	Timeout time.Duration `yaml:"timeout"`
 	// DownloadSize controls the download test size: auto, small, medium, large
 	DownloadSize string `yaml:"download_size"`
 	// UploadSize controls the upload test size: auto, small, medium, large
 	UploadSize string `yaml:"upload_size"`
+	// Warmup is the initial part of the download and upload transfers that
+	// is excluded from the measured speed, so TCP slow-start doesn't lower
+	// it (0 = measure the whole transfer)
+	Warmup time.Duration `yaml:"warmup,omitempty"`
+	// Threads is the number of concurrent connections of the download and
+	// upload phases (0 = one per CPU, the speedtest-go default)
+	Threads int `yaml:"threads,omitempty"`
+	// BreakerThreshold is the number of consecutive failures after which a
+	// server is excluded from selection (negative disables the breaker)
+	BreakerThreshold int `yaml:"breaker_threshold"`
+	// BreakerCooldown is how long a failing server stays excluded
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown"`
+	// ReachabilityTarget is a host:port that is connected to through each
+	// connection before testing, so dead links fail fast
+	ReachabilityTarget string `yaml:"reachability_target"`
+	// ReachabilityTimeout is how long the reachability check may take (negative disables it)
+	ReachabilityTimeout time.Duration `yaml:"reachability_timeout"`
+	// ProxyURL routes the tests through an HTTP(S) or SOCKS5 proxy (e.g.
+	// "http://proxy.example.com:3128" or "socks5://127.0.0.1:1080"); the
+	// connection's source binding and DSCP marking apply to the connection
+	// to the proxy
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+	// Order controls the connection order in sequential runs: config, random, round_robin
+	Order string `yaml:"order"`
+	// Parallel tests all connections concurrently instead of one after another
+	Parallel bool `yaml:"parallel,omitempty"`
+	// Aggregate records the summed throughput of each parallel run as the
+	// synthetic AggregateConnectionName connection (requires Parallel)
+	Aggregate bool `yaml:"aggregate,omitempty"`
+	// PromFile is a file that "flowgauge test" writes the metric values to after
+	// each run, for the node_exporter textfile collector (empty = disabled)
+	PromFile string `yaml:"prom_file,omitempty"`
 }
 
+// AggregateConnectionName is the connection name under which aggregate
+// results of parallel runs are stored.
+const AggregateConnectionName = "AGGREGATE"
+
 // DSCPValue represents common DSCP values for QoS marking.
 const (
 	DSCPBestEffort = 0  // BE - Default/Best Effort
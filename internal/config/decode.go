@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML decodes data over cfg. Unless cfg.General.AllowUnknownKeys is
+// set (by this or a previously decoded file), keys that don't match a config
+// field are rejected, so typos don't silently fall back to defaults.
+// Decoding errors are annotated with the path of the offending setting.
+func decodeYAML(data []byte, cfg *Config) error {
+	if err := decodeWith(data, cfg, false); err != nil {
+		return err
+	}
+	if cfg.General.AllowUnknownKeys {
+		return nil
+	}
+
+	// The flag may be set by this file itself, so unknown keys are checked
+	// in a second pass into a scratch config
+	return decodeWith(data, &Config{}, true)
+}
+
+// decodeWith decodes data into cfg, optionally rejecting unknown keys.
+// An empty document is not an error.
+func decodeWith(data []byte, cfg *Config, knownFields bool) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(knownFields)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return annotateYAMLError(data, err)
+	}
+	return nil
+}
+
+// yamlErrorLine matches the line number yaml.v3 prefixes decoding errors with.
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): `)
+
+// annotateYAMLError adds the path of the setting on the reported line to
+// each decoding error, e.g. "line 12 (connections[1].dscp): cannot unmarshal
+// !!str `46` into int". Other errors (e.g. syntax errors) are returned as is.
+func annotateYAMLError(data []byte, err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	var root yaml.Node
+	if yaml.Unmarshal(data, &root) != nil {
+		return err
+	}
+	paths := make(map[int]string)
+	collectPaths(&root, "", paths)
+
+	annotated := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		annotated[i] = msg
+		m := yamlErrorLine.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		if path, ok := paths[line]; ok {
+			annotated[i] = fmt.Sprintf("line %d (%s): %s", line, path, msg[len(m[0]):])
+		}
+	}
+	return &yaml.TypeError{Errors: annotated}
+}
+
+// collectPaths maps the line of every key and sequence item below node to
+// its setting path (e.g. "connections[1].dscp"). The first path found for a
+// line wins, so a line maps to the outermost setting starting on it.
+func collectPaths(node *yaml.Node, path string, paths map[int]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectPaths(child, path, paths)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+			if _, ok := paths[key.Line]; !ok {
+				paths[key.Line] = keyPath
+			}
+			collectPaths(value, keyPath, paths)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if _, ok := paths[item.Line]; !ok && item.Kind == yaml.ScalarNode {
+				paths[item.Line] = itemPath
+			}
+			collectPaths(item, itemPath, paths)
+		}
+	}
+}
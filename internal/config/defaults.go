@@ -1,53 +1,188 @@
 package config
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 // Default values for configuration
 const (
-	DefaultLogLevel         = "info"
-	DefaultDataDir          = "/var/lib/flowgauge"
-	DefaultStorageType      = "sqlite"
-	DefaultSQLitePath       = "/var/lib/flowgauge/results.db"
-	DefaultWebserverListen  = "127.0.0.1:8080"
-	DefaultSchedule         = "0 * * * *" // Every hour
-	DefaultTestTimeout      = 60 * time.Second
-	DefaultDownloadSize     = "auto"
-	DefaultUploadSize       = "auto"
-	DefaultPostgresPort     = 5432
-	DefaultPostgresSSL      = "disable"
+	DefaultLogLevel          = "info"
+	DefaultDataDir           = "/var/lib/flowgauge"
+	DefaultTimezone          = "local"
+	DefaultSpeedUnit         = "mbps"
+	DefaultRoundDecimals     = 2
+	DefaultStorageType       = "sqlite"
+	DefaultSQLitePath        = "/var/lib/flowgauge/results.db"
+	DefaultWebserverListen   = "127.0.0.1:8080"
+	DefaultSocketMode        = "0660"
+	DefaultDashboardCacheTTL = 10 * time.Second
+	DefaultMaxResultsLimit   = 1000
+	DefaultMaxHeaderBytes    = 1 << 20 // 1 MB, same as net/http
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultRequestTimeout    = 60 * time.Second
+	DefaultTheme             = ThemeDark
+	DefaultSchedule          = "0 * * * *" // Every hour
+	DefaultCatchUpThreshold  = 5 * time.Minute
+	DefaultTestTimeout       = 60 * time.Second
+	DefaultDownloadSize      = "auto"
+	DefaultUploadSize        = "auto"
+	DefaultBreakerThreshold  = 3
+	DefaultBreakerCooldown   = 30 * time.Minute
+	DefaultTestOrder         = "config"
+	DefaultAlertConsecutive  = 3
+	DefaultMetricsClearValue = "nan"
+	DefaultPostgresPort      = 5432
+	DefaultPostgresSSL       = "disable"
+	DefaultPostgresBuffer    = 1000
+	DefaultSlowQuery         = time.Second
+	DefaultRollupSchedule    = "15 * * * *" // Every hour, after the default test schedule
+	DefaultRawRetention      = "30d"
+	DefaultRollupRetention   = "730d"
+	DefaultThinningSchedule  = "45 * * * *" // Every hour, between the default test and rollup schedules
+	DefaultReportSchedule    = "0 8 * * 1"  // Monday, 08:00
+	DefaultReportPeriod      = "7d"
+	DefaultReportFormat      = "markdown"
+
+	// DefaultReachabilityTarget is the host speedtest-go fetches the server list from
+	DefaultReachabilityTarget  = "www.speedtest.net:443"
+	DefaultReachabilityTimeout = 2 * time.Second
+)
+
+// Server selection defaults
+const (
+	DefaultServerSelection        = "latency"
+	DefaultSelectionLatencyMargin = 5 * time.Millisecond
+)
+
+// DefaultServerCacheTTL is how long a fetched server list is reused.
+const DefaultServerCacheTTL = 5 * time.Minute
+
+// Triggered test queue defaults
+const (
+	DefaultTriggerConcurrency = 1
+	DefaultTriggerQueueDepth  = 10
+)
+
+// Dashboard chart defaults and bounds
+const (
+	DefaultChartWindow        = 2 * time.Hour
+	DefaultChartPointsPerHour = 100
+	DefaultMinChartPoints     = 200
+	DefaultMaxChartPoints     = 1000
+
+	// MinChartWindow and MaxChartWindow bound the mini chart window
+	MinChartWindow = 15 * time.Minute
+	MaxChartWindow = 7 * 24 * time.Hour
+	// ChartPointsCeiling is the upper bound for max_chart_points
+	ChartPointsCeiling = 10000
 )
 
+// MaxWarmup is the longest allowed speedtest.warmup. Download and upload
+// transfers last 15 seconds, so some of the transfer must remain to be
+// measured.
+const MaxWarmup = 10 * time.Second
+
+// MaxThreads is the highest allowed speedtest.threads. More connections
+// don't raise the throughput further but load the test servers.
+const MaxThreads = 64
+
+// MaxResultsLimitCeiling is the absolute upper bound for the number of results
+// returned by a single query. The storage layer enforces it defensively.
+const MaxResultsLimitCeiling = 100000
+
+// DefaultLatencyBuckets returns the default upper bounds (in ms) of the
+// latency histogram.
+func DefaultLatencyBuckets() []float64 {
+	return []float64{5, 10, 20, 30, 50, 75, 100, 150, 200, 300, 500, 1000}
+}
+
 // NewDefault creates a new Config with all default values applied.
 func NewDefault() *Config {
 	return &Config{
 		General: GeneralConfig{
-			LogLevel: DefaultLogLevel,
-			DataDir:  DefaultDataDir,
+			LogLevel:  DefaultLogLevel,
+			DataDir:   DefaultDataDir,
+			Timezone:  DefaultTimezone,
+			SpeedUnit: DefaultSpeedUnit,
+
+			RoundDecimals: DefaultRoundDecimals,
 		},
 		Storage: StorageConfig{
-			Type: DefaultStorageType,
+			Type:               DefaultStorageType,
+			SlowQueryThreshold: DefaultSlowQuery,
+			Rollup: RollupConfig{
+				Schedule:     DefaultRollupSchedule,
+				RawRetention: DefaultRawRetention,
+				Retention:    DefaultRollupRetention,
+			},
+			Thinning: ThinningConfig{
+				Schedule: DefaultThinningSchedule,
+			},
 			SQLite: SQLiteConfig{
 				Path: DefaultSQLitePath,
 			},
 			Postgres: PostgresConfig{
-				Port:    DefaultPostgresPort,
-				SSLMode: DefaultPostgresSSL,
+				Port:       DefaultPostgresPort,
+				SSLMode:    DefaultPostgresSSL,
+				BufferSize: DefaultPostgresBuffer,
 			},
 		},
 		Webserver: WebserverConfig{
-			Enabled: true,
-			Listen:  DefaultWebserverListen,
+			Enabled:           true,
+			Listen:            DefaultWebserverListen,
+			SocketMode:        DefaultSocketMode,
+			DashboardCacheTTL: DefaultDashboardCacheTTL,
+			MaxResultsLimit:   DefaultMaxResultsLimit,
+			MaxHeaderBytes:    DefaultMaxHeaderBytes,
+			IdleTimeout:       DefaultIdleTimeout,
+			RequestTimeout:    DefaultRequestTimeout,
+			Theme:             DefaultTheme,
+
+			TriggerConcurrency: DefaultTriggerConcurrency,
+			TriggerQueueDepth:  DefaultTriggerQueueDepth,
+			Dashboard: DashboardConfig{
+				ChartWindow:        DefaultChartWindow,
+				ChartPointsPerHour: DefaultChartPointsPerHour,
+				MinChartPoints:     DefaultMinChartPoints,
+				MaxChartPoints:     DefaultMaxChartPoints,
+			},
 		},
 		Connections: []ConnectionConfig{},
 		Scheduler: SchedulerConfig{
 			Enabled:  false,
 			Schedule: DefaultSchedule,
+
+			CatchUpThreshold: DefaultCatchUpThreshold,
 		},
 		Speedtest: SpeedtestConfig{
-			ServerIDs:    []int{},
-			Timeout:      DefaultTestTimeout,
-			DownloadSize: DefaultDownloadSize,
-			UploadSize:   DefaultUploadSize,
+			ServerIDs:        []int{},
+			Timeout:          DefaultTestTimeout,
+			DownloadSize:     DefaultDownloadSize,
+			UploadSize:       DefaultUploadSize,
+			BreakerThreshold: DefaultBreakerThreshold,
+			BreakerCooldown:  DefaultBreakerCooldown,
+			Order:            DefaultTestOrder,
+
+			ReachabilityTarget:  DefaultReachabilityTarget,
+			ReachabilityTimeout: DefaultReachabilityTimeout,
+
+			ServerSelection:        DefaultServerSelection,
+			SelectionLatencyMargin: DefaultSelectionLatencyMargin,
+			ServerCacheTTL:         DefaultServerCacheTTL,
+		},
+		Reports: ReportsConfig{
+			Schedule: DefaultReportSchedule,
+			Period:   DefaultReportPeriod,
+			Format:   DefaultReportFormat,
+		},
+		Alerts: AlertsConfig{
+			Consecutive: DefaultAlertConsecutive,
+		},
+		Prometheus: PrometheusConfig{
+			ClearValue:     DefaultMetricsClearValue,
+			LatencyBuckets: DefaultLatencyBuckets(),
 		},
 	}
 }
@@ -61,6 +196,15 @@ func ApplyDefaults(cfg *Config) {
 	if cfg.General.DataDir == "" {
 		cfg.General.DataDir = DefaultDataDir
 	}
+	if cfg.General.Timezone == "" {
+		cfg.General.Timezone = DefaultTimezone
+	}
+	if cfg.General.SpeedUnit == "" {
+		cfg.General.SpeedUnit = DefaultSpeedUnit
+	}
+	if cfg.General.RoundDecimals == 0 {
+		cfg.General.RoundDecimals = DefaultRoundDecimals
+	}
 
 	// Storage defaults
 	if cfg.Storage.Type == "" {
@@ -75,16 +219,89 @@ func ApplyDefaults(cfg *Config) {
 	if cfg.Storage.Postgres.SSLMode == "" {
 		cfg.Storage.Postgres.SSLMode = DefaultPostgresSSL
 	}
+	if cfg.Storage.Postgres.BufferSize == 0 {
+		cfg.Storage.Postgres.BufferSize = DefaultPostgresBuffer
+	}
+	if cfg.Storage.SlowQueryThreshold == 0 {
+		cfg.Storage.SlowQueryThreshold = DefaultSlowQuery
+	}
+	if cfg.Storage.Rollup.Schedule == "" {
+		cfg.Storage.Rollup.Schedule = DefaultRollupSchedule
+	}
+	if cfg.Storage.Rollup.RawRetention == "" {
+		cfg.Storage.Rollup.RawRetention = DefaultRawRetention
+	}
+	if cfg.Storage.Rollup.Retention == "" {
+		cfg.Storage.Rollup.Retention = DefaultRollupRetention
+	}
+	if cfg.Storage.Thinning.Schedule == "" {
+		cfg.Storage.Thinning.Schedule = DefaultThinningSchedule
+	}
+	for i := range cfg.Storage.Backends {
+		b := &cfg.Storage.Backends[i]
+		if b.Postgres.Port == 0 {
+			b.Postgres.Port = DefaultPostgresPort
+		}
+		if b.Postgres.SSLMode == "" {
+			b.Postgres.SSLMode = DefaultPostgresSSL
+		}
+		if b.Postgres.BufferSize == 0 {
+			b.Postgres.BufferSize = DefaultPostgresBuffer
+		}
+	}
 
 	// Webserver defaults
 	if cfg.Webserver.Listen == "" {
 		cfg.Webserver.Listen = DefaultWebserverListen
 	}
+	if cfg.Webserver.SocketMode == "" {
+		cfg.Webserver.SocketMode = DefaultSocketMode
+	}
+	if cfg.Webserver.DashboardCacheTTL == 0 {
+		cfg.Webserver.DashboardCacheTTL = DefaultDashboardCacheTTL
+	}
+	if cfg.Webserver.Dashboard.ChartWindow == 0 {
+		cfg.Webserver.Dashboard.ChartWindow = DefaultChartWindow
+	}
+	if cfg.Webserver.Dashboard.ChartPointsPerHour == 0 {
+		cfg.Webserver.Dashboard.ChartPointsPerHour = DefaultChartPointsPerHour
+	}
+	if cfg.Webserver.Dashboard.MinChartPoints == 0 {
+		cfg.Webserver.Dashboard.MinChartPoints = DefaultMinChartPoints
+	}
+	if cfg.Webserver.Dashboard.MaxChartPoints == 0 {
+		cfg.Webserver.Dashboard.MaxChartPoints = DefaultMaxChartPoints
+	}
+	if cfg.Webserver.MaxResultsLimit == 0 {
+		cfg.Webserver.MaxResultsLimit = DefaultMaxResultsLimit
+	}
+	if cfg.Webserver.MaxHeaderBytes == 0 {
+		cfg.Webserver.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if cfg.Webserver.IdleTimeout == 0 {
+		cfg.Webserver.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.Webserver.RequestTimeout == 0 {
+		cfg.Webserver.RequestTimeout = DefaultRequestTimeout
+	}
+	if cfg.Webserver.TriggerConcurrency == 0 {
+		cfg.Webserver.TriggerConcurrency = DefaultTriggerConcurrency
+	}
+	if cfg.Webserver.TriggerQueueDepth == 0 {
+		cfg.Webserver.TriggerQueueDepth = DefaultTriggerQueueDepth
+	}
+	if cfg.Webserver.Theme == "" {
+		cfg.Webserver.Theme = DefaultTheme
+	}
 
-	// Scheduler defaults
+	// Scheduler defaults (collapse stray whitespace in the cron expression)
+	cfg.Scheduler.Schedule = strings.Join(strings.Fields(cfg.Scheduler.Schedule), " ")
 	if cfg.Scheduler.Schedule == "" {
 		cfg.Scheduler.Schedule = DefaultSchedule
 	}
+	if cfg.Scheduler.CatchUpThreshold == 0 {
+		cfg.Scheduler.CatchUpThreshold = DefaultCatchUpThreshold
+	}
 
 	// Speedtest defaults
 	if cfg.Speedtest.Timeout == 0 {
@@ -96,10 +313,56 @@ func ApplyDefaults(cfg *Config) {
 	if cfg.Speedtest.UploadSize == "" {
 		cfg.Speedtest.UploadSize = DefaultUploadSize
 	}
+	if cfg.Speedtest.BreakerThreshold == 0 {
+		cfg.Speedtest.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if cfg.Speedtest.BreakerCooldown == 0 {
+		cfg.Speedtest.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if cfg.Speedtest.ReachabilityTarget == "" {
+		cfg.Speedtest.ReachabilityTarget = DefaultReachabilityTarget
+	}
+	if cfg.Speedtest.ReachabilityTimeout == 0 {
+		cfg.Speedtest.ReachabilityTimeout = DefaultReachabilityTimeout
+	}
+	if cfg.Speedtest.Order == "" {
+		cfg.Speedtest.Order = DefaultTestOrder
+	}
+	if cfg.Speedtest.ServerSelection == "" {
+		cfg.Speedtest.ServerSelection = DefaultServerSelection
+	}
+	if cfg.Speedtest.SelectionLatencyMargin == 0 {
+		cfg.Speedtest.SelectionLatencyMargin = DefaultSelectionLatencyMargin
+	}
+	if cfg.Speedtest.ServerCacheTTL == 0 {
+		cfg.Speedtest.ServerCacheTTL = DefaultServerCacheTTL
+	}
 	if cfg.Speedtest.ServerIDs == nil {
 		cfg.Speedtest.ServerIDs = []int{}
 	}
 
+	// Alert defaults
+	if cfg.Alerts.Consecutive == 0 {
+		cfg.Alerts.Consecutive = DefaultAlertConsecutive
+	}
+	if cfg.Reports.Schedule == "" {
+		cfg.Reports.Schedule = DefaultReportSchedule
+	}
+	if cfg.Reports.Period == "" {
+		cfg.Reports.Period = DefaultReportPeriod
+	}
+	if cfg.Reports.Format == "" {
+		cfg.Reports.Format = DefaultReportFormat
+	}
+
+	// Prometheus defaults
+	if cfg.Prometheus.ClearValue == "" {
+		cfg.Prometheus.ClearValue = DefaultMetricsClearValue
+	}
+	if len(cfg.Prometheus.LatencyBuckets) == 0 {
+		cfg.Prometheus.LatencyBuckets = DefaultLatencyBuckets()
+	}
+
 	// Note: YAML unmarshal sets bool to false by default for connections,
 	// so we can't distinguish between "enabled: false" and unset.
 	// Users must explicitly set "enabled: true" for active connections.
@@ -116,6 +379,70 @@ func (c *Config) GetEnabledConnections() []ConnectionConfig {
 	return enabled
 }
 
+// GetConnectionsPrimaryFirst returns all connections with the primary one
+// first, the others in configuration order.
+func (c *Config) GetConnectionsPrimaryFirst() []ConnectionConfig {
+	conns := make([]ConnectionConfig, 0, len(c.Connections))
+	for _, conn := range c.Connections {
+		if conn.Primary {
+			conns = append(conns, conn)
+		}
+	}
+	for _, conn := range c.Connections {
+		if !conn.Primary {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+// GetConnectionsByGroup returns all connections in the given group.
+func (c *Config) GetConnectionsByGroup(group string) []ConnectionConfig {
+	var conns []ConnectionConfig
+	for _, conn := range c.Connections {
+		if conn.Group == group {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+// GetGroups returns the sorted list of distinct connection groups.
+func (c *Config) GetGroups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, conn := range c.Connections {
+		if conn.Group != "" && !seen[conn.Group] {
+			seen[conn.Group] = true
+			groups = append(groups, conn.Group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Location returns the configured display timezone.
+// Falls back to local time if the timezone cannot be loaded.
+func (c *Config) Location() *time.Location {
+	loc, err := LoadLocation(c.General.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// LoadLocation resolves a timezone name to a location.
+// Besides IANA names, "UTC" and "local" (or empty) are accepted case-insensitively.
+func LoadLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
 // GetConnectionByName returns a connection by its name, or nil if not found.
 func (c *Config) GetConnectionByName(name string) *ConnectionConfig {
 	for i := range c.Connections {
@@ -125,4 +452,3 @@ func (c *Config) GetConnectionByName(name string) *ConnectionConfig {
 	}
 	return nil
 }
-
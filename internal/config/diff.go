@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Diff returns a human-readable list of changes between two configurations.
+// Changes that can only take effect after a restart are marked as such.
+func Diff(old, new *Config) []string {
+	var changes []string
+
+	if old.General.LogLevel != new.General.LogLevel {
+		changes = append(changes, fmt.Sprintf("general.log_level: %q -> %q (requires restart)",
+			old.General.LogLevel, new.General.LogLevel))
+	}
+
+	if old.General.Timezone != new.General.Timezone {
+		changes = append(changes, fmt.Sprintf("general.timezone: %q -> %q",
+			old.General.Timezone, new.General.Timezone))
+	}
+
+	if old.General.RoundDecimals != new.General.RoundDecimals {
+		changes = append(changes, fmt.Sprintf("general.round_decimals: %d -> %d",
+			old.General.RoundDecimals, new.General.RoundDecimals))
+	}
+
+	if !reflect.DeepEqual(old.Storage, new.Storage) {
+		changes = append(changes, "storage settings changed (requires restart)")
+	}
+
+	if old.Webserver.Listen != new.Webserver.Listen {
+		changes = append(changes, fmt.Sprintf("webserver.listen: %q -> %q (requires restart)",
+			old.Webserver.Listen, new.Webserver.Listen))
+	}
+	if old.Webserver.SocketMode != new.Webserver.SocketMode {
+		changes = append(changes, fmt.Sprintf("webserver.socket_mode: %q -> %q (requires restart)",
+			old.Webserver.SocketMode, new.Webserver.SocketMode))
+	}
+	if old.Webserver.MaxHeaderBytes != new.Webserver.MaxHeaderBytes ||
+		old.Webserver.IdleTimeout != new.Webserver.IdleTimeout ||
+		old.Webserver.RequestTimeout != new.Webserver.RequestTimeout ||
+		old.Webserver.DisableKeepAlives != new.Webserver.DisableKeepAlives {
+		changes = append(changes, "webserver connection settings changed (requires restart)")
+	}
+	if old.Webserver.Dashboard != new.Webserver.Dashboard {
+		changes = append(changes, "webserver.dashboard settings changed")
+	}
+	if old.Webserver.DashboardCacheTTL != new.Webserver.DashboardCacheTTL {
+		changes = append(changes, fmt.Sprintf("webserver.dashboard_cache_ttl: %s -> %s",
+			old.Webserver.DashboardCacheTTL, new.Webserver.DashboardCacheTTL))
+	}
+	if old.Webserver.TriggerConcurrency != new.Webserver.TriggerConcurrency ||
+		old.Webserver.TriggerQueueDepth != new.Webserver.TriggerQueueDepth {
+		changes = append(changes, "webserver trigger queue settings changed")
+	}
+	if old.Webserver.AllowTriggers != new.Webserver.AllowTriggers {
+		changes = append(changes, fmt.Sprintf("webserver.allow_triggers: %t -> %t",
+			old.Webserver.AllowTriggers, new.Webserver.AllowTriggers))
+	}
+	if old.Webserver.EmbedAssets != new.Webserver.EmbedAssets {
+		changes = append(changes, fmt.Sprintf("webserver.embed_assets: %t -> %t",
+			old.Webserver.EmbedAssets, new.Webserver.EmbedAssets))
+	}
+	if old.Webserver.Theme != new.Webserver.Theme {
+		changes = append(changes, fmt.Sprintf("webserver.theme: %s -> %s",
+			old.Webserver.Theme, new.Webserver.Theme))
+	}
+	if old.Webserver.CanonicalHost != new.Webserver.CanonicalHost ||
+		old.Webserver.ForceHTTPS != new.Webserver.ForceHTTPS {
+		changes = append(changes, "webserver redirect settings changed")
+	}
+	if !reflect.DeepEqual(old.Webserver.Auth, new.Webserver.Auth) {
+		changes = append(changes, "webserver.auth changed")
+	}
+
+	if old.Scheduler.Enabled != new.Scheduler.Enabled {
+		changes = append(changes, fmt.Sprintf("scheduler.enabled: %t -> %t",
+			old.Scheduler.Enabled, new.Scheduler.Enabled))
+	}
+	if old.Scheduler.Schedule != new.Scheduler.Schedule {
+		changes = append(changes, fmt.Sprintf("scheduler.schedule: %q -> %q",
+			old.Scheduler.Schedule, new.Scheduler.Schedule))
+	}
+
+	if old.Scheduler.Jitter != new.Scheduler.Jitter {
+		changes = append(changes, fmt.Sprintf("scheduler.jitter: %s -> %s",
+			old.Scheduler.Jitter, new.Scheduler.Jitter))
+	}
+
+	if old.Scheduler.CatchUp != new.Scheduler.CatchUp ||
+		old.Scheduler.CatchUpThreshold != new.Scheduler.CatchUpThreshold {
+		changes = append(changes, "scheduler catch-up settings changed (requires restart)")
+	}
+
+	if old.Scheduler.AtomicSaves != new.Scheduler.AtomicSaves {
+		changes = append(changes, fmt.Sprintf("scheduler.atomic_saves: %t -> %t",
+			old.Scheduler.AtomicSaves, new.Scheduler.AtomicSaves))
+	}
+
+	if !reflect.DeepEqual(old.Scheduler.SkipWindows, new.Scheduler.SkipWindows) {
+		changes = append(changes, fmt.Sprintf("scheduler.skip_windows: %v -> %v",
+			old.Scheduler.SkipWindows, new.Scheduler.SkipWindows))
+	}
+
+	if !reflect.DeepEqual(old.Speedtest, new.Speedtest) {
+		changes = append(changes, "speedtest settings changed")
+	}
+
+	if old.Alerts != new.Alerts {
+		changes = append(changes, "alerts settings changed")
+	}
+
+	if old.Reports != new.Reports {
+		changes = append(changes, "reports settings changed")
+	}
+
+	if !reflect.DeepEqual(old.Prometheus, new.Prometheus) {
+		changes = append(changes, "prometheus settings changed")
+	}
+
+	changes = append(changes, diffConnections(old.Connections, new.Connections)...)
+
+	if len(changes) == 0 && !reflect.DeepEqual(old, new) {
+		changes = append(changes, "other settings changed")
+	}
+
+	return changes
+}
+
+// diffConnections compares connection lists by name.
+func diffConnections(old, new []ConnectionConfig) []string {
+	var changes []string
+
+	oldByName := make(map[string]ConnectionConfig, len(old))
+	for _, conn := range old {
+		oldByName[conn.Name] = conn
+	}
+	newByName := make(map[string]bool, len(new))
+
+	for _, conn := range new {
+		newByName[conn.Name] = true
+		prev, ok := oldByName[conn.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("connection %q added", conn.Name))
+			continue
+		}
+		if !reflect.DeepEqual(prev, conn) {
+			changes = append(changes, fmt.Sprintf("connection %q modified", conn.Name))
+		}
+	}
+
+	for _, conn := range old {
+		if !newByName[conn.Name] {
+			changes = append(changes, fmt.Sprintf("connection %q removed", conn.Name))
+		}
+	}
+
+	return changes
+}
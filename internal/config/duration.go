@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a non-negative duration like time.ParseDuration,
+// additionally accepting whole days with a "d" suffix (e.g. "30d") and whole
+// weeks with a "w" suffix (e.g. "2w").
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days: %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	if weeks, ok := strings.CutSuffix(s, "w"); ok {
+		n, err := strconv.Atoi(weeks)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of weeks: %q", s)
+		}
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative: %q", s)
+	}
+	return d, nil
+}
+
+// ParseTimeOrDuration parses a point in time for since/until filters, given
+// as an RFC3339 timestamp (e.g. "2024-01-02T15:04:05Z"), a date (e.g.
+// "2024-01-02", meaning midnight at the start of the day in loc), or a
+// duration accepted by ParseDuration (e.g. "24h", "7d"), meaning that long
+// before now.
+func ParseTimeOrDuration(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, nil
+	}
+	if d, err := ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC3339, a date like 2024-01-02, or a duration like 24h or 7d", s)
+}
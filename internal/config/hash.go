@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// configHashLength is the number of hex digits of a connection hash.
+const configHashLength = 12
+
+// ConnectionHash returns a short hash of the settings that affect the
+// measurements of a connection: its source binding and DSCP value, and the
+// server selection, test sizes, warmup, threads and proxy of st. Results with different
+// hashes were produced under different configurations and may not be
+// comparable.
+// Settings that don't change what is measured (e.g. timeouts, the schedule
+// or the name) are not included.
+func ConnectionHash(conn ConnectionConfig, st SpeedtestConfig) string {
+	serverIDs := make([]string, len(st.ServerIDs))
+	for i, id := range st.ServerIDs {
+		serverIDs[i] = fmt.Sprint(id)
+	}
+
+	fields := []string{
+		"source_ip=" + conn.SourceIP,
+		"interface=" + conn.Interface,
+		fmt.Sprintf("dscp=%d", conn.DSCP),
+		"server_ids=" + strings.Join(serverIDs, ","),
+		"server_selection=" + st.ServerSelection,
+		"selection_latency_margin=" + st.SelectionLatencyMargin.String(),
+		"download_size=" + st.DownloadSize,
+		"upload_size=" + st.UploadSize,
+	}
+	// Only if set, so the hashes of existing results stay valid
+	if st.Warmup > 0 {
+		fields = append(fields, "warmup="+st.Warmup.String())
+	}
+	if st.Threads > 0 {
+		fields = append(fields, "threads="+strconv.Itoa(st.Threads))
+	}
+	// The proxy's address, without credentials
+	if proxy, err := st.ParseProxyURL(); err == nil && proxy != nil {
+		fields = append(fields, "proxy="+proxy.Scheme+"://"+ProxyAddress(proxy))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])[:configHashLength]
+}
@@ -2,9 +2,15 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -19,23 +25,47 @@ var DefaultConfigPaths = []string{
 	"./flowgauge.yml",
 }
 
+// DropInDirName is the name of the drop-in directory, relative to the
+// directory of the main config file (e.g. /etc/flowgauge/conf.d).
+const DropInDirName = "conf.d"
+
+// StdinConfigPath is the config path that reads the configuration from stdin.
+const StdinConfigPath = "-"
+
+// ConfigFetchTimeout limits fetching a configuration from an HTTP(S) URL.
+const ConfigFetchTimeout = 30 * time.Second
+
+// maxRemoteConfigSize limits the size of a configuration read from stdin or
+// a URL, so a misbehaving source can't exhaust memory.
+const maxRemoteConfigSize = 10 << 20
+
 // Load reads and parses a configuration file from the given path.
 // If path is empty, it searches DefaultConfigPaths.
 // Environment variable FLOWGAUGE_CONFIG takes precedence over defaults.
+// The path may also be StdinConfigPath or an http:// or https:// URL;
+// drop-in files are only merged for local files.
 func Load(path string) (*Config, error) {
 	configPath, err := resolveConfigPath(path)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := os.ReadFile(configPath)
+	data, err := readConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+		return nil, err
 	}
 
 	cfg := &Config{}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	if err := decodeYAML(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", configSource(configPath), err)
+	}
+
+	// Merge drop-in files from conf.d over the base config
+	if isLocalConfigPath(configPath) {
+		dropInDir := filepath.Join(filepath.Dir(configPath), DropInDirName)
+		if err := loadDropIns(cfg, dropInDir); err != nil {
+			return nil, err
+		}
 	}
 
 	// Apply defaults for missing values
@@ -49,11 +79,146 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// readConfig returns the content of a local config file, stdin or a URL.
+func readConfig(path string) ([]byte, error) {
+	switch {
+	case path == StdinConfigPath:
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, maxRemoteConfigSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config from stdin: %w", err)
+		}
+		// Stdin can only be read once, e.g. not again on reload
+		if len(data) == 0 {
+			return nil, fmt.Errorf("no configuration received on stdin")
+		}
+		return data, nil
+	case isConfigURL(path):
+		return fetchConfig(path)
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		return data, nil
+	}
+}
+
+// fetchConfig downloads a configuration from an HTTP(S) URL.
+func fetchConfig(rawURL string) ([]byte, error) {
+	client := &http.Client{Timeout: ConfigFetchTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config from %s: %w", configSource(rawURL), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config from %s: %s", configSource(rawURL), resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from %s: %w", configSource(rawURL), err)
+	}
+	return data, nil
+}
+
+// isConfigURL reports whether path is an http:// or https:// URL.
+func isConfigURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// isLocalConfigPath reports whether path refers to a local file.
+func isLocalConfigPath(path string) bool {
+	return path != StdinConfigPath && !isConfigURL(path)
+}
+
+// configSource describes where a configuration is read from for messages,
+// with credentials removed from URLs.
+func configSource(path string) string {
+	switch {
+	case path == StdinConfigPath:
+		return "stdin"
+	case isConfigURL(path):
+		u, err := url.Parse(path)
+		if err != nil {
+			return path
+		}
+		return u.Redacted()
+	default:
+		return path
+	}
+}
+
+// loadDropIns merges all *.yaml and *.yml files in dir over cfg, in
+// lexical order. A missing directory is not an error.
+//
+// Merge semantics:
+//   - Scalars and nested settings present in a drop-in override the base value.
+//   - Lists other than connections (e.g. server_ids) replace the base list.
+//   - Connections are merged by name: a connection with an existing name
+//     replaces it entirely, new names are appended.
+func loadDropIns(cfg *Config, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return fmt.Errorf("failed to list drop-in files in %s: %w", dir, err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read drop-in file %s: %w", file, err)
+		}
+
+		// Decode over the current config so only fields present in the
+		// drop-in are overridden. Connections are collected separately.
+		base := cfg.Connections
+		cfg.Connections = nil
+		if err := decodeYAML(data, cfg); err != nil {
+			return fmt.Errorf("failed to parse drop-in file %s: %w", file, err)
+		}
+		cfg.Connections = mergeConnections(base, cfg.Connections)
+	}
+
+	return nil
+}
+
+// mergeConnections overrides connections in base by name and appends new ones.
+func mergeConnections(base, overrides []ConnectionConfig) []ConnectionConfig {
+	for _, conn := range overrides {
+		replaced := false
+		for i := range base {
+			if base[i].Name == conn.Name {
+				base[i] = conn
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			base = append(base, conn)
+		}
+	}
+	return base
+}
+
 // resolveConfigPath determines which config file to use.
 // Priority: explicit path > FLOWGAUGE_CONFIG env > default paths
 func resolveConfigPath(path string) (string, error) {
-	// 1. Explicit path provided
+	// 1. Explicit path provided (stdin and URLs are checked when read)
 	if path != "" {
+		if !isLocalConfigPath(path) {
+			return path, nil
+		}
 		if _, err := os.Stat(path); err != nil {
 			return "", fmt.Errorf("config file not found: %s", path)
 		}
@@ -62,6 +227,9 @@ func resolveConfigPath(path string) (string, error) {
 
 	// 2. Environment variable
 	if envPath := os.Getenv("FLOWGAUGE_CONFIG"); envPath != "" {
+		if !isLocalConfigPath(envPath) {
+			return envPath, nil
+		}
 		if _, err := os.Stat(envPath); err != nil {
 			return "", fmt.Errorf("config file from FLOWGAUGE_CONFIG not found: %s", envPath)
 		}
@@ -91,43 +259,100 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("invalid log_level: %q (must be debug, info, warn, or error)", cfg.General.LogLevel)
 	}
 
-	// Validate storage type
-	validStorageTypes := map[string]bool{
-		"sqlite":   true,
-		"postgres": true,
+	// Validate timezone
+	if _, err := LoadLocation(cfg.General.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %q: %w", cfg.General.Timezone, err)
 	}
-	if !validStorageTypes[cfg.Storage.Type] {
-		return fmt.Errorf("invalid storage type: %q (must be sqlite or postgres)", cfg.Storage.Type)
+
+	// Validate speed unit
+	validSpeedUnits := map[string]bool{
+		"mbps":   true,
+		"gbps":   true,
+		"mbytes": true,
+	}
+	if !validSpeedUnits[cfg.General.SpeedUnit] {
+		return fmt.Errorf("invalid speed_unit: %q (must be mbps, gbps, or mbytes)", cfg.General.SpeedUnit)
 	}
 
-	// Validate SQLite path if using SQLite
-	if cfg.Storage.Type == "sqlite" && cfg.Storage.SQLite.Path == "" {
-		return fmt.Errorf("sqlite path is required when storage type is sqlite")
+	if cfg.General.RoundDecimals > 10 {
+		return fmt.Errorf("invalid round_decimals: %d (must be at most 10)", cfg.General.RoundDecimals)
 	}
 
-	// Validate PostgreSQL config if using PostgreSQL
-	if cfg.Storage.Type == "postgres" {
-		if cfg.Storage.Postgres.Host == "" {
-			return fmt.Errorf("postgres host is required when storage type is postgres")
+	// Validate storage backends
+	if err := validateStorageBackend(cfg.Storage.Primary()); err != nil {
+		return err
+	}
+	for i, b := range cfg.Storage.Backends {
+		if err := validateStorageBackend(b); err != nil {
+			return fmt.Errorf("storage backend %d: %w", i+1, err)
 		}
-		if cfg.Storage.Postgres.Database == "" {
-			return fmt.Errorf("postgres database is required when storage type is postgres")
+		if b.Type == "sqlite" && cfg.Storage.Type == "sqlite" && b.SQLite.Path == cfg.Storage.SQLite.Path {
+			return fmt.Errorf("storage backend %d: sqlite path %q is already used by the primary backend", i+1, b.SQLite.Path)
+		}
+	}
+	if cfg.Storage.Rollup.Enabled {
+		if err := validateRollup(cfg.Storage.Rollup); err != nil {
+			return err
+		}
+	}
+	if cfg.Storage.Thinning.Enabled {
+		if err := validateThinning(cfg.Storage.Thinning); err != nil {
+			return err
 		}
 	}
 
 	// Validate webserver listen address
 	if cfg.Webserver.Enabled {
-		if _, _, err := net.SplitHostPort(cfg.Webserver.Listen); err != nil {
+		if path, ok := cfg.Webserver.UnixSocketPath(); ok {
+			if path == "" {
+				return fmt.Errorf("invalid webserver listen address %q: missing socket path", cfg.Webserver.Listen)
+			}
+			if _, err := cfg.Webserver.ParseSocketMode(); err != nil {
+				return fmt.Errorf("invalid webserver socket_mode: %w", err)
+			}
+		} else if _, _, err := net.SplitHostPort(cfg.Webserver.Listen); err != nil {
 			return fmt.Errorf("invalid webserver listen address %q: %w", cfg.Webserver.Listen, err)
 		}
 	}
 
+	// Validate results limit
+	if cfg.Webserver.MaxResultsLimit < 1 || cfg.Webserver.MaxResultsLimit > MaxResultsLimitCeiling {
+		return fmt.Errorf("invalid webserver max_results_limit: %d (must be between 1 and %d)",
+			cfg.Webserver.MaxResultsLimit, MaxResultsLimitCeiling)
+	}
+
+	if err := validateDashboard(cfg.Webserver.Dashboard); err != nil {
+		return fmt.Errorf("invalid webserver dashboard config: %w", err)
+	}
+
+	if cfg.Webserver.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid webserver max_header_bytes: %d (must not be negative)", cfg.Webserver.MaxHeaderBytes)
+	}
+	if cfg.Webserver.IdleTimeout < 0 {
+		return fmt.Errorf("invalid webserver idle_timeout: %s (must not be negative)", cfg.Webserver.IdleTimeout)
+	}
+	if cfg.Webserver.RequestTimeout < 0 {
+		return fmt.Errorf("invalid webserver request_timeout: %s (must not be negative)", cfg.Webserver.RequestTimeout)
+	}
+	if cfg.Webserver.TriggerConcurrency < 1 {
+		return fmt.Errorf("invalid webserver trigger_concurrency: %d (must be at least 1)", cfg.Webserver.TriggerConcurrency)
+	}
+	if cfg.Webserver.Theme != ThemeDark && cfg.Webserver.Theme != ThemeLight {
+		return fmt.Errorf("invalid webserver theme: %q (must be dark or light)", cfg.Webserver.Theme)
+	}
+	if host := cfg.Webserver.CanonicalHost; host != "" {
+		if u, err := url.Parse("//" + host); err != nil || u.Host != host || u.User != nil {
+			return fmt.Errorf("invalid webserver canonical_host: %q (must be a host name, optionally with port, e.g. flowgauge.example.com)", host)
+		}
+	}
+
 	// Validate connections
 	if len(cfg.Connections) == 0 {
 		return fmt.Errorf("at least one connection must be configured")
 	}
 
 	connectionNames := make(map[string]bool)
+	var primary string
 	for i, conn := range cfg.Connections {
 		if conn.Name == "" {
 			return fmt.Errorf("connection[%d]: name is required", i)
@@ -136,6 +361,16 @@ func Validate(cfg *Config) error {
 			return fmt.Errorf("connection[%d]: duplicate connection name %q", i, conn.Name)
 		}
 		connectionNames[conn.Name] = true
+		if cfg.Speedtest.Aggregate && conn.Name == AggregateConnectionName {
+			return fmt.Errorf("connection[%d]: name %q is reserved for aggregate results", i, conn.Name)
+		}
+
+		if conn.Primary {
+			if primary != "" {
+				return fmt.Errorf("connection %q: only one connection can be primary, %q already is", conn.Name, primary)
+			}
+			primary = conn.Name
+		}
 
 		// Validate DSCP value (0-63)
 		if conn.DSCP < 0 || conn.DSCP > 63 {
@@ -148,6 +383,25 @@ func Validate(cfg *Config) error {
 				return fmt.Errorf("connection %q: invalid source_ip %q", conn.Name, conn.SourceIP)
 			}
 		}
+
+		if conn.Timeout < 0 {
+			return fmt.Errorf("connection %q: invalid timeout %s (must be positive)", conn.Name, conn.Timeout)
+		}
+
+		// A source IP belongs to a single address family
+		if conn.DualStack && conn.SourceIP != "" {
+			return fmt.Errorf("connection %q: dual_stack can't be combined with source_ip, use interface instead", conn.Name)
+		}
+
+		for _, key := range conn.APIKeys {
+			if key == "" {
+				return fmt.Errorf("connection %q: api_keys must not be empty", conn.Name)
+			}
+		}
+		// Without auth, requests without a key could read all connections
+		if len(conn.APIKeys) > 0 && (cfg.Webserver.Auth == nil || cfg.Webserver.Auth.Username == "") {
+			return fmt.Errorf("connection %q: api_keys require webserver.auth", conn.Name)
+		}
 	}
 
 	// Validate speedtest config
@@ -165,6 +419,225 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("invalid speedtest upload_size: %q", cfg.Speedtest.UploadSize)
 	}
 
+	validOrders := map[string]bool{
+		"config":      true,
+		"random":      true,
+		"round_robin": true,
+	}
+	if !validOrders[cfg.Speedtest.Order] {
+		return fmt.Errorf("invalid speedtest order: %q (must be config, random, or round_robin)", cfg.Speedtest.Order)
+	}
+
+	validSelections := map[string]bool{
+		"latency": true,
+		"history": true,
+	}
+	if !validSelections[cfg.Speedtest.ServerSelection] {
+		return fmt.Errorf("invalid speedtest server_selection: %q (must be latency or history)", cfg.Speedtest.ServerSelection)
+	}
+
+	if cfg.Speedtest.SelectionLatencyMargin < 0 {
+		return fmt.Errorf("invalid speedtest selection_latency_margin: %s (must not be negative)", cfg.Speedtest.SelectionLatencyMargin)
+	}
+
+	if cfg.Speedtest.Warmup < 0 || cfg.Speedtest.Warmup > MaxWarmup {
+		return fmt.Errorf("invalid speedtest warmup: %s (must be between 0 and %s)", cfg.Speedtest.Warmup, MaxWarmup)
+	}
+
+	if cfg.Speedtest.Threads < 0 || cfg.Speedtest.Threads > MaxThreads {
+		return fmt.Errorf("invalid speedtest threads: %d (must be between 1 and %d, or 0 for one per CPU)", cfg.Speedtest.Threads, MaxThreads)
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Speedtest.ReachabilityTarget); err != nil {
+		return fmt.Errorf("invalid speedtest reachability_target %q: %w", cfg.Speedtest.ReachabilityTarget, err)
+	}
+	if _, err := cfg.Speedtest.ParseProxyURL(); err != nil {
+		return fmt.Errorf("invalid speedtest proxy_url %q: %w", cfg.Speedtest.ProxyURL, err)
+	}
+
+	if cfg.Speedtest.Aggregate && !cfg.Speedtest.Parallel {
+		return fmt.Errorf("invalid speedtest aggregate: requires speedtest.parallel to be enabled")
+	}
+
+	if cfg.Scheduler.Jitter < 0 {
+		return fmt.Errorf("invalid scheduler jitter: %s (must not be negative)", cfg.Scheduler.Jitter)
+	}
+
+	// Validate the cron expression now rather than when the scheduler starts
+	if _, err := cfg.Scheduler.ParseSchedule(); err != nil {
+		return fmt.Errorf("invalid scheduler schedule %q: %w", cfg.Scheduler.Schedule, err)
+	}
+
+	if cfg.Scheduler.CatchUpThreshold < 0 {
+		return fmt.Errorf("invalid scheduler catch_up_threshold: %s (must not be negative)", cfg.Scheduler.CatchUpThreshold)
+	}
+
+	// Validate scheduler maintenance windows
+	if _, err := cfg.Scheduler.ParseSkipWindows(); err != nil {
+		return fmt.Errorf("invalid scheduler skip_windows: %w", err)
+	}
+
+	if err := validateAlerts(cfg.Alerts); err != nil {
+		return fmt.Errorf("invalid alerts config: %w", err)
+	}
+	if cfg.Reports.Enabled {
+		if err := validateReports(cfg.Reports); err != nil {
+			return fmt.Errorf("invalid reports config: %w", err)
+		}
+	}
+
+	if cfg.Prometheus.ClearValue != "nan" && cfg.Prometheus.ClearValue != "zero" {
+		return fmt.Errorf("invalid prometheus clear_value: %q (must be nan or zero)", cfg.Prometheus.ClearValue)
+	}
+	if err := validateBuckets(cfg.Prometheus.LatencyBuckets); err != nil {
+		return fmt.Errorf("invalid prometheus latency_buckets: %w", err)
+	}
+	if err := validateBuckets(cfg.Prometheus.ThroughputBuckets); err != nil {
+		return fmt.Errorf("invalid prometheus throughput_buckets: %w", err)
+	}
+
+	return nil
+}
+
+// validateBuckets checks that histogram buckets are positive and strictly
+// increasing, as required by Prometheus.
+func validateBuckets(buckets []float64) error {
+	for i, b := range buckets {
+		if b <= 0 {
+			return fmt.Errorf("%g (must be positive)", b)
+		}
+		if i > 0 && b <= buckets[i-1] {
+			return fmt.Errorf("%g after %g (must be strictly increasing)", b, buckets[i-1])
+		}
+	}
+	return nil
+}
+
+// validateAlerts checks the alert thresholds and webhook URL.
+func validateAlerts(a AlertsConfig) error {
+	if a.Consecutive < 1 {
+		return fmt.Errorf("consecutive must be at least 1, got %d", a.Consecutive)
+	}
+	if a.MinDownloadMbps < 0 || a.MinUploadMbps < 0 || a.MaxLatencyMs < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if a.BaselineFraction < 0 || a.BaselineFraction > 1 {
+		return fmt.Errorf("baseline_fraction must be between 0 and 1, got %g", a.BaselineFraction)
+	}
+	if a.WebhookURL != "" {
+		u, err := url.Parse(a.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook_url: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook_url %q: must be an http or https URL", a.WebhookURL)
+		}
+	}
+	return nil
+}
+
+// validateReports checks the schedule, period, format and delivery targets
+// of the reports.
+func validateReports(r ReportsConfig) error {
+	if _, err := r.ParseSchedule(); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", r.Schedule, err)
+	}
+	if period, err := r.PeriodDuration(); err != nil || period < time.Hour {
+		return fmt.Errorf("invalid period %q: must be a duration of at least 1h, e.g. 7d", r.Period)
+	}
+	if r.Format != "markdown" && r.Format != "html" {
+		return fmt.Errorf("invalid format %q: must be markdown or html", r.Format)
+	}
+	if r.Directory == "" && r.WebhookURL == "" {
+		return fmt.Errorf("directory or webhook_url is required")
+	}
+	if r.WebhookURL != "" {
+		u, err := url.Parse(r.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook_url: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook_url %q: must be an http or https URL", r.WebhookURL)
+		}
+	}
+	return nil
+}
+
+// validateDashboard checks the dashboard chart window and point limits.
+func validateDashboard(d DashboardConfig) error {
+	if d.ChartWindow < MinChartWindow || d.ChartWindow > MaxChartWindow {
+		return fmt.Errorf("chart_window must be between %s and %s, got %s",
+			MinChartWindow, MaxChartWindow, d.ChartWindow)
+	}
+	if d.ChartPointsPerHour < 1 {
+		return fmt.Errorf("chart_points_per_hour must be at least 1, got %d", d.ChartPointsPerHour)
+	}
+	if d.MinChartPoints < 1 || d.MaxChartPoints > ChartPointsCeiling || d.MinChartPoints > d.MaxChartPoints {
+		return fmt.Errorf("min_chart_points (%d) and max_chart_points (%d) must satisfy 1 <= min <= max <= %d",
+			d.MinChartPoints, d.MaxChartPoints, ChartPointsCeiling)
+	}
+	return nil
+}
+
+// validateStorageBackend checks the type and required settings of a backend.
+func validateStorageBackend(b StorageBackendConfig) error {
+	switch b.Type {
+	case "sqlite":
+		if b.SQLite.Path == "" {
+			return fmt.Errorf("sqlite path is required when storage type is sqlite")
+		}
+	case "postgres":
+		if b.Postgres.Host == "" {
+			return fmt.Errorf("postgres host is required when storage type is postgres")
+		}
+		if b.Postgres.Database == "" {
+			return fmt.Errorf("postgres database is required when storage type is postgres")
+		}
+	case "memory":
+		if b.Memory.MaxResults < 0 {
+			return fmt.Errorf("invalid memory max_results: %d (must be 0 or greater)", b.Memory.MaxResults)
+		}
+	default:
+		return fmt.Errorf("invalid storage type: %q (must be sqlite, postgres or memory)", b.Type)
+	}
+	return nil
+}
+
+// validateRollup checks the schedule and retention periods of the rollup job.
+func validateRollup(r RollupConfig) error {
+	if _, err := r.ParseSchedule(); err != nil {
+		return fmt.Errorf("invalid storage rollup schedule %q: %w", r.Schedule, err)
+	}
+	raw, err := r.RawRetentionDuration()
+	if err != nil || raw < time.Hour {
+		return fmt.Errorf("invalid storage rollup raw_retention: %q (must be a duration of at least 1h, e.g. 30d)", r.RawRetention)
+	}
+	retention, err := r.RetentionDuration()
+	if err != nil || (retention != 0 && retention <= raw) {
+		return fmt.Errorf("invalid storage rollup retention: %q (must be 0 or longer than raw_retention)", r.Retention)
+	}
+	return nil
+}
+
+// validateThinning checks the schedule and tiers of the thinning job.
+func validateThinning(t ThinningConfig) error {
+	if _, err := t.ParseSchedule(); err != nil {
+		return fmt.Errorf("invalid storage thinning schedule %q: %w", t.Schedule, err)
+	}
+	if len(t.Tiers) == 0 {
+		return fmt.Errorf("storage thinning requires at least one tier")
+	}
+	var previous time.Duration
+	for i, tier := range t.Tiers {
+		after, err := tier.AfterDuration()
+		if err != nil || after <= previous {
+			return fmt.Errorf("invalid storage thinning tier %d after: %q (must be a positive duration, longer than that of the previous tier)", i+1, tier.After)
+		}
+		if tier.KeepEvery < 2 {
+			return fmt.Errorf("invalid storage thinning tier %d keep_every: %d (must be at least 2)", i+1, tier.KeepEvery)
+		}
+		previous = after
+	}
 	return nil
 }
 
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// proxyDefaultPorts are the supported schemes of speedtest.proxy_url and
+// their default ports.
+var proxyDefaultPorts = map[string]string{
+	"http":    "80",
+	"https":   "443",
+	"socks5":  "1080",
+	"socks5h": "1080",
+}
+
+// ParseProxyURL parses the proxy URL, returning nil if no proxy is set.
+func (c *SpeedtestConfig) ParseProxyURL() (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := proxyDefaultPorts[u.Scheme]; !ok {
+		return nil, fmt.Errorf("unsupported scheme %q (must be http, https, socks5 or socks5h)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing proxy host")
+	}
+	return u, nil
+}
+
+// ProxyAddress returns the host:port of a proxy URL, with the default port
+// of its scheme if it has none.
+func ProxyAddress(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = proxyDefaultPorts[u.Scheme]
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedactedValue replaces sensitive values in sanitized configurations.
+const RedactedValue = "***"
+
+// Sanitize returns a deep copy of the configuration with passwords and
+// other secrets masked. Empty secrets are left empty so it remains visible
+// whether a value is set.
+func (c *Config) Sanitize() *Config {
+	clone := *c
+
+	clone.Connections = append([]ConnectionConfig(nil), c.Connections...)
+	for i := range clone.Connections {
+		keys := make([]string, len(c.Connections[i].APIKeys))
+		for j, key := range c.Connections[i].APIKeys {
+			keys[j] = redact(key)
+		}
+		if len(keys) > 0 {
+			clone.Connections[i].APIKeys = keys
+		}
+	}
+	clone.Scheduler.SkipWindows = append([]string(nil), c.Scheduler.SkipWindows...)
+	clone.Speedtest.ServerIDs = append([]int(nil), c.Speedtest.ServerIDs...)
+
+	clone.Storage.Postgres.Password = redact(c.Storage.Postgres.Password)
+	clone.Storage.Backends = append([]StorageBackendConfig(nil), c.Storage.Backends...)
+	for i := range clone.Storage.Backends {
+		clone.Storage.Backends[i].Postgres.Password = redact(c.Storage.Backends[i].Postgres.Password)
+	}
+	// Webhook URLs often embed an access token
+	clone.Alerts.WebhookURL = redact(c.Alerts.WebhookURL)
+	clone.Reports.WebhookURL = redact(c.Reports.WebhookURL)
+	clone.Speedtest.ProxyURL = redactURLPassword(c.Speedtest.ProxyURL)
+
+	if c.Webserver.Auth != nil {
+		auth := *c.Webserver.Auth
+		auth.Password = redact(auth.Password)
+		clone.Webserver.Auth = &auth
+	}
+
+	return &clone
+}
+
+// redactURLPassword masks the password of a URL, keeping the rest visible.
+// URLs that can't be parsed are masked entirely.
+func redactURLPassword(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redact(s)
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	// Set the userinfo by hand, as url.UserPassword would escape the mask
+	user := url.User(u.User.Username()).String()
+	u.User = nil
+	return strings.Replace(u.String(), "//", "//"+user+":"+RedactedValue+"@", 1)
+}
+
+// redact masks a non-empty secret.
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return RedactedValue
+}
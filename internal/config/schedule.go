@@ -0,0 +1,28 @@
+package config
+
+import "github.com/robfig/cron/v3"
+
+// ParseSchedule parses the cron expression with the same standard parser the
+// scheduler uses: five fields (minute hour day month weekday), optionally
+// prefixed with CRON_TZ=, or a descriptor such as "@hourly".
+func (c *SchedulerConfig) ParseSchedule() (cron.Schedule, error) {
+	return cron.ParseStandard(c.Schedule)
+}
+
+// ParseSchedule parses the cron expression of the rollup job with the same
+// standard parser.
+func (c RollupConfig) ParseSchedule() (cron.Schedule, error) {
+	return cron.ParseStandard(c.Schedule)
+}
+
+// ParseSchedule parses the cron expression of the thinning job with the same
+// standard parser.
+func (c ThinningConfig) ParseSchedule() (cron.Schedule, error) {
+	return cron.ParseStandard(c.Schedule)
+}
+
+// ParseSchedule parses the cron expression of the reports with the same
+// standard parser.
+func (c ReportsConfig) ParseSchedule() (cron.Schedule, error) {
+	return cron.ParseStandard(c.Schedule)
+}
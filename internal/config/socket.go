@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unixListenPrefix marks a webserver listen address as a Unix domain socket path.
+const unixListenPrefix = "unix:"
+
+// UnixSocketPath returns the socket path if Listen is a Unix domain socket
+// address (e.g. "unix:/run/flowgauge.sock").
+func (c *WebserverConfig) UnixSocketPath() (string, bool) {
+	return strings.CutPrefix(c.Listen, unixListenPrefix)
+}
+
+// ParseSocketMode parses SocketMode as octal file permissions (e.g. "0660").
+func (c *WebserverConfig) ParseSocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("%q is not an octal file mode (e.g. \"0660\")", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
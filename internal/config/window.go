@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily time range, e.g. "02:00-04:00".
+// A window whose end is before its start wraps around midnight.
+type TimeWindow struct {
+	// Start and End are offsets from midnight
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseTimeWindow parses a time window in "HH:MM-HH:MM" format.
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q (expected HH:MM-HH:MM)", s)
+	}
+
+	start, err := parseClock(startStr)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if start == end {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: start and end are equal", s)
+	}
+
+	return TimeWindow{Start: start, End: end}, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether the wall-clock time of t lies within the window.
+// The start is inclusive, the end exclusive.
+func (w TimeWindow) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// String returns the window in "HH:MM-HH:MM" format.
+func (w TimeWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d",
+		int(w.Start.Hours()), int(w.Start.Minutes())%60,
+		int(w.End.Hours()), int(w.End.Minutes())%60)
+}
+
+// ParseSkipWindows parses the configured maintenance windows.
+func (c *SchedulerConfig) ParseSkipWindows() ([]TimeWindow, error) {
+	windows := make([]TimeWindow, 0, len(c.SkipWindows))
+	for _, s := range c.SkipWindows {
+		w, err := ParseTimeWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
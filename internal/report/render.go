@@ -0,0 +1,174 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// noValue is shown for values that can't be computed, e.g. averages of a
+// period without successful tests.
+const noValue = "-"
+
+// row is a metric of a connection as rendered in the report table.
+type row struct {
+	Metric   string
+	Average  string
+	Min      string
+	Max      string
+	Previous string
+	Change   string
+}
+
+// section is a connection as rendered in the report.
+type section struct {
+	Name       string
+	Rows       []row
+	TestCount  int
+	ErrorCount int
+}
+
+// Render renders the report in the given format, with speeds in unit.
+func (r *Report) Render(format string, unit speedtest.SpeedUnit) (string, error) {
+	sections := make([]section, 0, len(r.Connections))
+	for _, c := range r.Connections {
+		sections = append(sections, section{
+			Name:       c.Name,
+			Rows:       rows(c.Current, c.Previous, unit),
+			TestCount:  c.Current.TestCount,
+			ErrorCount: c.Current.ErrorCount,
+		})
+	}
+
+	switch format {
+	case FormatMarkdown:
+		return r.renderMarkdown(sections), nil
+	case FormatHTML:
+		return r.renderHTML(sections)
+	default:
+		return "", fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func (r *Report) renderMarkdown(sections []section) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	if len(sections) == 0 {
+		b.WriteString("No connections.\n")
+	}
+	for _, s := range sections {
+		fmt.Fprintf(&b, "## %s\n\n", s.Name)
+		fmt.Fprintf(&b, "%d tests, %d failed.\n\n", s.TestCount, s.ErrorCount)
+		b.WriteString("| Metric | Average | Min | Max | Previous average | Change |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+		for _, row := range s.Rows {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				row.Metric, row.Average, row.Min, row.Max, row.Previous, row.Change)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "_Generated by FlowGauge at %s._\n", r.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	return b.String()
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td { text-align: right; }
+td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Sections}}
+<h2>{{.Name}}</h2>
+<p>{{.TestCount}} tests, {{.ErrorCount}} failed.</p>
+<table>
+<tr><th>Metric</th><th>Average</th><th>Min</th><th>Max</th><th>Previous average</th><th>Change</th></tr>
+{{- range .Rows}}
+<tr><td>{{.Metric}}</td><td>{{.Average}}</td><td>{{.Min}}</td><td>{{.Max}}</td><td>{{.Previous}}</td><td>{{.Change}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No connections.</p>
+{{- end}}
+<p><em>Generated by FlowGauge at {{.GeneratedAt}}.</em></p>
+</body>
+</html>
+`))
+
+func (r *Report) renderHTML(sections []section) (string, error) {
+	var b strings.Builder
+	err := htmlTemplate.Execute(&b, map[string]any{
+		"Title":       r.Title(),
+		"Sections":    sections,
+		"GeneratedAt": r.GeneratedAt.Format("2006-01-02 15:04 MST"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.String(), nil
+}
+
+// rows returns the metrics of a connection in the current period, compared
+// with the previous one.
+func rows(current, previous *storage.Stats, unit speedtest.SpeedUnit) []row {
+	ok := current.TestCount > current.ErrorCount
+	prevOK := previous.TestCount > previous.ErrorCount
+
+	speed := func(mbps float64) string {
+		return fmt.Sprintf("%.2f %s", unit.Convert(mbps), unit.Label())
+	}
+	ms := func(v float64) string {
+		return fmt.Sprintf("%.1f ms", v)
+	}
+	metric := func(name string, format func(float64) string, avg, lo, hi, prevAvg float64) row {
+		r := row{Metric: name, Average: noValue, Min: noValue, Max: noValue, Previous: noValue, Change: noValue}
+		if ok {
+			r.Average, r.Min, r.Max = format(avg), format(lo), format(hi)
+		}
+		if prevOK {
+			r.Previous = format(prevAvg)
+		}
+		if ok && prevOK {
+			r.Change = percentChange(avg, prevAvg)
+		}
+		return r
+	}
+
+	uptime := row{Metric: "Uptime", Average: noValue, Min: noValue, Max: noValue, Previous: noValue, Change: noValue}
+	if current.TestCount > 0 {
+		uptime.Average = fmt.Sprintf("%.1f%%", current.Uptime)
+	}
+	if previous.TestCount > 0 {
+		uptime.Previous = fmt.Sprintf("%.1f%%", previous.Uptime)
+	}
+	if current.TestCount > 0 && previous.TestCount > 0 {
+		uptime.Change = fmt.Sprintf("%+.1f pp", current.Uptime-previous.Uptime)
+	}
+
+	return []row{
+		metric("Download", speed, current.AvgDownload, current.MinDownload, current.MaxDownload, previous.AvgDownload),
+		metric("Upload", speed, current.AvgUpload, current.MinUpload, current.MaxUpload, previous.AvgUpload),
+		metric("Latency", ms, current.AvgLatency, current.MinLatency, current.MaxLatency, previous.AvgLatency),
+		metric("Jitter", ms, current.AvgJitter, current.MinJitter, current.MaxJitter, previous.AvgJitter),
+		uptime,
+	}
+}
+
+// percentChange formats the relative change from previous to current.
+func percentChange(current, previous float64) string {
+	if previous == 0 {
+		return noValue
+	}
+	return fmt.Sprintf("%+.1f%%", (current-previous)/previous*100)
+}
@@ -0,0 +1,75 @@
+// Package report generates summary reports of the connections' statistics
+// over a period, compared with the period before it.
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// Formats of a rendered report.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Report summarizes the connections over a period.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Since       time.Time `json:"since"`
+	Until       time.Time `json:"until"`
+	// Connections are in the order they were requested
+	Connections []ConnectionSummary `json:"connections"`
+}
+
+// ConnectionSummary holds the statistics of a connection in the report
+// period and in the period of the same length before it.
+type ConnectionSummary struct {
+	Name     string         `json:"name"`
+	Current  *storage.Stats `json:"current"`
+	Previous *storage.Stats `json:"previous"`
+}
+
+// Generate builds the report of the given connections over the period
+// ending at now. Timestamps are converted to loc.
+func Generate(ctx context.Context, store storage.Storage, connections []string, period time.Duration, now time.Time, loc *time.Location) (*Report, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	since := now.Add(-period)
+
+	report := &Report{
+		GeneratedAt: now.In(loc),
+		Since:       since.In(loc),
+		Until:       now.In(loc),
+		Connections: make([]ConnectionSummary, 0, len(connections)),
+	}
+	for _, name := range connections {
+		current, err := store.GetStatsBetween(ctx, name, since, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats of %s: %w", name, err)
+		}
+		previous, err := store.GetStatsBetween(ctx, name, since.Add(-period), since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get previous stats of %s: %w", name, err)
+		}
+		current.InLocation(loc)
+		previous.InLocation(loc)
+
+		report.Connections = append(report.Connections, ConnectionSummary{
+			Name:     name,
+			Current:  current,
+			Previous: previous,
+		})
+	}
+	return report, nil
+}
+
+// Title returns the title of the report, with the dates of its period.
+func (r *Report) Title() string {
+	return fmt.Sprintf("FlowGauge report %s to %s",
+		r.Since.Format("2006-01-02 15:04"), r.Until.Format("2006-01-02 15:04"))
+}
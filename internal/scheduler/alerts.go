@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// webhookTimeout bounds the delivery of a single alert notification.
+const webhookTimeout = 10 * time.Second
+
+// baselinePeriod is how far back results are included in the baseline.
+const baselinePeriod = 7 * 24 * time.Hour
+
+// baselineMinSamples is the number of results an hour of day needs before
+// results are compared to its baseline.
+const baselineMinSamples = 3
+
+// Alert event types.
+const (
+	AlertEventAlert    = "alert"
+	AlertEventRecovery = "recovery"
+)
+
+// AlertEvent is the notification sent when a connection starts or stops
+// being degraded.
+type AlertEvent struct {
+	// Type is AlertEventAlert or AlertEventRecovery
+	Type       string `json:"type"`
+	Connection string `json:"connection"`
+	// Consecutive is the number of consecutive breaching results
+	// (for recoveries, the number before the connection recovered)
+	Consecutive int `json:"consecutive"`
+	// Reasons describe why the triggering result breached (alerts only)
+	Reasons   []string          `json:"reasons,omitempty"`
+	Result    *speedtest.Result `json:"result"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// alertState tracks the breaches of one connection.
+type alertState struct {
+	breaches int
+	firing   bool
+}
+
+// AlertDetector fires a notification once a connection breached the alert
+// thresholds in alerts.consecutive scheduled results in a row, and a recovery
+// notification with the first result within the thresholds afterwards.
+// Single bad samples thus never alert.
+type AlertDetector struct {
+	logger *zap.Logger
+	client *http.Client
+
+	mu     sync.Mutex
+	config config.AlertsConfig
+	// states are kept per connection and address family, so the IPv4 and
+	// IPv6 results of dual-stack connections are counted separately
+	states map[string]*alertState
+	// store provides the baselines, computed by hour of day in location
+	store    storage.Storage
+	location *time.Location
+}
+
+// NewAlertDetector creates an alert detector.
+func NewAlertDetector(cfg config.AlertsConfig, logger *zap.Logger) *AlertDetector {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &AlertDetector{
+		logger: logger,
+		client: &http.Client{Timeout: webhookTimeout},
+		config: cfg,
+		states: make(map[string]*alertState),
+	}
+}
+
+// SetConfig applies new alert settings. The breach counts of connections are
+// kept, so a reload doesn't reset a pending or firing alert; disabling alerts
+// clears them.
+func (d *AlertDetector) SetConfig(cfg config.AlertsConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = cfg
+	if !cfg.Enabled {
+		d.states = make(map[string]*alertState)
+	}
+}
+
+// setBaselineSource sets the storage the baselines are queried from and the
+// timezone of their hours of day.
+func (d *AlertDetector) setBaselineSource(store storage.Storage, loc *time.Location) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store = store
+	d.location = loc
+}
+
+// Observe checks a result against the thresholds and sends a notification if
+// the connection's alert state changes. Aggregate results are ignored.
+// Results must be observed before they are saved, so they are not part of
+// their own baseline.
+func (d *AlertDetector) Observe(ctx context.Context, result *speedtest.Result) {
+	if result.IsAggregate() {
+		return
+	}
+
+	event := d.update(result, d.baseline(ctx, result))
+	if event == nil {
+		return
+	}
+
+	if event.Type == AlertEventAlert {
+		d.logger.Warn("Connection degraded",
+			zap.String("connection", event.Connection),
+			zap.Int("consecutive", event.Consecutive),
+			zap.Strings("reasons", event.Reasons),
+		)
+	} else {
+		d.logger.Info("Connection recovered",
+			zap.String("connection", event.Connection),
+			zap.Int("consecutive", event.Consecutive),
+		)
+	}
+
+	d.mu.Lock()
+	webhookURL := d.config.WebhookURL
+	d.mu.Unlock()
+	if webhookURL == "" {
+		return
+	}
+	if err := d.sendWebhook(ctx, webhookURL, event); err != nil {
+		d.logger.Error("Failed to send alert notification",
+			zap.String("connection", event.Connection),
+			zap.String("type", event.Type),
+			zap.Error(err),
+		)
+	}
+}
+
+// baseline returns the connection's baseline for the hour of day of result,
+// or nil if baselines are not checked or can't be determined.
+func (d *AlertDetector) baseline(ctx context.Context, result *speedtest.Result) *storage.Baseline {
+	d.mu.Lock()
+	enabled := d.config.Enabled && d.config.BaselineFraction > 0
+	store, loc := d.store, d.location
+	d.mu.Unlock()
+	if !enabled || store == nil || result.IsError() {
+		return nil
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	hour := result.Timestamp.In(loc).Hour()
+	baseline, err := store.GetBaseline(ctx, result.ConnectionName, result.IPFamily, hour,
+		result.Timestamp.Add(-baselinePeriod), loc)
+	if err != nil {
+		d.logger.Warn("Failed to get baseline, skipping baseline check",
+			zap.String("connection", result.ConnectionName),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return baseline
+}
+
+// update records a result and returns the event to send, if any.
+func (d *AlertDetector) update(result *speedtest.Result, baseline *storage.Baseline) *AlertEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.config.Enabled {
+		return nil
+	}
+
+	key := result.ConnectionName + "/" + result.IPFamily
+	state, ok := d.states[key]
+	if !ok {
+		state = &alertState{}
+		d.states[key] = state
+	}
+
+	reasons := breachReasons(d.config, result, baseline)
+	if len(reasons) == 0 {
+		breaches, firing := state.breaches, state.firing
+		state.breaches = 0
+		state.firing = false
+		if !firing {
+			return nil
+		}
+		return &AlertEvent{
+			Type:        AlertEventRecovery,
+			Connection:  result.ConnectionName,
+			Consecutive: breaches,
+			Result:      result,
+			Timestamp:   time.Now(),
+		}
+	}
+
+	state.breaches++
+	if state.firing || state.breaches < d.config.Consecutive {
+		return nil
+	}
+	state.firing = true
+	return &AlertEvent{
+		Type:        AlertEventAlert,
+		Connection:  result.ConnectionName,
+		Consecutive: state.breaches,
+		Reasons:     reasons,
+		Result:      result,
+		Timestamp:   time.Now(),
+	}
+}
+
+// breachReasons returns why a result is outside the thresholds, or nil if it
+// isn't. Values of failed phases are not compared, and baseline values only
+// once their hour of day has enough samples.
+func breachReasons(cfg config.AlertsConfig, result *speedtest.Result, baseline *storage.Baseline) []string {
+	if result.IsError() {
+		return []string{fmt.Sprintf("test failed: %s", result.Error)}
+	}
+
+	var reasons []string
+	if cfg.MinDownloadMbps > 0 && result.DownloadOK && result.DownloadMbps < cfg.MinDownloadMbps {
+		reasons = append(reasons, fmt.Sprintf("download %.2f Mbps below %.2f Mbps",
+			result.DownloadMbps, cfg.MinDownloadMbps))
+	}
+	if cfg.MinUploadMbps > 0 && result.UploadOK && result.UploadMbps < cfg.MinUploadMbps {
+		reasons = append(reasons, fmt.Sprintf("upload %.2f Mbps below %.2f Mbps",
+			result.UploadMbps, cfg.MinUploadMbps))
+	}
+	if cfg.MaxLatencyMs > 0 && result.LatencyOK && result.LatencyMs > cfg.MaxLatencyMs {
+		reasons = append(reasons, fmt.Sprintf("latency %.2f ms above %.2f ms",
+			result.LatencyMs, cfg.MaxLatencyMs))
+	}
+	if cfg.BaselineFraction > 0 && baseline != nil {
+		if result.DownloadOK && baseline.DownloadSamples >= baselineMinSamples &&
+			result.DownloadMbps < baseline.DownloadMbps*cfg.BaselineFraction {
+			reasons = append(reasons, fmt.Sprintf("download %.2f Mbps below %.0f%% of the %02d:00 baseline of %.2f Mbps",
+				result.DownloadMbps, cfg.BaselineFraction*100, baseline.Hour, baseline.DownloadMbps))
+		}
+		if result.UploadOK && baseline.UploadSamples >= baselineMinSamples &&
+			result.UploadMbps < baseline.UploadMbps*cfg.BaselineFraction {
+			reasons = append(reasons, fmt.Sprintf("upload %.2f Mbps below %.0f%% of the %02d:00 baseline of %.2f Mbps",
+				result.UploadMbps, cfg.BaselineFraction*100, baseline.Hour, baseline.UploadMbps))
+		}
+	}
+	return reasons
+}
+
+// sendWebhook posts the event as JSON to webhookURL.
+func (d *AlertDetector) sendWebhook(ctx context.Context, webhookURL string, event *AlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", withoutURL(err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// withoutURL strips the URL from a *url.Error, as returned by the HTTP
+// client. Webhook URLs often contain a token, so they must not be logged.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
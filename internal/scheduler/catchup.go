@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// catchUpCheckTimeout bounds the lookup of the latest results before a catch-up.
+const catchUpCheckTimeout = 30 * time.Second
+
+// CatchUp runs the speedtests once in the background if scheduler.catch_up is
+// enabled and a scheduled run was missed while FlowGauge wasn't running (e.g.
+// the machine was asleep or rebooting), so the time series has no gap.
+// Should be called once after Start.
+func (s *Scheduler) CatchUp() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running || !s.config.CatchUp {
+		return
+	}
+
+	cfg := *s.config
+	connections := s.runner.GetConnections()
+	job := s.newJob()
+	stop := s.stopCh
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		missed := missedRuns(ctx, s.storage, &cfg, connections, time.Now(), s.logger)
+		if len(missed) == 0 {
+			return
+		}
+		s.logger.Info("Scheduled run was missed, starting catch-up speedtest",
+			zap.Strings("connections", missed),
+		)
+
+		if job.paused() {
+			return
+		}
+		if window, ok := job.activeSkipWindow(time.Now()); ok {
+			s.logger.Info("In maintenance window, skipping catch-up speedtest",
+				zap.String("window", window.String()),
+			)
+			return
+		}
+		if !job.storageAvailable() {
+			return
+		}
+		if err := job.RunWithContext(ctx); err != nil {
+			s.logger.Error("Catch-up speedtest failed", zap.Error(err))
+		}
+	}()
+}
+
+// missedRuns compares the latest stored result of each connection to the
+// schedule and returns the connections whose next scheduled run after that
+// result is more than jitter plus catch_up_threshold in the past. Connections
+// without results are new and never counted as missed.
+func missedRuns(ctx context.Context, store storage.Storage, cfg *config.SchedulerConfig,
+	connections []speedtest.WANConnection, now time.Time, logger *zap.Logger) []string {
+	schedule, err := cfg.ParseSchedule()
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, catchUpCheckTimeout)
+	defer cancel()
+
+	latest, err := store.GetLatestResults(ctx)
+	if err != nil {
+		logger.Warn("Failed to get latest results, skipping catch-up check", zap.Error(err))
+		return nil
+	}
+
+	lastRun := make(map[string]time.Time, len(latest))
+	for _, r := range latest {
+		// The cron schedule is evaluated in local time, like the scheduler does
+		lastRun[r.ConnectionName] = r.CreatedAt.In(time.Local)
+	}
+
+	var missed []string
+	grace := cfg.Jitter + cfg.CatchUpThreshold
+	for _, conn := range connections {
+		last, ok := lastRun[conn.Name]
+		if !ok {
+			continue
+		}
+		if due := schedule.Next(last); now.Sub(due) > grace {
+			logger.Debug("Missed scheduled run",
+				zap.String("connection", conn.Name),
+				zap.Time("last_result", last),
+				zap.Time("due", due),
+			)
+			missed = append(missed, conn.Name)
+		}
+	}
+
+	return missed
+}
@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// Connection health states.
+const (
+	HealthUp       = "up"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthState returns the health a result indicates: down if the test
+// failed, degraded if some of its phases failed, and up otherwise, along
+// with the reason for states other than up.
+func HealthState(result *speedtest.Result) (state, reason string) {
+	if result.IsError() {
+		return HealthDown, result.Error
+	}
+
+	var failed []string
+	if !result.LatencyOK {
+		failed = append(failed, speedtest.PhaseLatency)
+	}
+	if !result.DownloadOK {
+		failed = append(failed, speedtest.PhaseDownload)
+	}
+	if !result.UploadOK {
+		failed = append(failed, speedtest.PhaseUpload)
+	}
+	if len(failed) > 0 {
+		return HealthDegraded, strings.Join(failed, ", ") + " failed"
+	}
+	return HealthUp, ""
+}
+
+// HealthTracker keeps the health state of each connection and records every
+// change as a connection event, so outages can be reviewed afterwards.
+type HealthTracker struct {
+	store  storage.Storage
+	logger *zap.Logger
+
+	mu sync.Mutex
+	// states are kept per connection and address family, like the alert
+	// states; an entry is loaded from the last stored event on first use
+	states map[string]string
+}
+
+// NewHealthTracker creates a health tracker that stores its events in store.
+func NewHealthTracker(store storage.Storage, logger *zap.Logger) *HealthTracker {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &HealthTracker{
+		store:  store,
+		logger: logger,
+		states: make(map[string]string),
+	}
+}
+
+// Observe updates the connection's health with a result and saves an event
+// if it changed. Aggregate results are ignored.
+func (t *HealthTracker) Observe(ctx context.Context, result *speedtest.Result) {
+	if result.IsAggregate() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := result.ConnectionName + "/" + result.IPFamily
+	previous, ok := t.states[key]
+	if !ok {
+		previous = t.lastState(ctx, result.ConnectionName, result.IPFamily)
+	}
+
+	state, reason := HealthState(result)
+	if state == previous {
+		t.states[key] = state
+		return
+	}
+
+	event := &storage.ConnectionEvent{
+		ConnectionName: result.ConnectionName,
+		IPFamily:       result.IPFamily,
+		FromState:      previous,
+		ToState:        state,
+		Reason:         reason,
+		CreatedAt:      result.Timestamp,
+	}
+	if err := t.store.SaveConnectionEvent(ctx, event); err != nil {
+		// Not remembered, so the change is saved with the next result
+		t.logger.Error("Failed to save connection event",
+			zap.String("connection", result.ConnectionName),
+			zap.Error(err),
+		)
+		return
+	}
+	t.states[key] = state
+
+	t.logger.Info("Connection health changed",
+		zap.String("connection", speedtest.ConnectionLabel(result.ConnectionName, result.IPFamily)),
+		zap.String("from", previous),
+		zap.String("to", state),
+		zap.String("reason", reason),
+	)
+}
+
+// lastState returns the state of the connection's last stored event for the
+// address family, or "" if there is none.
+func (t *HealthTracker) lastState(ctx context.Context, connectionName, ipFamily string) string {
+	events, err := t.store.GetConnectionEvents(ctx, connectionName, time.Time{}, 0)
+	if err != nil {
+		t.logger.Warn("Failed to get last connection event",
+			zap.String("connection", connectionName),
+			zap.Error(err),
+		)
+		return ""
+	}
+	for _, e := range events {
+		if e.IPFamily == ipFamily {
+			return e.ToState
+		}
+	}
+	return ""
+}
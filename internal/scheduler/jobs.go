@@ -2,20 +2,48 @@ package scheduler
 
 import (
 	"context"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/lan-dot-party/flowgauge/internal/api"
+	"github.com/lan-dot-party/flowgauge/internal/config"
 	"github.com/lan-dot-party/flowgauge/internal/speedtest"
 	"github.com/lan-dot-party/flowgauge/internal/storage"
 )
 
+// storagePingTimeout bounds the storage health check before a scheduled run.
+const storagePingTimeout = 10 * time.Second
+
 // SpeedtestJob runs speedtests on a schedule.
 type SpeedtestJob struct {
 	runner  *speedtest.MultiWANRunner
 	storage storage.Storage
 	logger  *zap.Logger
+	onSaved func()
+
+	// skipWindows are daily time ranges in which scheduled runs are skipped
+	skipWindows []config.TimeWindow
+	location    *time.Location
+
+	// jitter is the maximum random delay before a scheduled run starts
+	jitter time.Duration
+	// stop aborts a pending jittered run when the scheduler stops
+	stop <-chan struct{}
+	// roundDecimals is the precision results are stored with
+	roundDecimals int
+	// atomicSaves saves the results of a run in one transaction
+	atomicSaves bool
+	// storageDown is set while runs are skipped because storage is unreachable
+	storageDown atomic.Bool
+	// alerts checks each result for sustained degradation (optional)
+	alerts *AlertDetector
+	// health records changes of the connections' health (optional)
+	health *HealthTracker
+	// readOnly returns true while runs are paused for maintenance (optional)
+	readOnly func() bool
 }
 
 // NewSpeedtestJob creates a new speedtest job.
@@ -28,11 +56,34 @@ func NewSpeedtestJob(runner *speedtest.MultiWANRunner, store storage.Storage, lo
 		runner:  runner,
 		storage: store,
 		logger:  logger,
+
+		roundDecimals: config.DefaultRoundDecimals,
 	}
 }
 
 // Run executes the speedtest job (implements cron.Job interface).
+// The start is delayed by a random jitter if configured, and runs that
+// start within a maintenance window are skipped.
 func (j *SpeedtestJob) Run() {
+	if !j.waitJitter() {
+		return
+	}
+
+	if j.paused() {
+		return
+	}
+
+	if window, ok := j.activeSkipWindow(time.Now()); ok {
+		j.logger.Info("In maintenance window, skipping scheduled speedtest",
+			zap.String("window", window.String()),
+		)
+		return
+	}
+
+	if !j.storageAvailable() {
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
@@ -41,6 +92,72 @@ func (j *SpeedtestJob) Run() {
 	}
 }
 
+// waitJitter sleeps for a random duration up to the configured jitter.
+// Returns false if the scheduler was stopped while waiting.
+func (j *SpeedtestJob) waitJitter() bool {
+	if j.jitter <= 0 {
+		return true
+	}
+
+	delay := rand.N(j.jitter)
+	j.logger.Info("Delaying scheduled speedtest",
+		zap.Duration("delay", delay),
+		zap.Time("start_at", time.Now().Add(delay)),
+	)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-j.stop:
+		j.logger.Info("Scheduler stopped, cancelling delayed speedtest")
+		return false
+	}
+}
+
+// paused returns true if runs are suppressed by read-only mode.
+func (j *SpeedtestJob) paused() bool {
+	if j.readOnly == nil || !j.readOnly() {
+		return false
+	}
+	j.logger.Info("Read-only mode enabled, skipping scheduled speedtest")
+	return true
+}
+
+// storageAvailable checks that results can be saved before testing, so no
+// bandwidth is spent on tests whose results would be lost. Runs resume
+// automatically once storage is reachable again.
+func (j *SpeedtestJob) storageAvailable() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), storagePingTimeout)
+	defer cancel()
+
+	if err := j.storage.Ping(ctx); err != nil {
+		j.logger.Warn("Storage unavailable, skipping scheduled speedtest", zap.Error(err))
+		j.storageDown.Store(true)
+		return false
+	}
+
+	if j.storageDown.Swap(false) {
+		j.logger.Info("Storage available again, resuming scheduled speedtests")
+	}
+	return true
+}
+
+// activeSkipWindow returns the skip window containing now, if any.
+func (j *SpeedtestJob) activeSkipWindow(now time.Time) (config.TimeWindow, bool) {
+	if j.location != nil {
+		now = now.In(j.location)
+	}
+	for _, window := range j.skipWindows {
+		if window.Contains(now) {
+			return window, true
+		}
+	}
+	return config.TimeWindow{}, false
+}
+
 // RunWithContext executes the speedtest job with a context.
 func (j *SpeedtestJob) RunWithContext(ctx context.Context) error {
 	startTime := time.Now()
@@ -60,13 +177,28 @@ func (j *SpeedtestJob) RunWithContext(ctx context.Context) error {
 
 	// Save results to storage and update Prometheus metrics
 	var savedCount, errorCount int
+	var batch []*storage.TestResult
 	for _, result := range results {
+		result.Source = speedtest.SourceScheduled
+		speedtest.LogCompleted(j.logger, &result)
+
 		// Update Prometheus metrics
 		api.UpdateMetricsForResult(&result)
+
+		if j.alerts != nil {
+			j.alerts.Observe(ctx, &result)
+		}
+		if j.health != nil {
+			j.health.Observe(ctx, &result)
+		}
 		
 		// Save to database
-		dbResult := storage.FromSpeedtestResult(&result)
-		
+		dbResult := storage.FromSpeedtestResult(&result, j.roundDecimals)
+		if j.atomicSaves {
+			batch = append(batch, dbResult)
+			continue
+		}
+
 		if err := j.storage.SaveResult(ctx, dbResult); err != nil {
 			j.logger.Error("Failed to save speedtest result",
 				zap.String("connection", result.ConnectionName),
@@ -77,22 +209,26 @@ func (j *SpeedtestJob) RunWithContext(ctx context.Context) error {
 		}
 
 		savedCount++
+	}
 
-		if result.IsError() {
-			j.logger.Warn("Speedtest completed with error",
-				zap.String("connection", result.ConnectionName),
-				zap.String("error", result.Error),
+	// All results of the run or none, so an interrupted save doesn't look
+	// like some connections weren't tested
+	if len(batch) > 0 {
+		if err := j.storage.SaveResultsAtomic(ctx, batch); err != nil {
+			j.logger.Error("Failed to save speedtest results",
+				zap.Int("results", len(batch)),
+				zap.Error(err),
 			)
+			errorCount += len(batch)
 		} else {
-			j.logger.Info("Speedtest result saved",
-				zap.String("connection", result.ConnectionName),
-				zap.Float64("download_mbps", result.DownloadMbps),
-				zap.Float64("upload_mbps", result.UploadMbps),
-				zap.Float64("latency_ms", result.LatencyMs),
-			)
+			savedCount += len(batch)
 		}
 	}
 
+	if savedCount > 0 && j.onSaved != nil {
+		j.onSaved()
+	}
+
 	duration := time.Since(startTime)
 	j.logger.Info("Scheduled speedtest completed",
 		zap.Int("total", len(results)),
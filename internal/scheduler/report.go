@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/report"
+	"github.com/lan-dot-party/flowgauge/internal/speedtest"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// reportTimeout bounds a report run, including its delivery.
+const reportTimeout = 5 * time.Minute
+
+// ReportJob generates a summary report of the connections and delivers it
+// to the reports directory and/or webhook.
+type ReportJob struct {
+	storage storage.Storage
+	logger  *zap.Logger
+	client  *http.Client
+
+	cfg config.ReportsConfig
+	// period is the parsed cfg.Period
+	period time.Duration
+	// connections are the names of the connections in the report
+	connections []string
+	// location is the timezone of the report's dates
+	location *time.Location
+	// unit is the unit speeds are shown in
+	unit speedtest.SpeedUnit
+}
+
+// reportPayload is the JSON body posted to the reports webhook.
+type reportPayload struct {
+	Title   string         `json:"title"`
+	Format  string         `json:"format"`
+	Since   time.Time      `json:"since"`
+	Until   time.Time      `json:"until"`
+	Content string         `json:"content"`
+	Report  *report.Report `json:"report"`
+}
+
+// NewReportJob creates a report job. The period has been validated with the
+// config, so parse errors are ignored.
+func NewReportJob(cfg config.ReportsConfig, connections []string, store storage.Storage, logger *zap.Logger) *ReportJob {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	job := &ReportJob{
+		storage:     store,
+		logger:      logger,
+		client:      &http.Client{Timeout: webhookTimeout},
+		cfg:         cfg,
+		connections: connections,
+		location:    time.Local,
+		unit:        speedtest.SpeedUnitMbps,
+	}
+	job.period, _ = cfg.PeriodDuration()
+	return job
+}
+
+// Run executes the report job (implements cron.Job interface).
+func (j *ReportJob) Run() {
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	if err := j.RunWithContext(ctx, time.Now()); err != nil {
+		j.logger.Error("Report failed", zap.Error(err))
+	}
+}
+
+// RunWithContext generates the report of the period ending at now and
+// delivers it. Both targets are attempted even if one fails.
+func (j *ReportJob) RunWithContext(ctx context.Context, now time.Time) error {
+	rep, err := report.Generate(ctx, j.storage, j.connections, j.period, now, j.location)
+	if err != nil {
+		return err
+	}
+	content, err := rep.Render(j.cfg.Format, j.unit)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if j.cfg.Directory != "" {
+		path, err := j.writeFile(rep, content)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			j.logger.Info("Report written", zap.String("path", path))
+		}
+	}
+	if j.cfg.WebhookURL != "" {
+		if err := j.sendWebhook(ctx, rep, content); err != nil {
+			errs = append(errs, err)
+		} else {
+			j.logger.Info("Report sent to webhook")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeFile writes the report to the reports directory, named after the
+// last day of its period, and returns its path.
+func (j *ReportJob) writeFile(rep *report.Report, content string) (string, error) {
+	if err := os.MkdirAll(j.cfg.Directory, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	ext := ".md"
+	if j.cfg.Format == report.FormatHTML {
+		ext = ".html"
+	}
+	path := filepath.Join(j.cfg.Directory, "flowgauge-report-"+rep.Until.Format("2006-01-02")+ext)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// sendWebhook posts the report to the reports webhook.
+func (j *ReportJob) sendWebhook(ctx context.Context, rep *report.Report, content string) error {
+	body, err := json.Marshal(reportPayload{
+		Title:   rep.Title(),
+		Format:  j.cfg.Format,
+		Since:   rep.Since,
+		Until:   rep.Until,
+		Content: content,
+		Report:  rep,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", withoutURL(err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
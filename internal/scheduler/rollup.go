@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// rollupTimeout bounds a rollup run, which may aggregate many results on
+// its first run against an existing database.
+const rollupTimeout = 30 * time.Minute
+
+// RollupJob aggregates old results into hourly rollups and deletes expired
+// rollups (see storage.rollup).
+type RollupJob struct {
+	storage storage.Storage
+	logger  *zap.Logger
+	onSaved func()
+
+	// rawRetention is how long results are kept before they are rolled up
+	rawRetention time.Duration
+	// retention is how long rollups are kept (0 = forever)
+	retention time.Duration
+	// readOnly returns true while runs are paused for maintenance (optional)
+	readOnly func() bool
+}
+
+// NewRollupJob creates a rollup job. The retention periods have been
+// validated with the config, so parse errors are ignored.
+func NewRollupJob(cfg config.RollupConfig, store storage.Storage, logger *zap.Logger) *RollupJob {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	job := &RollupJob{
+		storage: store,
+		logger:  logger,
+	}
+	job.rawRetention, _ = cfg.RawRetentionDuration()
+	job.retention, _ = cfg.RetentionDuration()
+	return job
+}
+
+// Run executes the rollup job (implements cron.Job interface). Runs are
+// skipped in read-only mode, as they delete results.
+func (j *RollupJob) Run() {
+	if j.readOnly != nil && j.readOnly() {
+		j.logger.Info("Read-only mode enabled, skipping rollup")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollupTimeout)
+	defer cancel()
+
+	if err := j.RunWithContext(ctx, time.Now()); err != nil {
+		j.logger.Error("Rollup failed", zap.Error(err))
+	}
+}
+
+// RunWithContext rolls up the results older than the raw retention and
+// deletes the rollups older than the retention, relative to now.
+func (j *RollupJob) RunWithContext(ctx context.Context, now time.Time) error {
+	rolledUp, err := j.storage.RollupResults(ctx, now.Add(-j.rawRetention))
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	if j.retention > 0 {
+		deleted, err = j.storage.DeleteOldRollups(ctx, now.Add(-j.retention))
+		if err != nil {
+			return err
+		}
+	}
+
+	if rolledUp == 0 && deleted == 0 {
+		j.logger.Debug("Rollup found nothing to do")
+		return nil
+	}
+	j.logger.Info("Rolled up old results",
+		zap.Int64("results", rolledUp),
+		zap.Int64("deleted_rollups", deleted),
+	)
+	if j.onSaved != nil {
+		j.onSaved()
+	}
+	return nil
+}
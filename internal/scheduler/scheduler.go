@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
@@ -24,6 +25,33 @@ type Scheduler struct {
 	running  bool
 	mu       sync.Mutex
 	jobID    cron.EntryID
+
+	// onResultSaved is called after results have been saved (optional)
+	onResultSaved func()
+	// location is the timezone in which skip windows are evaluated
+	location *time.Location
+	// stopCh is closed on Stop to cancel jittered runs that are still waiting
+	stopCh chan struct{}
+	// roundDecimals is the precision results are stored with
+	roundDecimals int
+	// alerts detects sustained degradation of connections (nil = disabled)
+	alerts *AlertDetector
+	// health tracks the health state of connections
+	health *HealthTracker
+	// readOnly pauses runs while it returns true (optional)
+	readOnly func() bool
+	// rollup are the settings of the rollup job, registered by Start
+	rollup config.RollupConfig
+	// thinning are the settings of the thinning job, registered by Start
+	thinning config.ThinningConfig
+	// reports are the settings of the report job, registered by Start
+	reports config.ReportsConfig
+	// reportJobID is the cron entry of the report job (0 = not registered)
+	reportJobID cron.EntryID
+	// reportConnections are the connections in the reports
+	reportConnections []string
+	// speedUnit is the unit speeds are shown in by the reports
+	speedUnit speedtest.SpeedUnit
 }
 
 // NewScheduler creates a new scheduler instance.
@@ -56,11 +84,177 @@ func NewScheduler(cfg *config.SchedulerConfig, runner *speedtest.MultiWANRunner,
 		cron:    c,
 		config:  cfg,
 		runner:  runner,
-		storage: store,
-		logger:  logger,
+		storage:  store,
+		logger:   logger,
+		location: time.Local,
+		stopCh:   make(chan struct{}),
+		health:   NewHealthTracker(store, logger),
+
+		roundDecimals: config.DefaultRoundDecimals,
 	}, nil
 }
 
+// SetOnResultSaved registers a callback invoked after a job has saved results.
+// Used by the web server to invalidate cached dashboard data.
+func (s *Scheduler) SetOnResultSaved(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onResultSaved = fn
+}
+
+// SetReadOnly registers a check that suppresses runs while it returns true.
+// Used by the web server's read-only mode, which can change at any time, so
+// it is evaluated at the start of each run.
+func (s *Scheduler) SetReadOnly(fn func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly = fn
+}
+
+// SetLocation sets the timezone in which skip windows are evaluated.
+// Takes effect for jobs registered afterwards (Start or Reload).
+func (s *Scheduler) SetLocation(loc *time.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.location = loc
+}
+
+// SetRoundDecimals sets the number of decimals results are stored with.
+// Takes effect for jobs registered afterwards (Start or Reload).
+func (s *Scheduler) SetRoundDecimals(decimals int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roundDecimals = decimals
+}
+
+// SetAlerts applies the alert settings. The detector's state is kept across
+// calls, so alerts survive configuration reloads. Baselines are computed in
+// the timezone set with SetLocation.
+func (s *Scheduler) SetAlerts(cfg config.AlertsConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.alerts == nil {
+		s.alerts = NewAlertDetector(cfg, s.logger)
+	} else {
+		s.alerts.SetConfig(cfg)
+	}
+	s.alerts.setBaselineSource(s.storage, s.location)
+}
+
+// SetRollup sets the rollup settings (storage.rollup). Storage settings
+// require a restart, so it takes effect with Start only.
+func (s *Scheduler) SetRollup(cfg config.RollupConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollup = cfg
+}
+
+// SetThinning sets the thinning settings (storage.thinning). Storage
+// settings require a restart, so it takes effect with Start only.
+func (s *Scheduler) SetThinning(cfg config.ThinningConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.thinning = cfg
+}
+
+// SetReports sets the report settings (reports), the connections in the
+// reports and the unit their speeds are shown in. If the scheduler is
+// running, the report job is replaced, so reloads take effect immediately.
+func (s *Scheduler) SetReports(cfg config.ReportsConfig, connections []string, unit speedtest.SpeedUnit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = cfg
+	s.reportConnections = connections
+	s.speedUnit = unit
+
+	if !s.running {
+		return
+	}
+	if s.reportJobID != 0 {
+		s.cron.Remove(s.reportJobID)
+		s.reportJobID = 0
+	}
+	if cfg.Enabled {
+		if err := s.addReportJob(); err != nil {
+			s.logger.Error("Failed to update report job", zap.Error(err))
+		}
+	}
+}
+
+// newJob creates a speedtest job wired with the scheduler's hooks.
+// Skip windows have been validated with the config, so parse errors are ignored.
+func (s *Scheduler) newJob() *SpeedtestJob {
+	job := NewSpeedtestJob(s.runner, s.storage, s.logger)
+	job.onSaved = s.onResultSaved
+	job.skipWindows, _ = s.config.ParseSkipWindows()
+	job.location = s.location
+	job.jitter = s.config.Jitter
+	job.stop = s.stopCh
+	job.roundDecimals = s.roundDecimals
+	job.atomicSaves = s.config.AtomicSaves
+	job.alerts = s.alerts
+	job.health = s.health
+	job.readOnly = s.readOnly
+	return job
+}
+
+// addRollupJob registers the rollup job. Must be called with s.mu held.
+func (s *Scheduler) addRollupJob() error {
+	job := NewRollupJob(s.rollup, s.storage, s.logger)
+	job.onSaved = s.onResultSaved
+	job.readOnly = s.readOnly
+
+	if _, err := s.cron.AddJob(s.rollup.Schedule, job); err != nil {
+		return fmt.Errorf("failed to add rollup job: %w (schedule: %s)", err, s.rollup.Schedule)
+	}
+
+	s.logger.Info("Rollup enabled",
+		zap.String("schedule", s.rollup.Schedule),
+		zap.String("raw_retention", s.rollup.RawRetention),
+		zap.String("retention", s.rollup.Retention),
+	)
+	return nil
+}
+
+// addThinningJob registers the thinning job. Must be called with s.mu held.
+func (s *Scheduler) addThinningJob() error {
+	job := NewThinningJob(s.thinning, s.storage, s.logger)
+	job.onSaved = s.onResultSaved
+	job.readOnly = s.readOnly
+
+	if _, err := s.cron.AddJob(s.thinning.Schedule, job); err != nil {
+		return fmt.Errorf("failed to add thinning job: %w (schedule: %s)", err, s.thinning.Schedule)
+	}
+
+	s.logger.Info("Thinning enabled",
+		zap.String("schedule", s.thinning.Schedule),
+		zap.Int("tiers", len(s.thinning.Tiers)),
+	)
+	return nil
+}
+
+// addReportJob registers the report job. Must be called with s.mu held.
+func (s *Scheduler) addReportJob() error {
+	job := NewReportJob(s.reports, s.reportConnections, s.storage, s.logger)
+	job.location = s.location
+	if s.speedUnit != "" {
+		job.unit = s.speedUnit
+	}
+
+	entryID, err := s.cron.AddJob(s.reports.Schedule, job)
+	if err != nil {
+		return fmt.Errorf("failed to add report job: %w (schedule: %s)", err, s.reports.Schedule)
+	}
+	s.reportJobID = entryID
+
+	s.logger.Info("Reports enabled",
+		zap.String("schedule", s.reports.Schedule),
+		zap.String("period", s.reports.Period),
+		zap.String("format", s.reports.Format),
+	)
+	return nil
+}
+
 // Start begins the scheduler.
 func (s *Scheduler) Start() error {
 	s.mu.Lock()
@@ -76,7 +270,7 @@ func (s *Scheduler) Start() error {
 	}
 
 	// Create the speedtest job
-	job := NewSpeedtestJob(s.runner, s.storage, s.logger)
+	job := s.newJob()
 
 	// Add the job to cron
 	entryID, err := s.cron.AddFunc(s.config.Schedule, job.Run)
@@ -85,12 +279,29 @@ func (s *Scheduler) Start() error {
 	}
 	s.jobID = entryID
 
+	if s.rollup.Enabled {
+		if err := s.addRollupJob(); err != nil {
+			return err
+		}
+	}
+	if s.thinning.Enabled {
+		if err := s.addThinningJob(); err != nil {
+			return err
+		}
+	}
+	if s.reports.Enabled {
+		if err := s.addReportJob(); err != nil {
+			return err
+		}
+	}
+
 	// Start the cron scheduler
 	s.cron.Start()
 	s.running = true
 
 	s.logger.Info("Scheduler started",
 		zap.String("schedule", s.config.Schedule),
+		zap.Duration("jitter", s.config.Jitter),
 		zap.Int("entry_id", int(entryID)),
 	)
 
@@ -103,6 +314,74 @@ func (s *Scheduler) Start() error {
 	return nil
 }
 
+// ValidateConfig returns an error if cfg would be rejected by Reload, so
+// callers can check a new configuration before applying other settings.
+func ValidateConfig(cfg *config.SchedulerConfig) error {
+	if cfg.Enabled {
+		if _, err := cfg.ParseSchedule(); err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", cfg.Schedule, err)
+		}
+	}
+	if cfg.Jitter < 0 {
+		return fmt.Errorf("invalid jitter %s: must not be negative", cfg.Jitter)
+	}
+	if _, err := cfg.ParseSkipWindows(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Reload applies a new scheduler configuration and runner, re-registering
+// the cron job. The previous configuration is kept if the new schedule is invalid.
+func (s *Scheduler) Reload(cfg *config.SchedulerConfig, runner *speedtest.MultiWANRunner) error {
+	if cfg == nil {
+		return fmt.Errorf("scheduler config is required")
+	}
+	if runner == nil {
+		return fmt.Errorf("speedtest runner is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Validate the new schedule before touching the current job
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	if s.jobID != 0 {
+		s.cron.Remove(s.jobID)
+		s.jobID = 0
+	}
+
+	s.config = cfg
+	s.runner = runner
+
+	if !cfg.Enabled {
+		s.logger.Info("Scheduler disabled by configuration reload")
+		return nil
+	}
+
+	entryID, err := s.cron.AddFunc(cfg.Schedule, s.newJob().Run)
+	if err != nil {
+		return fmt.Errorf("failed to add cron job: %w (schedule: %s)", err, cfg.Schedule)
+	}
+	s.jobID = entryID
+
+	if !s.running {
+		s.cron.Start()
+		s.running = true
+	}
+
+	s.logger.Info("Scheduler reloaded",
+		zap.String("schedule", cfg.Schedule),
+		zap.Duration("jitter", cfg.Jitter),
+		zap.Time("next_run", s.cron.Entry(entryID).Next),
+	)
+
+	return nil
+}
+
 // Stop gracefully stops the scheduler.
 func (s *Scheduler) Stop() {
 	s.mu.Lock()
@@ -112,6 +391,10 @@ func (s *Scheduler) Stop() {
 		return
 	}
 
+	// Cancel runs still waiting for their jitter delay, so Stop doesn't block on them
+	close(s.stopCh)
+	s.stopCh = make(chan struct{})
+
 	ctx := s.cron.Stop()
 	<-ctx.Done()
 	s.running = false
@@ -198,7 +481,9 @@ func (l *cronLogger) Printf(format string, args ...interface{}) {
 
 // RunOnce runs the speedtest job once immediately (useful for testing).
 func (s *Scheduler) RunOnce(ctx context.Context) error {
-	job := NewSpeedtestJob(s.runner, s.storage, s.logger)
+	s.mu.Lock()
+	job := s.newJob()
+	s.mu.Unlock()
 	return job.RunWithContext(ctx)
 }
 
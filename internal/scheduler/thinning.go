@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/storage"
+)
+
+// thinningTimeout bounds a thinning run, which may thin many results on its
+// first run against an existing database.
+const thinningTimeout = 30 * time.Minute
+
+// thinningTier is a parsed config.ThinningTier.
+type thinningTier struct {
+	after     time.Duration
+	keepEvery int
+}
+
+// ThinningJob progressively thins out older results (see storage.thinning).
+type ThinningJob struct {
+	storage storage.Storage
+	logger  *zap.Logger
+	onSaved func()
+
+	// tiers are ordered by increasing age; the level of a tier is its
+	// position, starting at 1
+	tiers []thinningTier
+	// readOnly returns true while runs are paused for maintenance (optional)
+	readOnly func() bool
+}
+
+// NewThinningJob creates a thinning job. The tiers have been validated with
+// the config, so parse errors are ignored.
+func NewThinningJob(cfg config.ThinningConfig, store storage.Storage, logger *zap.Logger) *ThinningJob {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	job := &ThinningJob{
+		storage: store,
+		logger:  logger,
+	}
+	for _, t := range cfg.Tiers {
+		after, _ := t.AfterDuration()
+		job.tiers = append(job.tiers, thinningTier{after: after, keepEvery: t.KeepEvery})
+	}
+	return job
+}
+
+// Run executes the thinning job (implements cron.Job interface). Runs are
+// skipped in read-only mode, as they delete results.
+func (j *ThinningJob) Run() {
+	if j.readOnly != nil && j.readOnly() {
+		j.logger.Info("Read-only mode enabled, skipping thinning")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), thinningTimeout)
+	defer cancel()
+
+	if err := j.RunWithContext(ctx, time.Now()); err != nil {
+		j.logger.Error("Thinning failed", zap.Error(err))
+	}
+}
+
+// RunWithContext applies the tiers to the results older than their age,
+// relative to now. The tiers are applied in order, so results that reached
+// several tiers since the last run are thinned by each of them.
+func (j *ThinningJob) RunWithContext(ctx context.Context, now time.Time) error {
+	var total int64
+	for i, t := range j.tiers {
+		deleted, err := j.storage.ThinResults(ctx, now.Add(-t.after), t.keepEvery, i+1)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			j.logger.Debug("Thinned results",
+				zap.Int("tier", i+1),
+				zap.Duration("after", t.after),
+				zap.Int("keep_every", t.keepEvery),
+				zap.Int64("deleted", deleted),
+			)
+		}
+		total += deleted
+	}
+
+	if total == 0 {
+		j.logger.Debug("Thinning found nothing to do")
+		return nil
+	}
+	j.logger.Info("Thinned out old results", zap.Int64("deleted", total))
+	if j.onSaved != nil {
+		j.onSaved()
+	}
+	return nil
+}
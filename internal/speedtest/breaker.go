@@ -0,0 +1,99 @@
+package speedtest
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serverBreaker is a simple in-process circuit breaker keyed by server host.
+// After threshold consecutive failures against a host, the host is excluded
+// from server selection until the cooldown has elapsed.
+type serverBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  map[string]int
+	openUntil map[string]time.Time
+	logger    *zap.Logger
+}
+
+// newServerBreaker creates a new breaker. A threshold <= 0 disables it.
+func newServerBreaker(threshold int, cooldown time.Duration, logger *zap.Logger) *serverBreaker {
+	return &serverBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  make(map[string]int),
+		openUntil: make(map[string]time.Time),
+		logger:    logger,
+	}
+}
+
+// isOpen reports whether the breaker for host is open (host should be skipped).
+func (b *serverBreaker) isOpen(host string) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.openUntil[host]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		// Cooldown elapsed: allow a new attempt (half-open)
+		delete(b.openUntil, host)
+		b.logger.Info("Circuit breaker cooldown elapsed, retrying server",
+			zap.String("host", host),
+		)
+		return false
+	}
+	return true
+}
+
+// recordSuccess resets the failure count for host.
+func (b *serverBreaker) recordSuccess(host string) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures[host] > 0 {
+		b.logger.Debug("Circuit breaker reset",
+			zap.String("host", host),
+			zap.Int("previous_failures", b.failures[host]),
+		)
+	}
+	delete(b.failures, host)
+}
+
+// recordFailure increments the failure count for host and opens the breaker
+// once the threshold is reached.
+func (b *serverBreaker) recordFailure(host string) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[host]++
+	if b.failures[host] < b.threshold {
+		return
+	}
+
+	until := time.Now().Add(b.cooldown)
+	b.openUntil[host] = until
+	b.failures[host] = 0
+
+	b.logger.Warn("Circuit breaker opened, excluding server from selection",
+		zap.String("host", host),
+		zap.Int("threshold", b.threshold),
+		zap.Time("until", until),
+	)
+}
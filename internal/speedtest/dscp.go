@@ -5,6 +5,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -15,8 +19,50 @@ type DSCPDialer struct {
 	DSCP int
 	// SourceIP is the local IP address to bind to (optional)
 	SourceIP string
+	// Interface is the network interface whose address is bound to if SourceIP is empty (optional)
+	Interface string
+	// Family restricts connections to FamilyIPv4 or FamilyIPv6 (optional)
+	Family string
 	// Logger for debug output
 	Logger *zap.Logger
+
+	// applied and failed record whether controlFunc could mark the sockets
+	applied atomic.Bool
+	failed  atomic.Bool
+	// firstConnect is the duration of the first connect in nanoseconds, set
+	// once by DialContext
+	firstConnect atomic.Int64
+	// parent is the dialer this one was created from by connectTimer; its
+	// control function marks the sockets, so Applied covers both
+	parent *DSCPDialer
+}
+
+// Applied returns true if DSCP marking was applied to every socket created
+// so far (and at least one). Always false for DSCP 0.
+func (d *DSCPDialer) Applied() bool {
+	return d.DSCP > 0 && d.applied.Load() && !d.failed.Load()
+}
+
+// FirstConnectTime returns how long establishing the first connection of
+// DialContext took, from the start of the TCP connect to its completion.
+// Name resolution is not included. Zero if no connection was established.
+func (d *DSCPDialer) FirstConnectTime() time.Duration {
+	return time.Duration(d.firstConnect.Load())
+}
+
+// connectTimer returns a dialer with the settings of d whose
+// FirstConnectTime is the first connect made through it, so a test can
+// time its own first connection to a server. Its sockets are reported by
+// d.Applied.
+func (d *DSCPDialer) connectTimer() *DSCPDialer {
+	return &DSCPDialer{
+		DSCP:      d.DSCP,
+		SourceIP:  d.SourceIP,
+		Interface: d.Interface,
+		Family:    d.Family,
+		Logger:    d.Logger,
+		parent:    d,
+	}
 }
 
 // Dial creates a new connection to the address on the named network.
@@ -29,10 +75,15 @@ func (d *DSCPDialer) DialContext(ctx context.Context, network, address string) (
 	// Create base dialer with optional source IP
 	dialer := &net.Dialer{}
 
-	if d.SourceIP != "" {
-		ip := net.ParseIP(d.SourceIP)
+	sourceIP, err := d.LocalIP()
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceIP != "" {
+		ip := net.ParseIP(sourceIP)
 		if ip == nil {
-			return nil, fmt.Errorf("invalid source IP: %s", d.SourceIP)
+			return nil, fmt.Errorf("invalid source IP: %s", sourceIP)
 		}
 
 		// Determine if we need TCP or UDP local address
@@ -46,23 +97,125 @@ func (d *DSCPDialer) DialContext(ctx context.Context, network, address string) (
 		}
 
 		if d.Logger != nil {
-			d.Logger.Debug("Binding to source IP", zap.String("ip", d.SourceIP))
+			d.Logger.Debug("Binding to source IP",
+				zap.String("ip", sourceIP),
+				zap.String("interface", d.Interface),
+			)
 		}
 	}
 
-	// Set up control function to apply DSCP before connection
-	if d.DSCP > 0 {
-		dialer.Control = d.controlFunc
+	// Set up control function to apply DSCP and the address family before
+	// connection. It runs right before the connect, so it also starts the
+	// connect timer of the address that is dialed.
+	control := d.Control()
+	if d.parent != nil {
+		control = d.parent.Control()
+	}
+	var connectStart atomic.Int64
+	dialer.Control = func(network, address string, c syscall.RawConn) error {
+		connectStart.Store(time.Now().UnixNano())
+		if control != nil {
+			return control(network, address, c)
+		}
+		return nil
 	}
 
 	conn, err := dialer.DialContext(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
+	// Only the first connect is kept, later (e.g. concurrent) ones don't
+	// overwrite it
+	d.firstConnect.CompareAndSwap(0, time.Now().UnixNano()-connectStart.Load())
 
 	return conn, nil
 }
 
+// Control returns the socket control function for the dialer's settings:
+// it marks sockets with the DSCP value and rejects addresses outside of
+// Family, so a dual-stack host is reached over the requested family only.
+// Returns nil if neither is configured.
+func (d *DSCPDialer) Control() func(network, address string, c syscall.RawConn) error {
+	if d.DSCP == 0 && d.Family == "" {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		// network is "tcp4", "tcp6", "udp4", ... once the address is resolved
+		if d.Family == FamilyIPv4 && strings.HasSuffix(network, "6") ||
+			d.Family == FamilyIPv6 && strings.HasSuffix(network, "4") {
+			return fmt.Errorf("%s is not an %s address", address, d.Family)
+		}
+		if d.DSCP > 0 {
+			return d.controlFunc(network, address, c)
+		}
+		return nil
+	}
+}
+
+// LocalIP returns the local address to bind to: SourceIP if set, otherwise
+// the current address of Interface (of Family, if set). The interface is
+// resolved on every call, so address changes (e.g. DHCP lease renewals) are
+// picked up. An empty string means the default route is used.
+func (d *DSCPDialer) LocalIP() (string, error) {
+	if d.SourceIP != "" || d.Interface == "" {
+		return d.SourceIP, nil
+	}
+
+	ip, err := interfaceIP(d.Interface, d.Family)
+	if err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+// InterfaceIP returns the primary address of the named network interface,
+// preferring global unicast IPv4 over IPv6 addresses.
+func InterfaceIP(name string) (net.IP, error) {
+	return interfaceIP(name, "")
+}
+
+// interfaceIP returns the primary address of the named network interface
+// in the given family, or of any family (preferring IPv4) if it is empty.
+func interfaceIP(name, family string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s not found: %w", name, err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("interface %s is down", name)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get addresses of interface %s: %w", name, err)
+	}
+
+	var ipv6 net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			if family != FamilyIPv6 {
+				return ip4, nil
+			}
+			continue
+		}
+		if ipv6 == nil {
+			ipv6 = ipNet.IP
+		}
+	}
+
+	if ipv6 == nil || family == FamilyIPv4 {
+		if family != "" {
+			return nil, fmt.Errorf("interface %s has no usable %s address", name, family)
+		}
+		return nil, fmt.Errorf("interface %s has no usable address", name)
+	}
+	return ipv6, nil
+}
+
 // NewDSCPDialer creates a new DSCPDialer with the given settings.
 func NewDSCPDialer(dscp int, sourceIP string, logger *zap.Logger) (*DSCPDialer, error) {
 	if dscp < 0 || dscp > 63 {
@@ -41,12 +41,30 @@ func (d *DSCPDialer) controlFunc(network, address string, c syscall.RawConn) err
 	})
 
 	if err != nil {
+		d.failed.Store(true)
 		return fmt.Errorf("failed to access raw connection: %w", err)
 	}
 	if setsockoptErr != nil {
+		d.failed.Store(true)
 		return fmt.Errorf("failed to set DSCP (TOS=%d): %w", d.DSCP<<2, setsockoptErr)
 	}
 
+	d.applied.Store(true)
+	return nil
+}
+
+// CheckDSCPSupport checks whether sockets can be marked with the given DSCP
+// value, by setting it on a temporary UDP socket.
+func CheckDSCPSupport(dscp int) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TOS, DSCPToTOS(dscp)); err != nil {
+		return fmt.Errorf("failed to set DSCP (TOS=%d): %w", DSCPToTOS(dscp), err)
+	}
 	return nil
 }
 
@@ -4,6 +4,7 @@
 package speedtest
 
 import (
+	"errors"
 	"syscall"
 
 	"go.uber.org/zap"
@@ -17,7 +18,13 @@ func (d *DSCPDialer) controlFunc(network, address string, c syscall.RawConn) err
 			zap.Int("dscp", d.DSCP),
 		)
 	}
+	d.failed.Store(true)
 	return nil
 }
 
+// CheckDSCPSupport always fails on Windows, where DSCP marking is not implemented.
+func CheckDSCPSupport(dscp int) error {
+	return errors.New("DSCP marking is not supported on Windows")
+}
+
 
@@ -0,0 +1,83 @@
+package speedtest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// InfluxMeasurement is the measurement name of results in line protocol.
+const InfluxMeasurement = "flowgauge"
+
+// influxTagEscaper escapes tag keys and values in line protocol.
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxStringEscaper escapes string field values in line protocol.
+var influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// InfluxLine returns the result as a line of the InfluxDB line protocol,
+// with nanosecond precision. The values of failed phases are omitted, like
+// in the Prometheus gauges; failed tests only have the success and error
+// fields.
+func (r *Result) InfluxLine() string {
+	var b strings.Builder
+	b.WriteString(InfluxMeasurement)
+
+	// Sorted by key; empty tag values are not allowed
+	tags := [][2]string{
+		{"connection", r.ConnectionName},
+		{"family", r.IPFamily},
+		{"server", r.ServerName},
+	}
+	if r.ServerID != 0 {
+		tags = append(tags, [2]string{"server_id", strconv.Itoa(r.ServerID)})
+	}
+	for _, tag := range tags {
+		if tag[1] != "" {
+			b.WriteString("," + tag[0] + "=" + influxTagEscaper.Replace(tag[1]))
+		}
+	}
+
+	fields := []string{"success=" + strconv.FormatBool(!r.IsError())}
+	if r.IsError() {
+		fields = append(fields, `error="`+influxStringEscaper.Replace(r.Error)+`"`)
+	} else {
+		if r.LatencyOK {
+			fields = append(fields,
+				"latency_ms="+formatInfluxFloat(r.LatencyMs),
+				"jitter_ms="+formatInfluxFloat(r.JitterMs),
+				"packet_loss_pct="+formatInfluxFloat(r.PacketLossPct),
+			)
+		}
+		if r.DownloadOK {
+			fields = append(fields, "download_mbps="+formatInfluxFloat(r.DownloadMbps))
+		}
+		if r.UploadOK {
+			fields = append(fields, "upload_mbps="+formatInfluxFloat(r.UploadMbps))
+		}
+	}
+	if r.ConnectMs > 0 {
+		fields = append(fields, "connect_ms="+formatInfluxFloat(r.ConnectMs))
+	}
+	fields = append(fields, "duration_seconds="+formatInfluxFloat(r.Duration))
+	if r.Attempts > 0 {
+		fields = append(fields, "attempts="+strconv.Itoa(r.Attempts)+"i")
+	}
+
+	b.WriteString(" " + strings.Join(fields, ","))
+	b.WriteString(" " + strconv.FormatInt(r.Timestamp.UnixNano(), 10))
+	return b.String()
+}
+
+// formatInfluxFloat formats a float field value.
+func formatInfluxFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ToInflux converts all results to InfluxDB line protocol, one line each.
+func (rs Results) ToInflux() string {
+	lines := make([]string, len(rs))
+	for i := range rs {
+		lines[i] = rs[i].InfluxLine()
+	}
+	return strings.Join(lines, "\n")
+}
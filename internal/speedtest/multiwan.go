@@ -3,8 +3,10 @@ package speedtest
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"net"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -13,28 +15,61 @@ import (
 
 // WANConnection represents a network connection configuration for testing.
 type WANConnection struct {
-	Name     string
-	SourceIP string
-	DSCP     int
-	Enabled  bool
+	Name      string
+	SourceIP  string
+	Interface string
+	DSCP      int
+	Enabled   bool
+	// DualStack tests the connection twice per run, over IPv4 and IPv6
+	DualStack bool
+	// Family restricts a test to FamilyIPv4 or FamilyIPv6 (empty = either)
+	Family string
+	// Timeout overrides speedtest.timeout for this connection (0 = global timeout)
+	Timeout time.Duration
+	// ConfigHash is stored with each result (see config.ConnectionHash)
+	ConfigHash string
 }
 
+// Address families of dual-stack tests.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
 // WANConnectionFromConfig converts a config.ConnectionConfig to WANConnection.
 func WANConnectionFromConfig(cfg config.ConnectionConfig) WANConnection {
 	return WANConnection{
-		Name:     cfg.Name,
-		SourceIP: cfg.SourceIP,
-		DSCP:     cfg.DSCP,
-		Enabled:  cfg.Enabled,
+		Name:      cfg.Name,
+		SourceIP:  cfg.SourceIP,
+		Interface: cfg.Interface,
+		DSCP:      cfg.DSCP,
+		Enabled:   cfg.Enabled,
+		DualStack: cfg.DualStack,
+		Timeout:   cfg.Timeout,
 	}
 }
 
+// Test orders for sequential runs.
+const (
+	OrderConfig     = "config"
+	OrderRandom     = "random"
+	OrderRoundRobin = "round_robin"
+)
+
 // MultiWANRunner manages speedtests across multiple WAN connections.
 type MultiWANRunner struct {
 	connections []WANConnection
 	runner      *Runner
 	logger      *zap.Logger
 	parallel    bool
+	// aggregate appends an aggregate result to each parallel run
+	aggregate bool
+
+	// order controls the connection order of sequential runs
+	order string
+	// rotation is the starting connection index for round_robin order
+	rotation int
+	mu       sync.Mutex
 }
 
 // NewMultiWANRunner creates a new MultiWANRunner from configuration.
@@ -51,6 +86,9 @@ func NewMultiWANRunner(connections []config.ConnectionConfig, cfg *config.Speedt
 		}
 
 		wanConn := WANConnectionFromConfig(conn)
+		if cfg != nil {
+			wanConn.ConfigHash = config.ConnectionHash(conn, *cfg)
+		}
 
 		// Validate source IP exists on this system (if specified)
 		if wanConn.SourceIP != "" {
@@ -62,6 +100,33 @@ func NewMultiWANRunner(connections []config.ConnectionConfig, cfg *config.Speedt
 				)
 				// Continue anyway - might be valid later or on different system
 			}
+			if wanConn.Interface != "" {
+				logger.Warn("Both source_ip and interface set, using source_ip",
+					zap.String("connection", wanConn.Name),
+					zap.String("source_ip", wanConn.SourceIP),
+					zap.String("interface", wanConn.Interface),
+				)
+			}
+		} else if wanConn.Interface != "" {
+			// The address is resolved per test, so only warn if it's currently unavailable
+			if _, err := InterfaceIP(wanConn.Interface); err != nil {
+				logger.Warn("Interface not available on system",
+					zap.String("connection", wanConn.Name),
+					zap.String("interface", wanConn.Interface),
+					zap.Error(err),
+				)
+			}
+		}
+
+		// Tests still run without marking, so make the misconfiguration visible
+		if wanConn.DSCP > 0 {
+			if err := CheckDSCPSupport(wanConn.DSCP); err != nil {
+				logger.Warn("DSCP marking not available on this system, tests will run unmarked",
+					zap.String("connection", wanConn.Name),
+					zap.Int("dscp", wanConn.DSCP),
+					zap.Error(err),
+				)
+			}
 		}
 
 		wanConns = append(wanConns, wanConn)
@@ -76,11 +141,25 @@ func NewMultiWANRunner(connections []config.ConnectionConfig, cfg *config.Speedt
 		return nil, fmt.Errorf("failed to create speedtest runner: %w", err)
 	}
 
+	order := OrderConfig
+	if cfg != nil && cfg.Order != "" {
+		order = cfg.Order
+	}
+
+	// Sequential by default to avoid bandwidth competition
+	var parallel, aggregate bool
+	if cfg != nil {
+		parallel = cfg.Parallel
+		aggregate = cfg.Parallel && cfg.Aggregate
+	}
+
 	return &MultiWANRunner{
 		connections: wanConns,
 		runner:      runner,
 		logger:      logger,
-		parallel:    false, // Sequential by default to avoid bandwidth competition
+		parallel:    parallel,
+		aggregate:   aggregate,
+		order:       order,
 	}, nil
 }
 
@@ -98,11 +177,46 @@ func (m *MultiWANRunner) RunAll(ctx context.Context) ([]Result, error) {
 	return m.runSequential(ctx)
 }
 
+// orderedConnections returns the connections in the order for the next
+// sequential run. Round robin advances the starting connection on each call.
+func (m *MultiWANRunner) orderedConnections() []WANConnection {
+	conns := make([]WANConnection, 0, len(m.connections))
+
+	switch m.order {
+	case OrderRandom:
+		conns = append(conns, m.connections...)
+		rand.Shuffle(len(conns), func(i, j int) {
+			conns[i], conns[j] = conns[j], conns[i]
+		})
+	case OrderRoundRobin:
+		m.mu.Lock()
+		start := m.rotation % len(m.connections)
+		m.rotation = start + 1
+		m.mu.Unlock()
+		conns = append(conns, m.connections[start:]...)
+		conns = append(conns, m.connections[:start]...)
+	default:
+		conns = append(conns, m.connections...)
+	}
+
+	return conns
+}
+
 // runSequential executes tests one after another.
 func (m *MultiWANRunner) runSequential(ctx context.Context) ([]Result, error) {
 	results := make([]Result, 0, len(m.connections))
 
-	for _, conn := range m.connections {
+	connections := m.orderedConnections()
+	names := make([]string, len(connections))
+	for i, conn := range connections {
+		names[i] = conn.Name
+	}
+	m.logger.Info("Sequential test order",
+		zap.String("order", m.order),
+		zap.Strings("connections", names),
+	)
+
+	for _, conn := range connections {
 		select {
 		case <-ctx.Done():
 			return results, ctx.Err()
@@ -112,34 +226,70 @@ func (m *MultiWANRunner) runSequential(ctx context.Context) ([]Result, error) {
 		m.logger.Info("Testing connection",
 			zap.String("name", conn.Name),
 			zap.String("source_ip", conn.SourceIP),
+			zap.String("interface", conn.Interface),
 			zap.Int("dscp", conn.DSCP),
+			zap.Bool("dual_stack", conn.DualStack),
 		)
 
-		result, err := m.runner.Run(ctx, conn)
-		if err != nil {
-			m.logger.Error("Speedtest failed",
-				zap.String("connection", conn.Name),
-				zap.Error(err),
-			)
-			// Create error result instead of failing completely
+		results = append(results, m.runConnection(ctx, conn)...)
+	}
+
+	return results, nil
+}
+
+// runConnection runs a full test of the connection, or one test per address
+// family (IPv4 first) for dual-stack connections. The families are tested
+// one after another, so they don't compete for the link's bandwidth.
+// Failed tests are returned as error results.
+func (m *MultiWANRunner) runConnection(ctx context.Context, conn WANConnection) []Result {
+	if !conn.DualStack {
+		return []Result{m.runOne(ctx, conn)}
+	}
+
+	results := make([]Result, 0, 2)
+	for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+		if ctx.Err() != nil {
+			break
+		}
+		c := conn
+		c.Family = family
+		results = append(results, m.runOne(ctx, c))
+	}
+	return results
+}
+
+// runOne runs a full test of the connection, returning an error result if
+// it failed.
+func (m *MultiWANRunner) runOne(ctx context.Context, conn WANConnection) Result {
+	result, err := m.runner.Run(ctx, conn, DefaultRunOptions())
+	if err != nil {
+		m.logger.Error("Speedtest failed",
+			zap.String("connection", conn.Name),
+			zap.String("family", conn.Family),
+			zap.Error(err),
+		)
+		// Keep the partial result (e.g. after a timeout), or create an
+		// error result instead of failing completely
+		if result == nil {
 			result = &Result{
 				ConnectionName: conn.Name,
 				SourceIP:       conn.SourceIP,
 				DSCP:           conn.DSCP,
-				Error:          err.Error(),
+				IPFamily:       conn.Family,
 			}
 		}
-
-		results = append(results, *result)
+		if result.Error == "" {
+			result.Error = err.Error()
+		}
 	}
-
-	return results, nil
+	return *result
 }
 
 // runParallel executes tests concurrently.
 func (m *MultiWANRunner) runParallel(ctx context.Context) ([]Result, error) {
 	var wg sync.WaitGroup
-	resultsChan := make(chan Result, len(m.connections))
+	// Dual-stack connections return two results
+	resultsChan := make(chan Result, 2*len(m.connections))
 
 	for _, conn := range m.connections {
 		wg.Add(1)
@@ -148,23 +298,12 @@ func (m *MultiWANRunner) runParallel(ctx context.Context) ([]Result, error) {
 
 			m.logger.Info("Testing connection (parallel)",
 				zap.String("name", c.Name),
+				zap.Bool("dual_stack", c.DualStack),
 			)
 
-			result, err := m.runner.Run(ctx, c)
-			if err != nil {
-				m.logger.Error("Speedtest failed",
-					zap.String("connection", c.Name),
-					zap.Error(err),
-				)
-				result = &Result{
-					ConnectionName: c.Name,
-					SourceIP:       c.SourceIP,
-					DSCP:           c.DSCP,
-					Error:          err.Error(),
-				}
+			for _, result := range m.runConnection(ctx, c) {
+				resultsChan <- result
 			}
-
-			resultsChan <- *result
 		}(conn)
 	}
 
@@ -173,19 +312,115 @@ func (m *MultiWANRunner) runParallel(ctx context.Context) ([]Result, error) {
 	close(resultsChan)
 
 	// Collect results
-	results := make([]Result, 0, len(m.connections))
+	results := make([]Result, 0, len(resultsChan)+1)
 	for result := range resultsChan {
 		results = append(results, result)
 	}
 
+	if m.aggregate {
+		if agg := Aggregate(results); agg != nil {
+			m.logger.Info("Aggregate throughput",
+				zap.Float64("download_mbps", agg.DownloadMbps),
+				zap.Float64("upload_mbps", agg.UploadMbps),
+				zap.Int("connections", len(results)),
+			)
+			results = append(results, *agg)
+		}
+	}
+
 	return results, nil
 }
 
-// RunConnection executes a speedtest for a specific connection by name.
-func (m *MultiWANRunner) RunConnection(ctx context.Context, name string) (*Result, error) {
+// RunConnection executes a speedtest for a specific connection by name,
+// running only the phases enabled in opts.
+func (m *MultiWANRunner) RunConnection(ctx context.Context, name string, opts RunOptions) (*Result, error) {
+	for _, conn := range m.connections {
+		if conn.Name == name {
+			return m.runner.Run(ctx, conn, opts)
+		}
+	}
+	return nil, fmt.Errorf("connection %q not found", name)
+}
+
+// CompareServers tests the named connection against each of the given servers
+// one after another, so results of different servers can be compared.
+// Failed tests are returned as error results.
+func (m *MultiWANRunner) CompareServers(ctx context.Context, name string, serverIDs []int) ([]Result, error) {
+	var conn *WANConnection
+	for i := range m.connections {
+		if m.connections[i].Name == name {
+			conn = &m.connections[i]
+			break
+		}
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection %q not found", name)
+	}
+
+	results := make([]Result, 0, len(serverIDs))
+	for _, id := range serverIDs {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		default:
+		}
+
+		m.logger.Info("Testing connection against server",
+			zap.String("name", conn.Name),
+			zap.Int("server_id", id),
+		)
+
+		opts := DefaultRunOptions()
+		opts.ServerID = id
+		result, err := m.runner.Run(ctx, *conn, opts)
+		if err != nil {
+			m.logger.Error("Speedtest failed",
+				zap.String("connection", conn.Name),
+				zap.Int("server_id", id),
+				zap.Error(err),
+			)
+			if result == nil {
+				result = &Result{
+					ConnectionName: conn.Name,
+					SourceIP:       conn.SourceIP,
+					DSCP:           conn.DSCP,
+				}
+			}
+			if result.Error == "" {
+				result.Error = err.Error()
+			}
+		}
+		result.ServerID = id
+
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// FetchServers fetches the server list through the named connection, or
+// without a source binding if name is empty.
+func (m *MultiWANRunner) FetchServers(ctx context.Context, name string) ([]ServerInfo, error) {
+	if name == "" {
+		return m.runner.FetchServers(ctx, WANConnection{})
+	}
+	for _, conn := range m.connections {
+		if conn.Name == name {
+			return m.runner.FetchServers(ctx, conn)
+		}
+	}
+	return nil, fmt.Errorf("connection %q not found", name)
+}
+
+// QuickTest performs a latency-only test of the named connection, or of the
+// first enabled connection if name is empty.
+func (m *MultiWANRunner) QuickTest(ctx context.Context, name string) (*Result, error) {
+	if name == "" {
+		return m.runner.QuickTest(ctx, m.connections[0])
+	}
 	for _, conn := range m.connections {
 		if conn.Name == name {
-			return m.runner.Run(ctx, conn)
+			return m.runner.QuickTest(ctx, conn)
 		}
 	}
 	return nil, fmt.Errorf("connection %q not found", name)
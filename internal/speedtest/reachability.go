@@ -0,0 +1,52 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+)
+
+// ErrUnreachable is returned by Run if the reachability check of a connection fails.
+var ErrUnreachable = errors.New("connection unreachable")
+
+// checkReachable opens a TCP connection to the reachability target through
+// the connection's source binding, so tests on dead links fail within the
+// reachability timeout instead of the much longer server list and test
+// timeouts. A refused connection still proves that the link works.
+// With a proxy, the proxy is checked instead, as it is the only host the
+// tests connect to directly.
+// The check is skipped if the timeout is not positive.
+func (r *Runner) checkReachable(ctx context.Context, dialer *DSCPDialer) error {
+	timeout := r.config.ReachabilityTimeout
+	target := r.config.ReachabilityTarget
+	if proxy, err := r.config.ParseProxyURL(); err == nil && proxy != nil {
+		target = config.ProxyAddress(proxy)
+	}
+	if timeout <= 0 || target == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %s not reachable within %s: %v", ErrUnreachable, target, timeout, err)
+	}
+	if conn != nil {
+		_ = conn.Close()
+	}
+
+	r.logger.Debug("Reachability check passed",
+		zap.String("target", target),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return nil
+}
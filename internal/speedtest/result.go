@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
 )
 
 // Result represents the outcome of a single speedtest.
@@ -12,12 +16,16 @@ type Result struct {
 	ConnectionName string `json:"connection_name"`
 	SourceIP       string `json:"source_ip,omitempty"`
 	DSCP           int    `json:"dscp"`
+	// DSCPApplied is true if the DSCP value was set on all test connections
+	DSCPApplied bool `json:"dscp_applied"`
 
 	// Server info
 	ServerID      int    `json:"server_id,omitempty"`
 	ServerName    string `json:"server_name,omitempty"`
 	ServerCountry string `json:"server_country,omitempty"`
 	ServerHost    string `json:"server_host,omitempty"`
+	// ServerDistanceKm is the great-circle distance to the server (0 if unknown)
+	ServerDistanceKm float64 `json:"server_distance_km,omitempty"`
 
 	// Test results
 	LatencyMs     float64 `json:"latency_ms"`
@@ -25,11 +33,202 @@ type Result struct {
 	DownloadMbps  float64 `json:"download_mbps"`
 	UploadMbps    float64 `json:"upload_mbps"`
 	PacketLossPct float64 `json:"packet_loss_pct,omitempty"`
+	// ConnectMs is the time to establish a TCP connection to the server
+	// through the connection's binding (0 if it couldn't be measured)
+	ConnectMs float64 `json:"connect_ms,omitempty"`
+
+	// Per-phase status: false if the phase failed, so its value is not meaningful
+	LatencyOK  bool `json:"latency_ok"`
+	DownloadOK bool `json:"download_ok"`
+	UploadOK   bool `json:"upload_ok"`
 
 	// Metadata
 	Timestamp time.Time `json:"timestamp"`
 	Duration  float64   `json:"duration_seconds,omitempty"`
 	Error     string    `json:"error,omitempty"`
+	// Attempts is the number of servers the test was run against, as it
+	// moves on to a fallback server if every phase fails (1 = first try,
+	// 0 for aggregate results)
+	Attempts int `json:"attempts,omitempty"`
+	// BytesDownloaded and BytesUploaded are the bytes transferred by the
+	// download and upload phases (0 for aggregate results, so usage isn't
+	// counted twice)
+	BytesDownloaded int64 `json:"bytes_downloaded,omitempty"`
+	BytesUploaded   int64 `json:"bytes_uploaded,omitempty"`
+	// IPFamily is the address family the test was restricted to (FamilyIPv4
+	// or FamilyIPv6), set for dual-stack connections only
+	IPFamily string `json:"ip_family,omitempty"`
+	// ConfigHash identifies the connection and speedtest settings the test
+	// ran with, so results of different configurations can be told apart
+	ConfigHash string `json:"config_hash,omitempty"`
+	// DownloadSize and UploadSize are the transfer sizes per request of the
+	// download (image dimensions) and upload (bytes) phases, if they ran
+	// (empty for aggregate results)
+	DownloadSize string `json:"download_size,omitempty"`
+	UploadSize   string `json:"upload_size,omitempty"`
+	// Source is what started the test (SourceScheduled, SourceAPI or
+	// SourceCLI), set by the caller of the runner
+	Source string `json:"source,omitempty"`
+	// Threads is the number of concurrent connections of the download and
+	// upload phases (0 for aggregate results)
+	Threads int `json:"threads,omitempty"`
+}
+
+// Sources of a test (Result.Source).
+const (
+	SourceScheduled = "scheduled"
+	SourceAPI       = "api"
+	SourceCLI       = "cli"
+)
+
+// IsValidSource returns true if source is one of the test sources.
+func IsValidSource(source string) bool {
+	switch source {
+	case SourceScheduled, SourceAPI, SourceCLI:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAggregate returns true if the result is an aggregate of a parallel run
+// rather than the test of a single connection.
+func (r *Result) IsAggregate() bool {
+	return r.ConnectionName == config.AggregateConnectionName
+}
+
+// TestCompletedMessage is the message of the log line emitted for every
+// finished test by LogCompleted.
+const TestCompletedMessage = "test_completed"
+
+// LogFields returns all values of the result as log fields. Field names are
+// the same as in the JSON representation of the result.
+func (r *Result) LogFields() []zap.Field {
+	return []zap.Field{
+		zap.String("connection_name", r.ConnectionName),
+		zap.String("ip_family", r.IPFamily),
+		zap.String("source_ip", r.SourceIP),
+		zap.Int("dscp", r.DSCP),
+		zap.Bool("dscp_applied", r.DSCPApplied),
+		zap.Int("server_id", r.ServerID),
+		zap.String("server_name", r.ServerName),
+		zap.String("server_country", r.ServerCountry),
+		zap.String("server_host", r.ServerHost),
+		zap.Float64("server_distance_km", r.ServerDistanceKm),
+		zap.Float64("latency_ms", r.LatencyMs),
+		zap.Float64("jitter_ms", r.JitterMs),
+		zap.Float64("connect_ms", r.ConnectMs),
+		zap.Float64("download_mbps", r.DownloadMbps),
+		zap.Float64("upload_mbps", r.UploadMbps),
+		zap.Float64("packet_loss_pct", r.PacketLossPct),
+		zap.Bool("latency_ok", r.LatencyOK),
+		zap.Bool("download_ok", r.DownloadOK),
+		zap.Bool("upload_ok", r.UploadOK),
+		zap.Time("test_timestamp", r.Timestamp),
+		zap.Float64("duration_seconds", r.Duration),
+		zap.Int("attempts", r.Attempts),
+		zap.Int64("bytes_downloaded", r.BytesDownloaded),
+		zap.Int64("bytes_uploaded", r.BytesUploaded),
+		zap.String("error", r.Error),
+		zap.String("config_hash", r.ConfigHash),
+		zap.String("download_size", r.DownloadSize),
+		zap.String("upload_size", r.UploadSize),
+		zap.String("source", r.Source),
+		zap.Int("threads", r.Threads),
+	}
+}
+
+// LogCompleted emits a single info line with message TestCompletedMessage and
+// all metrics of the result as fields, so finished tests can be picked up by
+// log pipelines without scraping the API or the database.
+func LogCompleted(logger *zap.Logger, r *Result) {
+	logger.Info(TestCompletedMessage, r.LogFields()...)
+}
+
+// Aggregate combines the results of a parallel run into a single result named
+// config.AggregateConnectionName. Download and upload are the sums over all
+// connections whose phase succeeded, i.e. the total throughput while all links
+// were loaded at the same time. Latency is not aggregated. Returns nil if
+// fewer than two connections were tested.
+//
+// The sum is only meaningful for parallel runs: in sequential runs each
+// connection has the full bandwidth of shared upstream links to itself.
+func Aggregate(results []Result) *Result {
+	// Count dual-stack connections once, with their IPv4 result
+	counted := make([]Result, 0, len(results))
+	connections := make(map[string]bool)
+	for _, r := range results {
+		if r.IsAggregate() || r.IPFamily == FamilyIPv6 {
+			continue
+		}
+		counted = append(counted, r)
+		connections[r.ConnectionName] = true
+	}
+	if len(connections) < 2 {
+		return nil
+	}
+
+	agg := &Result{ConnectionName: config.AggregateConnectionName}
+	for _, r := range counted {
+		if r.DownloadOK {
+			agg.DownloadMbps += r.DownloadMbps
+			agg.DownloadOK = true
+		}
+		if r.UploadOK {
+			agg.UploadMbps += r.UploadMbps
+			agg.UploadOK = true
+		}
+		if agg.Timestamp.IsZero() || (!r.Timestamp.IsZero() && r.Timestamp.Before(agg.Timestamp)) {
+			agg.Timestamp = r.Timestamp
+		}
+		if r.Duration > agg.Duration {
+			agg.Duration = r.Duration
+		}
+	}
+
+	if agg.Timestamp.IsZero() {
+		agg.Timestamp = time.Now()
+	}
+	if !agg.DownloadOK && !agg.UploadOK {
+		agg.Error = "no connection completed a throughput test"
+	}
+
+	return agg
+}
+
+// SpeedUnit is the unit used to display download and upload speeds.
+// Speeds are always stored and returned by the API in Mbps.
+type SpeedUnit string
+
+// Supported speed units.
+const (
+	SpeedUnitMbps   SpeedUnit = "mbps"
+	SpeedUnitGbps   SpeedUnit = "gbps"
+	SpeedUnitMBytes SpeedUnit = "mbytes"
+)
+
+// Convert converts a speed in Mbps to this unit.
+func (u SpeedUnit) Convert(mbps float64) float64 {
+	switch u {
+	case SpeedUnitGbps:
+		return mbps / 1000
+	case SpeedUnitMBytes:
+		return mbps / 8
+	default:
+		return mbps
+	}
+}
+
+// Label returns the display label for this unit.
+func (u SpeedUnit) Label() string {
+	switch u {
+	case SpeedUnitGbps:
+		return "Gbps"
+	case SpeedUnitMBytes:
+		return "MB/s"
+	default:
+		return "Mbps"
+	}
 }
 
 // IsError returns true if the result represents a failed test.
@@ -37,6 +236,15 @@ func (r *Result) IsError() bool {
 	return r.Error != ""
 }
 
+// ConnectionLabel returns the connection name for display, followed by the
+// address family for tests of dual-stack connections, e.g. "WAN1 (ipv6)".
+func ConnectionLabel(name, family string) string {
+	if family == "" {
+		return name
+	}
+	return name + " (" + family + ")"
+}
+
 // JSON returns the result as a JSON string.
 func (r *Result) JSON() string {
 	data, err := json.MarshalIndent(r, "", "  ")
@@ -49,7 +257,7 @@ func (r *Result) JSON() string {
 // String returns a human-readable representation of the result.
 func (r *Result) String() string {
 	if r.IsError() {
-		return fmt.Sprintf("%s: ERROR - %s", r.ConnectionName, r.Error)
+		return fmt.Sprintf("%s: ERROR - %s", ConnectionLabel(r.ConnectionName, r.IPFamily), r.Error)
 	}
 
 	return fmt.Sprintf(`%s:
@@ -57,7 +265,7 @@ func (r *Result) String() string {
   Latency:   %.2f ms
   Download:  %.2f Mbps
   Upload:    %.2f Mbps`,
-		r.ConnectionName,
+		ConnectionLabel(r.ConnectionName, r.IPFamily),
 		r.ServerName,
 		r.ServerCountry,
 		r.LatencyMs,
@@ -66,17 +274,18 @@ func (r *Result) String() string {
 	)
 }
 
-// FormatTable returns a formatted table row for CLI output.
-func (r *Result) FormatTable() string {
+// FormatTable returns a formatted table row for CLI output,
+// with speeds in the given unit.
+func (r *Result) FormatTable(unit SpeedUnit) string {
 	if r.IsError() {
-		return fmt.Sprintf("%-20s | %-10s | %s", r.ConnectionName, "ERROR", r.Error)
+		return fmt.Sprintf("%-20s | %-10s | %s", ConnectionLabel(r.ConnectionName, r.IPFamily), "ERROR", r.Error)
 	}
 
-	return fmt.Sprintf("%-20s | %8.2f ms | %10.2f Mbps | %10.2f Mbps | %s",
-		r.ConnectionName,
+	return fmt.Sprintf("%-20s | %8.2f ms | %10.2f %s | %10.2f %s | %s",
+		ConnectionLabel(r.ConnectionName, r.IPFamily),
 		r.LatencyMs,
-		r.DownloadMbps,
-		r.UploadMbps,
+		unit.Convert(r.DownloadMbps), unit.Label(),
+		unit.Convert(r.UploadMbps), unit.Label(),
 		r.ServerName,
 	)
 }
@@ -92,6 +301,44 @@ func TableSeparator() string {
 	return "---------------------+-------------+----------------+----------------+------------------"
 }
 
+// FormatServerTable returns a formatted table row for comparing the results
+// of one connection against different servers, with speeds in the given unit.
+func (r *Result) FormatServerTable(unit SpeedUnit) string {
+	server := r.ServerName
+	if server == "" {
+		server = "-"
+	} else if r.ServerCountry != "" {
+		server += " (" + r.ServerCountry + ")"
+	}
+	if runes := []rune(server); len(runes) > 24 {
+		server = string(runes[:23]) + "…"
+	}
+
+	if r.IsError() {
+		return fmt.Sprintf("%8d | %-24s | ERROR: %s", r.ServerID, server, r.Error)
+	}
+
+	return fmt.Sprintf("%8d | %-24s | %6.0f km | %8.2f ms | %10.2f %-4s | %10.2f %-4s",
+		r.ServerID,
+		server,
+		r.ServerDistanceKm,
+		r.LatencyMs,
+		unit.Convert(r.DownloadMbps), unit.Label(),
+		unit.Convert(r.UploadMbps), unit.Label(),
+	)
+}
+
+// ServerTableHeader returns the header for server comparison output.
+func ServerTableHeader() string {
+	return fmt.Sprintf("%8s | %-24s | %9s | %11s | %15s | %15s",
+		"ID", "Server", "Distance", "Latency", "Download", "Upload")
+}
+
+// ServerTableSeparator returns a separator line for server comparison output.
+func ServerTableSeparator() string {
+	return "---------+--------------------------+-----------+-------------+-----------------+----------------"
+}
+
 // Results is a collection of Result objects with helper methods.
 type Results []Result
 
@@ -104,15 +351,29 @@ func (rs Results) ToJSON() string {
 	return string(data)
 }
 
-// PrintTable prints results as a formatted table.
-func (rs Results) PrintTable() string {
+// PrintTable prints results as a formatted table, with speeds in the given unit.
+func (rs Results) PrintTable(unit SpeedUnit) string {
 	if len(rs) == 0 {
 		return "No results"
 	}
 
 	output := TableHeader() + "\n" + TableSeparator() + "\n"
 	for _, r := range rs {
-		output += r.FormatTable() + "\n"
+		output += r.FormatTable(unit) + "\n"
+	}
+	return output
+}
+
+// PrintServerComparison prints results of one connection against different
+// servers as a formatted table, with speeds in the given unit.
+func (rs Results) PrintServerComparison(unit SpeedUnit) string {
+	if len(rs) == 0 {
+		return "No results"
+	}
+
+	output := ServerTableHeader() + "\n" + ServerTableSeparator() + "\n"
+	for _, r := range rs {
+		output += r.FormatServerTable(unit) + "\n"
 	}
 	return output
 }
@@ -2,7 +2,14 @@ package speedtest
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/showwin/speedtest-go/speedtest"
@@ -13,8 +20,79 @@ import (
 
 // Runner executes speedtests using speedtest-go.
 type Runner struct {
-	config *config.SpeedtestConfig
-	logger *zap.Logger
+	config  *config.SpeedtestConfig
+	logger  *zap.Logger
+	breaker *serverBreaker
+	servers *serverCache
+	// history provides past downloads for the history server selection
+	history ServerHistory
+}
+
+// Test phases that can be selected per run.
+const (
+	PhaseLatency  = "latency"
+	PhaseDownload = "download"
+	PhaseUpload   = "upload"
+)
+
+// RunOptions controls which test phases a single run performs.
+type RunOptions struct {
+	Latency  bool
+	Download bool
+	Upload   bool
+	// ServerID tests against this server instead of the configured or
+	// auto-selected one (0 = default selection)
+	ServerID int
+}
+
+// DefaultRunOptions returns options that run all test phases.
+func DefaultRunOptions() RunOptions {
+	return RunOptions{Latency: true, Download: true, Upload: true}
+}
+
+// Phases returns the names of the enabled phases.
+func (o RunOptions) Phases() []string {
+	var phases []string
+	if o.Latency {
+		phases = append(phases, PhaseLatency)
+	}
+	if o.Download {
+		phases = append(phases, PhaseDownload)
+	}
+	if o.Upload {
+		phases = append(phases, PhaseUpload)
+	}
+	return phases
+}
+
+// speedtest-go transfers fixed sizes per request, regardless of
+// speedtest.download_size and upload_size, which it has no setting for:
+// a random image of requestDownloadSize pixels per download request and
+// requestUploadSize bytes per upload request. Results record these.
+const (
+	requestDownloadSize = "1500x1500"
+	requestUploadSize   = "999490"
+)
+
+// PhaseServerSelection is the phase of fetching and selecting a server,
+// reported in a TimeoutError.
+const PhaseServerSelection = "server selection"
+
+// TimeoutError reports that a speedtest exceeded its timeout (the
+// connection's timeout or speedtest.timeout).
+type TimeoutError struct {
+	// Phase is the test phase that was running when the timeout expired
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("speedtest timed out after %s during %s", e.Timeout, e.Phase)
+}
+
+// Unwrap allows errors.Is(err, context.DeadlineExceeded).
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
 }
 
 // NewRunner creates a new speedtest Runner.
@@ -32,20 +110,42 @@ func NewRunner(cfg *config.SpeedtestConfig, logger *zap.Logger) (*Runner, error)
 	}
 
 	return &Runner{
-		config: cfg,
-		logger: logger,
+		config:  cfg,
+		logger:  logger,
+		breaker: newServerBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, logger),
+		servers: newServerCache(cfg.ServerCacheTTL, logger),
 	}, nil
 }
 
 // Run executes a speedtest for the given WAN connection.
-func (r *Runner) Run(ctx context.Context, conn WANConnection) (*Result, error) {
+// Phases disabled in opts are skipped and reported as not OK. If every
+// phase fails, the test is repeated against up to maxAttempts-1 fallback
+// servers; result.Attempts counts the servers tried.
+// The whole test is limited to the connection's timeout, or speedtest.timeout
+// if it has none; exceeding it returns a *TimeoutError along with the partial
+// result.
+func (r *Runner) Run(ctx context.Context, conn WANConnection, opts RunOptions) (*Result, error) {
 	startTime := time.Now()
 
+	timeout := r.config.Timeout
+	if conn.Timeout > 0 {
+		timeout = conn.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	result := &Result{
 		ConnectionName: conn.Name,
 		SourceIP:       conn.SourceIP,
 		DSCP:           conn.DSCP,
 		Timestamp:      startTime,
+		Attempts:       1,
+		IPFamily:       conn.Family,
+		ConfigHash:     conn.ConfigHash,
+		Threads:        r.threads(),
 	}
 
 	// Create DSCP dialer for custom socket options
@@ -54,60 +154,166 @@ func (r *Runner) Run(ctx context.Context, conn WANConnection) (*Result, error) {
 		result.Error = fmt.Sprintf("failed to create DSCP dialer: %v", err)
 		return result, err
 	}
+	dscpDialer.Interface = conn.Interface
+	dscpDialer.Family = conn.Family
+	defer func() { result.DSCPApplied = dscpDialer.Applied() }()
 
-	// Build UserConfig with DialerControl for DSCP marking
-	// This is the proper way to inject custom socket options into speedtest-go
-	userConfig := &speedtest.UserConfig{}
-	
-	// Set source IP if specified
-	if conn.SourceIP != "" {
-		userConfig.Source = conn.SourceIP
+	// Resolve the source IP for every test, so interface address changes are picked up
+	sourceIP, err := dscpDialer.LocalIP()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to resolve source IP: %v", err)
+		return result, err
 	}
-	
-	// Set DialerControl for DSCP marking (works with both Source IP and without)
-	if conn.DSCP > 0 {
-		userConfig.DialerControl = dscpDialer.controlFunc
+	result.SourceIP = sourceIP
+
+	// Fail fast on dead links instead of waiting out the server list timeouts
+	if err := r.checkReachable(ctx, dscpDialer); err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(startTime).Seconds()
+		return result, err
 	}
-	
+
 	// Create speedtest client with our custom config
-	client := speedtest.New(
-		speedtest.WithUserConfig(userConfig),
-	)
-	
+	client, transport := r.newClient(dscpDialer, sourceIP)
+	// Record the transferred bytes on every return, including failed and timed out tests
+	defer func() {
+		result.BytesDownloaded = client.GetTotalDownload()
+		result.BytesUploaded = client.GetTotalUpload()
+	}()
+
 	r.logger.Debug("Created speedtest client",
-		zap.String("source_ip", conn.SourceIP),
+		zap.String("source_ip", sourceIP),
+		zap.String("interface", conn.Interface),
 		zap.Int("dscp", conn.DSCP),
-		zap.Bool("has_dialer_control", conn.DSCP > 0),
+		zap.String("family", conn.Family),
+		zap.Bool("proxy", r.config.ProxyURL != ""),
 	)
 
-	// Fetch server list
-	r.logger.Debug("Fetching speedtest servers")
-	serverList, err := client.FetchServers()
+	// Fetch client location (used for the server distance, optional)
+	user, err := client.FetchUserInfoContext(ctx)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to fetch servers: %v", err)
-		return result, err
+		r.logger.Debug("Failed to fetch user info, server distance unavailable", zap.Error(err))
+	}
+
+	// Servers to try in order; the test moves on to the next one if every
+	// phase fails
+	var servers []*speedtest.Server
+	if opts.ServerID > 0 {
+		// Explicit server override: look it up directly, since it may not be
+		// part of the nearby server list
+		r.logger.Debug("Fetching requested speedtest server", zap.Int("server_id", opts.ServerID))
+		server, err := client.FetchServerByIDContext(ctx, strconv.Itoa(opts.ServerID))
+		if timeoutErr := checkTimeout(ctx, PhaseServerSelection, timeout); timeoutErr != nil {
+			return r.timedOut(result, "", startTime, timeoutErr)
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to fetch server %d: %v", opts.ServerID, err)
+			return result, err
+		}
+		servers = []*speedtest.Server{server}
+	} else {
+		// Fetch server list
+		r.logger.Debug("Fetching speedtest servers")
+		serverList, err := r.servers.fetch(ctx, client)
+		if timeoutErr := checkTimeout(ctx, PhaseServerSelection, timeout); timeoutErr != nil {
+			return r.timedOut(result, "", startTime, timeoutErr)
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to fetch servers: %v", err)
+			return result, err
+		}
+
+		// Find servers (empty slice = auto-select based on latency)
+		var serverIDs []int
+		if len(r.config.ServerIDs) > 0 {
+			serverIDs = r.config.ServerIDs
+		}
+
+		// Exclude servers whose circuit breaker is open
+		serverList = r.availableServers(serverList)
+
+		// Prefer a consistently good server among those with similar latency
+		var server *speedtest.Server
+		if len(serverIDs) == 0 {
+			server = r.selectByHistory(ctx, conn, serverList)
+		}
+
+		targets, err := serverList.FindServer(serverIDs)
+		if server == nil {
+			if err != nil {
+				result.Error = fmt.Sprintf("failed to find server: %v", err)
+				return result, err
+			}
+
+			if len(targets) == 0 {
+				result.Error = "no speedtest servers available"
+				return result, fmt.Errorf("%s", result.Error)
+			}
+
+			// Use the first (best) server
+			server = targets[0]
+		}
+
+		// Fall back to the other configured servers, or the other servers
+		// by latency
+		fallbacks := targets
+		if len(serverIDs) == 0 {
+			fallbacks = serversByLatency(serverList)
+		}
+		servers = fallbackServers(server, fallbacks)
 	}
 
-	// Find servers (empty slice = auto-select based on latency)
-	var serverIDs []int
-	if len(r.config.ServerIDs) > 0 {
-		serverIDs = r.config.ServerIDs
+	var phaseErrors []string
+	allFailed := func() bool {
+		return len(phaseErrors) > 0 && len(phaseErrors) == len(opts.Phases())
 	}
+	for i, server := range servers {
+		result.Attempts = i + 1
+		if i > 0 {
+			r.logger.Warn("All test phases failed, retrying with the next server",
+				zap.String("connection", conn.Name),
+				zap.Int("attempt", result.Attempts),
+				zap.String("failed_server", servers[i-1].Host),
+				zap.String("server", server.Host),
+			)
+		}
 
-	targets, err := serverList.FindServer(serverIDs)
-	if err != nil {
-		result.Error = fmt.Sprintf("failed to find server: %v", err)
-		return result, err
+		var timeoutErr error
+		phaseErrors, timeoutErr = r.runPhases(ctx, result, client, transport, dscpDialer, user, server, opts, timeout)
+		if timeoutErr != nil {
+			return r.timedOut(result, server.Host, startTime, timeoutErr)
+		}
+		if !allFailed() || ctx.Err() != nil {
+			break
+		}
 	}
 
-	if len(targets) == 0 {
-		result.Error = "no speedtest servers available"
-		return result, fmt.Errorf("%s", result.Error)
+	// Calculate duration
+	result.Duration = time.Since(startTime).Seconds()
+
+	// A test without any successful phase is a failed test; partial failures
+	// are reported by the per-phase status only
+	if allFailed() {
+		result.Error = "all test phases failed: " + strings.Join(phaseErrors, "; ")
+		return result, errors.New(result.Error)
 	}
 
-	// Use the first (best) server
-	server := targets[0]
+	r.logger.Debug("Speedtest completed",
+		zap.String("connection", conn.Name),
+		zap.Float64("download_mbps", result.DownloadMbps),
+		zap.Float64("upload_mbps", result.UploadMbps),
+		zap.Float64("latency_ms", result.LatencyMs),
+		zap.Float64("duration_s", result.Duration),
+	)
 
+	return result, nil
+}
+
+// runPhases runs the phases enabled in opts against server and records
+// their values in result. Values of failed phases are not recorded, so a
+// failure isn't mistaken for a measured 0. It returns the errors of the
+// failed phases, or a TimeoutError if the test's deadline expired.
+func (r *Runner) runPhases(ctx context.Context, result *Result, client *speedtest.Speedtest, transport *http.Transport, dscpDialer *DSCPDialer, user *speedtest.User, server *speedtest.Server, opts RunOptions, timeout time.Duration) ([]string, error) {
 	r.logger.Debug("Selected server",
 		zap.String("name", server.Name),
 		zap.String("country", server.Country),
@@ -120,48 +326,279 @@ func (r *Runner) Run(ctx context.Context, conn WANConnection) (*Result, error) {
 	result.ServerCountry = server.Country
 	result.ServerHost = server.Host
 	result.ServerID = parseServerID(server.ID)
+	result.ServerDistanceKm = serverDistanceKm(user, server)
+	r.logger.Debug("Server distance", zap.Float64("distance_km", result.ServerDistanceKm))
+
+	var phaseErrors []string
+	// Any failed phase counts against the server, including the latency
+	// phase, which is the only one of a QuickTest
+	phaseFailed := false
+
+	// The phases' connections to the server are dialed through a dialer of
+	// their own, whose first connect is the test's connect time. Through a
+	// proxy, it would be the time to connect to the proxy instead.
+	serverDialer := dscpDialer.connectTimer()
+	transport.DialContext = serverDialer.DialContext
+	result.ConnectMs = 0
+	if r.config.ProxyURL == "" {
+		defer func() {
+			result.ConnectMs = float64(serverDialer.FirstConnectTime().Microseconds()) / 1000
+			r.logger.Debug("Connect time", zap.String("host", server.Host), zap.Float64("connect_ms", result.ConnectMs))
+		}()
+	}
 
 	// Run ping test
-	r.logger.Debug("Running latency test")
-	if err := server.PingTest(nil); err != nil {
-		r.logger.Warn("Ping test failed", zap.Error(err))
-	} else {
-		result.LatencyMs = float64(server.Latency.Milliseconds())
-		result.JitterMs = float64(server.Jitter.Milliseconds())
+	if opts.Latency {
+		r.logger.Debug("Running latency test")
+		err := server.PingTestContext(ctx, nil)
+		if timeoutErr := checkTimeout(ctx, PhaseLatency, timeout); timeoutErr != nil {
+			return phaseErrors, timeoutErr
+		}
+		if err != nil {
+			r.logger.Warn("Ping test failed", zap.Error(err))
+			phaseErrors = append(phaseErrors, fmt.Sprintf("%s: %v", PhaseLatency, err))
+			phaseFailed = true
+		} else {
+			result.LatencyMs = float64(server.Latency.Milliseconds())
+			result.JitterMs = float64(server.Jitter.Milliseconds())
+			result.LatencyOK = true
+		}
 	}
 
 	// Run download test
-	r.logger.Debug("Running download test")
-	if err := server.DownloadTest(); err != nil {
-		r.logger.Warn("Download test failed", zap.Error(err))
-	}
-	// Use ByteRate's Mbps() method for correct conversion
-	result.DownloadMbps = server.DLSpeed.Mbps()
-	r.logger.Debug("Download result",
-		zap.Float64("raw_dlspeed", float64(server.DLSpeed)),
-		zap.Float64("mbps", result.DownloadMbps),
-	)
+	if opts.Download {
+		r.logger.Debug("Running download test")
+		result.DownloadSize = requestDownloadSize
+		var meter *warmupMeter
+		if r.config.Warmup > 0 {
+			meter = startWarmupMeter(r.config.Warmup, client.GetTotalDownload)
+		}
+		err := server.DownloadTestContext(ctx)
+		if timeoutErr := checkTimeout(ctx, PhaseDownload, timeout); timeoutErr != nil {
+			return phaseErrors, timeoutErr
+		}
+		if err != nil {
+			r.logger.Warn("Download test failed", zap.Error(err))
+			phaseErrors = append(phaseErrors, fmt.Sprintf("%s: %v", PhaseDownload, err))
+			phaseFailed = true
+		} else {
+			result.DownloadOK = true
+			// Use ByteRate's Mbps() method for correct conversion
+			result.DownloadMbps = server.DLSpeed.Mbps()
+			r.logger.Debug("Download result",
+				zap.Float64("raw_dlspeed", float64(server.DLSpeed)),
+				zap.Float64("mbps", result.DownloadMbps),
+			)
+			result.DownloadMbps = r.applyWarmup(meter, PhaseDownload, result.DownloadMbps)
+		}
+	}
 
 	// Run upload test
-	r.logger.Debug("Running upload test")
-	if err := server.UploadTest(); err != nil {
-		r.logger.Warn("Upload test failed", zap.Error(err))
+	if opts.Upload {
+		r.logger.Debug("Running upload test")
+		result.UploadSize = requestUploadSize
+		var meter *warmupMeter
+		if r.config.Warmup > 0 {
+			meter = startWarmupMeter(r.config.Warmup, client.GetTotalUpload)
+		}
+		err := server.UploadTestContext(ctx)
+		if timeoutErr := checkTimeout(ctx, PhaseUpload, timeout); timeoutErr != nil {
+			return phaseErrors, timeoutErr
+		}
+		if err != nil {
+			r.logger.Warn("Upload test failed", zap.Error(err))
+			phaseErrors = append(phaseErrors, fmt.Sprintf("%s: %v", PhaseUpload, err))
+			phaseFailed = true
+		} else {
+			result.UploadOK = true
+			// Use ByteRate's Mbps() method for correct conversion
+			result.UploadMbps = server.ULSpeed.Mbps()
+			result.UploadMbps = r.applyWarmup(meter, PhaseUpload, result.UploadMbps)
+		}
 	}
-	// Use ByteRate's Mbps() method for correct conversion
-	result.UploadMbps = server.ULSpeed.Mbps()
 
-	// Calculate duration
-	result.Duration = time.Since(startTime).Seconds()
+	if phaseFailed {
+		r.breaker.recordFailure(server.Host)
+	} else {
+		r.breaker.recordSuccess(server.Host)
+	}
 
-	r.logger.Debug("Speedtest completed",
-		zap.String("connection", conn.Name),
-		zap.Float64("download_mbps", result.DownloadMbps),
-		zap.Float64("upload_mbps", result.UploadMbps),
-		zap.Float64("latency_ms", result.LatencyMs),
-		zap.Float64("duration_s", result.Duration),
+	return phaseErrors, nil
+}
+
+// applyWarmup returns the rate measured by meter after the warmup, or mbps
+// (the rate of the whole transfer) if there is no meter or the transfer
+// ended before the warmup did.
+func (r *Runner) applyWarmup(meter *warmupMeter, phase string, mbps float64) float64 {
+	if meter == nil {
+		return mbps
+	}
+	warmed, ok := meter.stop()
+	if mbps <= 0 {
+		return mbps
+	}
+	if !ok {
+		r.logger.Debug("Transfer ended during warmup, using the rate of the whole transfer",
+			zap.String("phase", phase),
+		)
+		return mbps
+	}
+	r.logger.Debug("Excluded warmup from measured rate",
+		zap.String("phase", phase),
+		zap.Float64("whole_mbps", mbps),
+		zap.Float64("mbps", warmed),
 	)
+	return warmed
+}
 
-	return result, nil
+// checkTimeout returns a TimeoutError if the test's deadline has expired.
+func checkTimeout(ctx context.Context, phase string, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Phase: phase, Timeout: timeout}
+	}
+	return nil
+}
+
+// timedOut records a timeout in the result and counts it as a failure of
+// the server (if one was selected).
+func (r *Runner) timedOut(result *Result, host string, startTime time.Time, err error) (*Result, error) {
+	r.logger.Warn("Speedtest timed out",
+		zap.String("connection", result.ConnectionName),
+		zap.String("server", host),
+		zap.Error(err),
+	)
+	if host != "" {
+		r.breaker.recordFailure(host)
+	}
+	result.Error = err.Error()
+	result.Duration = time.Since(startTime).Seconds()
+	return result, err
+}
+
+// newClient creates a speedtest-go client bound to sourceIP (if set), with
+// the socket options of dialer and the configured proxy. It also returns the
+// client's transport, whose DialContext may be replaced between requests.
+func (r *Runner) newClient(dialer *DSCPDialer, sourceIP string) (*speedtest.Speedtest, *http.Transport) {
+	// Build UserConfig with DialerControl for DSCP marking
+	// This is the proper way to inject custom socket options into speedtest-go
+	userConfig := &speedtest.UserConfig{}
+
+	// Set source IP if specified
+	if sourceIP != "" {
+		userConfig.Source = sourceIP
+	}
+
+	// Set DialerControl for DSCP marking and the address family restriction
+	// (works with both Source IP and without)
+	userConfig.DialerControl = dialer.Control()
+
+	// Route the tests through the proxy, if any (the source binding and
+	// DialerControl then apply to the connection to the proxy)
+	userConfig.Proxy = r.config.ProxyURL
+
+	// Concurrent connections of the download and upload phases
+	userConfig.MaxConnections = r.threads()
+
+	// WithUserConfig sets userConfig.T to the client's transport
+	client := speedtest.New(speedtest.WithUserConfig(userConfig))
+	return client, userConfig.T
+}
+
+// threads returns the number of concurrent connections of the download and
+// upload phases: speedtest.threads, or one per CPU like speedtest-go if unset.
+func (r *Runner) threads() int {
+	if r.config.Threads > 0 {
+		return r.config.Threads
+	}
+	return runtime.NumCPU()
+}
+
+// availableServers filters out servers whose circuit breaker is open.
+// If every server is excluded, the full list is returned so a test can still run.
+func (r *Runner) availableServers(servers speedtest.Servers) speedtest.Servers {
+	available := make(speedtest.Servers, 0, len(servers))
+	for _, s := range servers {
+		if r.breaker.isOpen(s.Host) {
+			r.logger.Debug("Skipping server with open circuit breaker",
+				zap.String("host", s.Host),
+				zap.String("name", s.Name),
+			)
+			continue
+		}
+		available = append(available, s)
+	}
+
+	if len(available) == 0 {
+		r.logger.Warn("All candidate servers have an open circuit breaker, ignoring breaker")
+		return servers
+	}
+	return available
+}
+
+// maxAttempts is the maximum number of servers a test is run against: if
+// every phase fails, it is repeated against the next fallback server.
+const maxAttempts = 3
+
+// fallbackServers returns server followed by the other servers of
+// fallbacks, up to maxAttempts in total.
+func fallbackServers(server *speedtest.Server, fallbacks speedtest.Servers) []*speedtest.Server {
+	servers := []*speedtest.Server{server}
+	for _, s := range fallbacks {
+		if len(servers) == maxAttempts {
+			break
+		}
+		if s.ID != server.ID {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// serversByLatency returns the servers that answered the ping, lowest
+// latency first.
+func serversByLatency(servers speedtest.Servers) speedtest.Servers {
+	responsive := make(speedtest.Servers, 0, len(servers))
+	for _, s := range servers {
+		if s.Latency > 0 {
+			responsive = append(responsive, s)
+		}
+	}
+	sort.SliceStable(responsive, func(i, j int) bool {
+		return responsive[i].Latency < responsive[j].Latency
+	})
+	return responsive
+}
+
+// serverDistanceKm returns the distance between the client and the server.
+// Falls back to the distance reported by Ookla if the client location is unknown.
+func serverDistanceKm(user *speedtest.User, server *speedtest.Server) float64 {
+	if user == nil {
+		return server.Distance
+	}
+
+	uLat, errULat := strconv.ParseFloat(user.Lat, 64)
+	uLon, errULon := strconv.ParseFloat(user.Lon, 64)
+	sLat, errSLat := strconv.ParseFloat(server.Lat, 64)
+	sLon, errSLon := strconv.ParseFloat(server.Lon, 64)
+	if errULat != nil || errULon != nil || errSLat != nil || errSLon != nil {
+		return server.Distance
+	}
+
+	return haversineKm(uLat, uLon, sLat, sLon)
+}
+
+// haversineKm returns the great-circle distance in kilometers between two
+// points given in decimal degrees.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
 }
 
 // parseServerID converts server ID string to int.
@@ -171,10 +608,8 @@ func parseServerID(id string) int {
 	return serverID
 }
 
-// QuickTest performs a quick test to verify connectivity and returns basic results.
-func (r *Runner) QuickTest(ctx context.Context) (*Result, error) {
-	return r.Run(ctx, WANConnection{
-		Name:    "default",
-		Enabled: true,
-	})
+// QuickTest performs a latency-only test of the given connection to quickly
+// verify connectivity, skipping the download and upload phases.
+func (r *Runner) QuickTest(ctx context.Context, conn WANConnection) (*Result, error) {
+	return r.Run(ctx, conn, RunOptions{Latency: true})
 }
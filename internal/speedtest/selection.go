@@ -0,0 +1,87 @@
+package speedtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/showwin/speedtest-go/speedtest"
+	"go.uber.org/zap"
+)
+
+// Server selection modes for auto-selected servers.
+const (
+	ServerSelectionLatency = "latency"
+	ServerSelectionHistory = "history"
+)
+
+// selectionHistoryPeriod is how far back results are considered by the
+// history server selection.
+const selectionHistoryPeriod = 30 * 24 * time.Hour
+
+// ServerHistory returns the average download in Mbps per server ID of a
+// connection's successful tests since the given time, limited to results of
+// the given IP family. It matches storage.Storage.GetServerDownloads.
+type ServerHistory func(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error)
+
+// SetServerHistory sets the source of historical results used by the
+// history server selection. Without it, servers are selected by latency.
+func (m *MultiWANRunner) SetServerHistory(history ServerHistory) {
+	m.runner.history = history
+}
+
+// selectByHistory picks the server with the best historical download for the
+// connection among the servers whose latency is within
+// speedtest.selection_latency_margin of the lowest one. This keeps a
+// connection on a consistently good server instead of flapping between
+// servers that are fast to ping but throttle throughput. Returns nil if
+// history selection is disabled or no candidate has history, so the caller
+// falls back to the lowest latency.
+func (r *Runner) selectByHistory(ctx context.Context, conn WANConnection, servers speedtest.Servers) *speedtest.Server {
+	if r.config.ServerSelection != ServerSelectionHistory || r.history == nil {
+		return nil
+	}
+
+	// Servers that didn't answer the ping have a latency <= 0
+	var minLatency time.Duration
+	for _, s := range servers {
+		if s.Latency > 0 && (minLatency == 0 || s.Latency < minLatency) {
+			minLatency = s.Latency
+		}
+	}
+	if minLatency == 0 {
+		return nil
+	}
+
+	history, err := r.history(ctx, conn.Name, conn.Family, time.Now().Add(-selectionHistoryPeriod))
+	if err != nil {
+		r.logger.Warn("Failed to load server history, selecting by latency",
+			zap.String("connection", conn.Name),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	var best *speedtest.Server
+	var bestDownload float64
+	for _, s := range servers {
+		if s.Latency <= 0 || s.Latency > minLatency+r.config.SelectionLatencyMargin {
+			continue
+		}
+		download, ok := history[parseServerID(s.ID)]
+		if ok && download > bestDownload {
+			best = s
+			bestDownload = download
+		}
+	}
+
+	if best != nil {
+		r.logger.Debug("Selected server by history",
+			zap.String("connection", conn.Name),
+			zap.String("server_id", best.ID),
+			zap.Duration("latency", best.Latency),
+			zap.Duration("min_latency", minLatency),
+			zap.Float64("avg_download_mbps", bestDownload),
+		)
+	}
+	return best
+}
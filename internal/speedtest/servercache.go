@@ -0,0 +1,73 @@
+package speedtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/showwin/speedtest-go/speedtest"
+	"go.uber.org/zap"
+)
+
+// serverCache caches the fetched server list for a TTL, so the connections
+// of a multi-WAN run share one fetch instead of each querying speedtest.net.
+// The server latencies are those measured by the connection that fetched
+// the list.
+type serverCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	servers speedtest.Servers
+	fetched time.Time
+	logger  *zap.Logger
+}
+
+// newServerCache creates a new cache. A ttl <= 0 disables it.
+func newServerCache(ttl time.Duration, logger *zap.Logger) *serverCache {
+	return &serverCache{
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// fetch returns the server list, from the cache if it was fetched within
+// the TTL. A failed fetch invalidates the cache.
+func (c *serverCache) fetch(ctx context.Context, client *speedtest.Speedtest) (speedtest.Servers, error) {
+	if c.ttl <= 0 {
+		return client.FetchServerListContext(ctx)
+	}
+
+	// Held during the fetch, so concurrent runs wait for it instead of
+	// fetching the list themselves
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.servers != nil && time.Since(c.fetched) < c.ttl {
+		c.logger.Debug("Using cached speedtest servers",
+			zap.Int("servers", len(c.servers)),
+			zap.Duration("age", time.Since(c.fetched)),
+		)
+		return copyServers(c.servers, client), nil
+	}
+
+	servers, err := client.FetchServerListContext(ctx)
+	if err != nil {
+		c.servers = nil
+		return nil, err
+	}
+	c.servers = copyServers(servers, nil)
+	c.fetched = time.Now()
+	return servers, nil
+}
+
+// copyServers returns copies of servers bound to client. speedtest-go runs
+// the tests of a server through the client stored in it, and records their
+// results in the server, so cached servers must not be shared between runs.
+func copyServers(servers speedtest.Servers, client *speedtest.Speedtest) speedtest.Servers {
+	copies := make(speedtest.Servers, len(servers))
+	for i, s := range servers {
+		server := *s
+		server.Context = client
+		copies[i] = &server
+	}
+	return copies
+}
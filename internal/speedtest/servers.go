@@ -0,0 +1,64 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/showwin/speedtest-go/speedtest"
+)
+
+// ServerInfo describes a server of the speedtest server list.
+type ServerInfo struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Sponsor string `json:"sponsor,omitempty"`
+	Country string `json:"country"`
+	Host    string `json:"host"`
+	// DistanceKm is the great-circle distance to the server
+	DistanceKm float64 `json:"distance_km"`
+	// LatencyMs is the latency measured while fetching the list (0 if the
+	// server didn't respond)
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+}
+
+// FetchServers fetches the server list through the source binding of conn,
+// sorted by distance. Unlike test runs, it bypasses the server cache, so the
+// latencies are those of conn.
+func (r *Runner) FetchServers(ctx context.Context, conn WANConnection) ([]ServerInfo, error) {
+	dialer, err := NewDSCPDialer(conn.DSCP, conn.SourceIP, r.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DSCP dialer: %w", err)
+	}
+	dialer.Interface = conn.Interface
+	dialer.Family = conn.Family
+
+	sourceIP, err := dialer.LocalIP()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve source IP: %w", err)
+	}
+
+	client, _ := r.newClient(dialer, sourceIP)
+	servers, err := client.FetchServerListContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servers: %w", err)
+	}
+
+	infos := make([]ServerInfo, 0, len(servers))
+	for _, s := range servers {
+		id, _ := strconv.Atoi(s.ID)
+		info := ServerInfo{
+			ID:         id,
+			Name:       s.Name,
+			Sponsor:    s.Sponsor,
+			Country:    s.Country,
+			Host:       s.Host,
+			DistanceKm: s.Distance,
+		}
+		if s.Latency > 0 && s.Latency != speedtest.PingTimeout {
+			info.LatencyMs = float64(s.Latency.Milliseconds())
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,50 @@
+package speedtest
+
+import (
+	"sync"
+	"time"
+)
+
+// warmupMeter measures the rate of a download or upload transfer after a
+// warmup period, from the cumulative bytes transferred by the client.
+// speedtest-go has no option to discard its initial samples, so the rate it
+// reports includes the TCP slow-start ramp-up.
+type warmupMeter struct {
+	// total returns the bytes transferred so far in the measured direction
+	total func() int64
+	timer *time.Timer
+
+	mu         sync.Mutex
+	startBytes int64
+	startTime  time.Time
+}
+
+// startWarmupMeter starts a meter whose measurement begins after warmup.
+func startWarmupMeter(warmup time.Duration, total func() int64) *warmupMeter {
+	m := &warmupMeter{total: total}
+	m.timer = time.AfterFunc(warmup, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.startBytes = total()
+		m.startTime = time.Now()
+	})
+	return m
+}
+
+// stop ends the measurement and returns the rate in Mbps since the warmup,
+// or false if the transfer ended before the warmup did.
+func (m *warmupMeter) stop() (float64, bool) {
+	m.timer.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.startTime.IsZero() {
+		return 0, false
+	}
+	elapsed := time.Since(m.startTime).Seconds()
+	bytes := m.total() - m.startBytes
+	if elapsed <= 0 || bytes <= 0 {
+		return 0, false
+	}
+	return float64(bytes) * 8 / elapsed / 1e6, true
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// Baseline is the typical speed of a connection at one hour of day: the
+// median of its successful tests in that hour over a period.
+type Baseline struct {
+	// Hour is the hour of day (0-23) in the timezone of the query
+	Hour int `json:"hour"`
+	// DownloadMbps and UploadMbps are the medians (0 without samples)
+	DownloadMbps float64 `json:"download_mbps"`
+	UploadMbps   float64 `json:"upload_mbps"`
+	// DownloadSamples and UploadSamples are the number of values the
+	// medians were computed from
+	DownloadSamples int `json:"download_samples"`
+	UploadSamples   int `json:"upload_samples"`
+}
+
+// scanBaseline reads the rows of a baseline query and returns the medians.
+// Columns: download_mbps, download_ok, upload_mbps, upload_ok.
+func scanBaseline(rows *sql.Rows, hour int) (*Baseline, error) {
+	var downloads, uploads []float64
+	for rows.Next() {
+		var download, upload float64
+		var downloadOK, uploadOK bool
+		if err := rows.Scan(&download, &downloadOK, &upload, &uploadOK); err != nil {
+			return nil, fmt.Errorf("failed to scan baseline sample: %w", err)
+		}
+		if downloadOK {
+			downloads = append(downloads, download)
+		}
+		if uploadOK {
+			uploads = append(uploads, upload)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating baseline samples: %w", err)
+	}
+
+	return &Baseline{
+		Hour:            hour,
+		DownloadMbps:    median(downloads),
+		UploadMbps:      median(uploads),
+		DownloadSamples: len(downloads),
+		UploadSamples:   len(uploads),
+	}, nil
+}
+
+// median returns the median of values, or 0 if there are none. values is
+// sorted in place.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
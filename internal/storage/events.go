@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ConnectionEvent records a change of a connection's health state, e.g. from
+// "up" to "down", for an outage timeline.
+type ConnectionEvent struct {
+	ID             int64  `json:"id"`
+	ConnectionName string `json:"connection_name"`
+	// IPFamily is "ipv4" or "ipv6" for dual-stack connections (empty otherwise)
+	IPFamily string `json:"ip_family,omitempty"`
+	// FromState is empty for the first event of a connection
+	FromState string `json:"from_state,omitempty"`
+	ToState   string `json:"to_state"`
+	// Reason describes the result that caused the change
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InLocation converts the event's timestamp to the given location.
+func (e *ConnectionEvent) InLocation(loc *time.Location) {
+	e.CreatedAt = e.CreatedAt.In(loc)
+}
+
+// scanConnectionEvents reads the rows of an events query. Columns: id,
+// connection_name, ip_family, from_state, to_state, reason, created_at.
+func scanConnectionEvents(rows *sql.Rows) ([]ConnectionEvent, error) {
+	events := []ConnectionEvent{}
+	for rows.Next() {
+		var e ConnectionEvent
+		if err := rows.Scan(&e.ID, &e.ConnectionName, &e.IPFamily, &e.FromState, &e.ToState, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan connection event: %w", err)
+		}
+		e.CreatedAt = utc(e.CreatedAt)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating connection events: %w", err)
+	}
+	return events, nil
+}
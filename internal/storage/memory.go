@@ -0,0 +1,553 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+)
+
+// MemoryStorage keeps results and connection events in memory, e.g. for
+// demos, CI and tests. Nothing is persisted: all data is lost when the
+// process exits. Filters and statistics are computed in Go with the same
+// semantics as the SQL backends.
+type MemoryStorage struct {
+	mu sync.RWMutex
+	// results are ordered by created_at, then ID (the order they are
+	// dropped in when maxResults is exceeded)
+	results []TestResult
+	events  []ConnectionEvent
+	rollups map[rollupKey]*hourlyRollup
+	// thinLevels are the thinning levels of results by ID (see ThinResults)
+	thinLevels map[int64]int
+
+	lastResultID int64
+	lastEventID  int64
+
+	// maxResults is the maximum number of results kept (0 = unlimited)
+	maxResults int
+}
+
+// NewMemoryStorage creates a new in-memory storage instance.
+func NewMemoryStorage(cfg config.MemoryConfig) *MemoryStorage {
+	return &MemoryStorage{
+		rollups:    make(map[rollupKey]*hourlyRollup),
+		thinLevels: make(map[int64]int),
+		maxResults: cfg.MaxResults,
+	}
+}
+
+// Init does nothing; the in-memory storage needs no setup.
+func (s *MemoryStorage) Init(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing; the results are kept until the process exits.
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// Ping always succeeds.
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SaveResult saves a speedtest result.
+func (s *MemoryStorage) SaveResult(ctx context.Context, result *TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.insertResult(result)
+	s.trimResults()
+	return nil
+}
+
+// SaveResults saves multiple results. Saving to memory can't fail, so
+// either all of them are saved or (on a canceled context) none.
+func (s *MemoryStorage) SaveResults(ctx context.Context, results []*TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, result := range results {
+		s.insertResult(result)
+	}
+	s.trimResults()
+	return nil
+}
+
+// SaveResultsAtomic saves multiple results, like SaveResults.
+func (s *MemoryStorage) SaveResultsAtomic(ctx context.Context, results []*TestResult) error {
+	return s.SaveResults(ctx, results)
+}
+
+// insertResult assigns the next ID to result and inserts a copy of it,
+// keeping s.results ordered. Must be called with s.mu held.
+func (s *MemoryStorage) insertResult(result *TestResult) {
+	s.lastResultID++
+	result.ID = s.lastResultID
+
+	r := *result
+	r.CreatedAt = utc(r.CreatedAt)
+
+	// Results are usually saved in order, so this is mostly an append
+	i := sort.Search(len(s.results), func(i int) bool {
+		return s.results[i].CreatedAt.After(r.CreatedAt)
+	})
+	s.results = append(s.results, TestResult{})
+	copy(s.results[i+1:], s.results[i:])
+	s.results[i] = r
+}
+
+// trimResults drops the oldest results beyond maxResults. Must be called
+// with s.mu held.
+func (s *MemoryStorage) trimResults() {
+	if s.maxResults <= 0 || len(s.results) <= s.maxResults {
+		return
+	}
+	s.results = append([]TestResult(nil), s.results[len(s.results)-s.maxResults:]...)
+}
+
+// GetResult retrieves a single result by ID.
+func (s *MemoryStorage) GetResult(ctx context.Context, id int64) (*TestResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.results {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("result not found: %d", id)
+}
+
+// GetResults retrieves results based on filter criteria, newest first.
+func (s *MemoryStorage) GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := s.filterResults(filter)
+	if filter.Offset > 0 {
+		if filter.Offset >= len(results) {
+			return []TestResult{}, nil
+		}
+		results = results[filter.Offset:]
+	}
+	if limit := clampLimit(filter.Limit); len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// GetResultsCursor retrieves results with an ID below beforeID (0 = from the
+// newest result), ordered by ID descending.
+func (s *MemoryStorage) GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.filterResults(filter)
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID > matched[j].ID
+	})
+
+	results := []TestResult{}
+	limit := clampLimit(filter.Limit)
+	for _, r := range matched {
+		if len(results) == limit {
+			break
+		}
+		if beforeID > 0 && r.ID >= beforeID {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// filterResults returns copies of the results matching filter (ignoring
+// limit and offset), newest first. Must be called with s.mu held.
+func (s *MemoryStorage) filterResults(filter ResultFilter) []TestResult {
+	var names map[string]bool
+	if len(filter.ConnectionNames) > 0 {
+		names = make(map[string]bool, len(filter.ConnectionNames))
+		for _, name := range filter.ConnectionNames {
+			names[name] = true
+		}
+	}
+	since, until := utc(filter.Since), utc(filter.Until)
+
+	results := []TestResult{}
+	for i := len(s.results) - 1; i >= 0; i-- {
+		r := s.results[i]
+		if filter.ConnectionName != "" && r.ConnectionName != filter.ConnectionName {
+			continue
+		}
+		if names != nil && !names[r.ConnectionName] {
+			continue
+		}
+		if !filter.Since.IsZero() && r.CreatedAt.Before(since) {
+			continue
+		}
+		if !filter.Until.IsZero() && r.CreatedAt.After(until) {
+			continue
+		}
+		if filter.ErrorOnly != nil && r.IsError() != *filter.ErrorOnly {
+			continue
+		}
+		if filter.ConfigHash != "" && r.ConfigHash != filter.ConfigHash {
+			continue
+		}
+		if filter.Source != "" && r.Source != filter.Source {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// GetLatestResults retrieves the most recent result for each connection,
+// ordered by connection name.
+func (s *MemoryStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	latest := make(map[string]TestResult)
+	for _, r := range s.results {
+		latest[r.ConnectionName] = r
+	}
+
+	results := make([]TestResult, 0, len(latest))
+	for _, r := range latest {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ConnectionName < results[j].ConnectionName
+	})
+	return results, nil
+}
+
+// GetLatestResult retrieves the most recent result of any connection, or nil
+// if there are no results.
+func (s *MemoryStorage) GetLatestResult(ctx context.Context) (*TestResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.results) == 0 {
+		return nil, nil
+	}
+	r := s.results[len(s.results)-1]
+	return &r, nil
+}
+
+// resultsBetween returns the results of a connection created between since
+// and until (inclusive; zero = no upper bound), oldest first. Must be called
+// with s.mu held.
+func (s *MemoryStorage) resultsBetween(connectionName string, since, until time.Time) []TestResult {
+	var results []TestResult
+	for _, r := range s.results {
+		if r.ConnectionName != connectionName || r.CreatedAt.Before(since) || (!until.IsZero() && r.CreatedAt.After(until)) {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// GetStats calculates statistics for a connection over a time period.
+func (s *MemoryStorage) GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error) {
+	until := utc(time.Now())
+	return s.GetStatsBetween(ctx, connectionName, until.Add(-period), until)
+}
+
+// GetStatsBetween calculates statistics for a connection between since and
+// until.
+func (s *MemoryStorage) GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error) {
+	since, until = utc(since), utc(until)
+	period := until.Sub(since)
+
+	s.mu.RLock()
+	results := s.resultsBetween(connectionName, since, until)
+	rollups := s.rollupsBetween(connectionName, since, until)
+	s.mu.RUnlock()
+
+	var m metricAggregates
+	for _, r := range rollups {
+		m.merge(r.metricAggregates)
+	}
+	for _, r := range results {
+		m.add(r)
+	}
+
+	stats := &Stats{
+		ConnectionName: connectionName,
+		Period:         period,
+		Since:          since,
+		Until:          until,
+	}
+	stats.setAggregates(m)
+
+	return stats, nil
+}
+
+// GetTrends returns the statistics of a connection over the given period,
+// bucketed by groupBy (TrendHourOfDay or TrendDayOfWeek) in the timezone loc.
+// Unlike the SQL backends, each result is bucketed with the UTC offset in
+// effect at its own time, so DST changes don't shift buckets.
+func (s *MemoryStorage) GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error) {
+	if !IsValidTrendGrouping(groupBy) {
+		return nil, fmt.Errorf("unknown trend grouping: %s", groupBy)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	until := utc(time.Now())
+	since := until.Add(-period)
+
+	s.mu.RLock()
+	results := s.resultsBetween(connectionName, since, until)
+	rollups := s.rollupsBetween(connectionName, since, until)
+	s.mu.RUnlock()
+
+	aggregates := make([]metricAggregates, trendBucketCount(groupBy))
+	for _, r := range rollups {
+		aggregates[trendBucket(groupBy, r.hour.In(loc))].merge(r.metricAggregates)
+	}
+	for _, r := range results {
+		aggregates[trendBucket(groupBy, r.CreatedAt.In(loc))].add(r)
+	}
+
+	buckets := make([]TrendBucket, len(aggregates))
+	for i, m := range aggregates {
+		buckets[i].Bucket = i
+		buckets[i].setAggregates(m)
+	}
+	return buckets, nil
+}
+
+// GetServerDownloads returns the average download in Mbps per server ID of
+// a connection's successful tests since the given time, limited to results of
+// the given IP family.
+func (s *MemoryStorage) GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error) {
+	s.mu.RLock()
+	results := s.resultsBetween(connectionName, utc(since), time.Time{})
+	s.mu.RUnlock()
+
+	servers := make(map[int]*aggregate)
+	for _, r := range results {
+		if r.IPFamily != ipFamily || r.IsError() || !r.DownloadOK || r.ServerID <= 0 {
+			continue
+		}
+		if servers[r.ServerID] == nil {
+			servers[r.ServerID] = &aggregate{}
+		}
+		servers[r.ServerID].add(r.DownloadMbps, r.CreatedAt)
+	}
+
+	downloads := make(map[int]float64, len(servers))
+	for id, a := range servers {
+		downloads[id] = a.avg()
+	}
+	return downloads, nil
+}
+
+// GetBaseline returns the median download and upload of a connection's
+// successful tests since the given time at the given hour of day in the
+// timezone loc, limited to results of the given IP family.
+func (s *MemoryStorage) GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	s.mu.RLock()
+	results := s.resultsBetween(connectionName, utc(since), time.Time{})
+	s.mu.RUnlock()
+
+	var downloads, uploads []float64
+	for _, r := range results {
+		if r.IPFamily != ipFamily || r.IsError() || r.CreatedAt.In(loc).Hour() != hour {
+			continue
+		}
+		if r.DownloadOK {
+			downloads = append(downloads, r.DownloadMbps)
+		}
+		if r.UploadOK {
+			uploads = append(uploads, r.UploadMbps)
+		}
+	}
+
+	return &Baseline{
+		Hour:            hour,
+		DownloadMbps:    median(downloads),
+		UploadMbps:      median(uploads),
+		DownloadSamples: len(downloads),
+		UploadSamples:   len(uploads),
+	}, nil
+}
+
+// SaveConnectionEvent saves a connection health event.
+func (s *MemoryStorage) SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastEventID++
+	event.ID = s.lastEventID
+
+	e := *event
+	e.CreatedAt = utc(e.CreatedAt)
+	s.events = append(s.events, e)
+	return nil
+}
+
+// GetConnectionEvents returns a connection's health events since the given
+// time (zero = all), newest first, at most limit (0 = no limit).
+func (s *MemoryStorage) GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []ConnectionEvent{}
+	for _, e := range s.events {
+		if e.ConnectionName != connectionName || (!since.IsZero() && e.CreatedAt.Before(utc(since))) {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.After(events[j].CreatedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// isOld returns true if r is older than olderThan and belongs to the given
+// connection (empty matches all connections).
+func isOld(r TestResult, olderThan time.Time, connectionName string) bool {
+	return r.CreatedAt.Before(olderThan) && (connectionName == "" || r.ConnectionName == connectionName)
+}
+
+// CountOldResults returns the number of results older than the specified time,
+// optionally limited to a single connection.
+func (s *MemoryStorage) CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, r := range s.results {
+		if isOld(r, utc(olderThan), connectionName) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteOldResults removes results older than the specified time,
+// optionally limited to a single connection.
+func (s *MemoryStorage) DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.results[:0]
+	for _, r := range s.results {
+		if !isOld(r, utc(olderThan), connectionName) {
+			kept = append(kept, r)
+		}
+	}
+	count := int64(len(s.results) - len(kept))
+	s.results = kept
+	return count, nil
+}
+
+// rollupsBetween returns a connection's rollups of the hours starting in
+// [since, until], oldest first. Must be called with s.mu held.
+func (s *MemoryStorage) rollupsBetween(connectionName string, since, until time.Time) []hourlyRollup {
+	var rollups []hourlyRollup
+	for key, r := range s.rollups {
+		if key.connectionName == connectionName && !key.hour.Before(since) && !key.hour.After(until) {
+			rollups = append(rollups, *r)
+		}
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		return rollups[i].hour.Before(rollups[j].hour)
+	})
+	return rollups
+}
+
+// RollupResults aggregates all results created before the start of the
+// hour of olderThan into hourly rollups and deletes them.
+func (s *MemoryStorage) RollupResults(ctx context.Context, olderThan time.Time) (int64, error) {
+	cutoff := utc(olderThan).Truncate(time.Hour)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// s.results is ordered by created_at
+	n := sort.Search(len(s.results), func(i int) bool {
+		return !s.results[i].CreatedAt.Before(cutoff)
+	})
+	for _, r := range s.results[:n] {
+		addRollup(s.rollups, r)
+	}
+	s.results = append([]TestResult(nil), s.results[n:]...)
+	return int64(n), nil
+}
+
+// DeleteOldRollups removes rollups of hours before the specified time.
+func (s *MemoryStorage) DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.rollups {
+		if key.hour.Before(olderThan) {
+			delete(s.rollups, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ThinResults keeps every keepEvery-th result of each connection and IP
+// family created before olderThan that hasn't been thinned to level yet,
+// and deletes the others.
+func (s *MemoryStorage) ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error) {
+	cutoff := utc(olderThan)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := newThinCounter(keepEvery)
+	kept := s.results[:0]
+	levels := make(map[int64]int)
+	for _, r := range s.results {
+		thinned := s.thinLevels[r.ID]
+		if r.CreatedAt.Before(cutoff) && thinned < level {
+			if !counter.keep(r.ConnectionName, r.IPFamily) {
+				continue
+			}
+			thinned = level
+		}
+		kept = append(kept, r)
+		// Levels of results dropped otherwise are forgotten here
+		if thinned > 0 {
+			levels[r.ID] = thinned
+		}
+	}
+	count := int64(len(s.results) - len(kept))
+	s.results = kept
+	s.thinLevels = levels
+	return count, nil
+}
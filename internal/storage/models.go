@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"math"
 	"time"
 
 	"github.com/lan-dot-party/flowgauge/internal/speedtest"
@@ -19,32 +20,87 @@ type TestResult struct {
 	DownloadMbps   float64   `json:"download_mbps"`
 	UploadMbps     float64   `json:"upload_mbps"`
 	PacketLossPct  float64   `json:"packet_loss_pct,omitempty"`
+	LatencyOK      bool      `json:"latency_ok"`
+	DownloadOK     bool      `json:"download_ok"`
+	UploadOK       bool      `json:"upload_ok"`
 	SourceIP       string    `json:"source_ip,omitempty"`
 	DSCP           int       `json:"dscp"`
 	Error          string    `json:"error,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
+	// ServerDistanceKm is the distance between client and test server (0 if unknown)
+	ServerDistanceKm float64 `json:"server_distance_km,omitempty"`
+	// Attempts is the number of attempts the test took (1 = first try)
+	Attempts int `json:"attempts"`
+	// BytesDownloaded and BytesUploaded are the bytes the test transferred
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+	// IPFamily is "ipv4" or "ipv6" for tests of dual-stack connections, which
+	// are tested once per address family (empty otherwise)
+	IPFamily string `json:"ip_family,omitempty"`
+	// ConfigHash identifies the connection and speedtest settings the result
+	// was produced with (empty for aggregates and results of older versions)
+	ConfigHash string `json:"config_hash,omitempty"`
+	// ConnectMs is the TCP connect time to the test server (0 if unknown)
+	ConnectMs float64 `json:"connect_ms,omitempty"`
+	// DownloadSize and UploadSize are the transfer sizes per request the
+	// result was produced with (empty for skipped phases, aggregates and
+	// results of older versions)
+	DownloadSize string `json:"download_size,omitempty"`
+	UploadSize   string `json:"upload_size,omitempty"`
+	// Source is what started the test: scheduled, api or cli (empty for
+	// results of older versions)
+	Source string `json:"source,omitempty"`
+	// Threads is the number of concurrent connections of the download and
+	// upload phases (0 for aggregates and results of older versions)
+	Threads int `json:"threads,omitempty"`
 }
 
-// FromSpeedtestResult converts a speedtest.Result to a storage TestResult.
-func FromSpeedtestResult(r *speedtest.Result) *TestResult {
+// FromSpeedtestResult converts a speedtest.Result to a storage TestResult,
+// rounding measured values to the given number of decimals (negative keeps
+// full precision).
+func FromSpeedtestResult(r *speedtest.Result, decimals int) *TestResult {
 	return &TestResult{
 		ConnectionName: r.ConnectionName,
 		ServerID:       r.ServerID,
 		ServerName:     r.ServerName,
 		ServerCountry:  r.ServerCountry,
 		ServerHost:     r.ServerHost,
-		LatencyMs:      r.LatencyMs,
-		JitterMs:       r.JitterMs,
-		DownloadMbps:   r.DownloadMbps,
-		UploadMbps:     r.UploadMbps,
-		PacketLossPct:  r.PacketLossPct,
+		LatencyMs:      round(r.LatencyMs, decimals),
+		JitterMs:       round(r.JitterMs, decimals),
+		DownloadMbps:   round(r.DownloadMbps, decimals),
+		UploadMbps:     round(r.UploadMbps, decimals),
+		PacketLossPct:  round(r.PacketLossPct, decimals),
+		LatencyOK:      r.LatencyOK,
+		DownloadOK:     r.DownloadOK,
+		UploadOK:       r.UploadOK,
 		SourceIP:       r.SourceIP,
 		DSCP:           r.DSCP,
 		Error:          r.Error,
 		CreatedAt:      r.Timestamp,
+
+		ServerDistanceKm: round(r.ServerDistanceKm, decimals),
+		Attempts:         r.Attempts,
+		BytesDownloaded:  r.BytesDownloaded,
+		BytesUploaded:    r.BytesUploaded,
+		IPFamily:         r.IPFamily,
+		ConfigHash:       r.ConfigHash,
+		ConnectMs:        round(r.ConnectMs, decimals),
+		DownloadSize:     r.DownloadSize,
+		UploadSize:       r.UploadSize,
+		Source:           r.Source,
+		Threads:          r.Threads,
 	}
 }
 
+// round rounds v to the given number of decimals (negative keeps v as is).
+func round(v float64, decimals int) float64 {
+	if decimals < 0 {
+		return v
+	}
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(v*factor) / factor
+}
+
 // ToSpeedtestResult converts a storage TestResult to a speedtest.Result.
 func (r *TestResult) ToSpeedtestResult() *speedtest.Result {
 	return &speedtest.Result{
@@ -58,10 +114,25 @@ func (r *TestResult) ToSpeedtestResult() *speedtest.Result {
 		DownloadMbps:   r.DownloadMbps,
 		UploadMbps:     r.UploadMbps,
 		PacketLossPct:  r.PacketLossPct,
+		LatencyOK:      r.LatencyOK,
+		DownloadOK:     r.DownloadOK,
+		UploadOK:       r.UploadOK,
 		SourceIP:       r.SourceIP,
 		DSCP:           r.DSCP,
 		Error:          r.Error,
 		Timestamp:      r.CreatedAt,
+
+		ServerDistanceKm: r.ServerDistanceKm,
+		Attempts:         r.Attempts,
+		BytesDownloaded:  r.BytesDownloaded,
+		BytesUploaded:    r.BytesUploaded,
+		IPFamily:         r.IPFamily,
+		ConfigHash:       r.ConfigHash,
+		ConnectMs:        r.ConnectMs,
+		DownloadSize:     r.DownloadSize,
+		UploadSize:       r.UploadSize,
+		Source:           r.Source,
+		Threads:          r.Threads,
 	}
 }
 
@@ -70,3 +141,8 @@ func (r *TestResult) IsError() bool {
 	return r.Error != ""
 }
 
+// InLocation converts the result's timestamps to the given location.
+func (r *TestResult) InLocation(loc *time.Location) {
+	r.CreatedAt = r.CreatedAt.In(loc)
+}
+
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/logger"
+)
+
+// MultiStorage writes results to a primary and any number of secondary
+// backends (e.g. a local SQLite database and a central PostgreSQL server).
+// All reads, statistics and cleanup use the primary only.
+// Failing secondary writes are logged but never fail the primary write.
+type MultiStorage struct {
+	primary     Storage
+	secondaries []*secondaryBackend
+}
+
+// secondaryBackend is an additional backend of a MultiStorage.
+type secondaryBackend struct {
+	name  string
+	store Storage
+
+	mu    sync.Mutex
+	ready bool
+}
+
+// NewMultiStorage creates a MultiStorage that writes to primary and to the
+// given additional backends. backup enables storage.backup_before_migrate
+// for the additional backends.
+func NewMultiStorage(primary Storage, backends []config.StorageBackendConfig, backup bool) (*MultiStorage, error) {
+	m := &MultiStorage{primary: primary}
+	for _, b := range backends {
+		store, err := newBackend(b, backup)
+		if err != nil {
+			_ = m.closeSecondaries()
+			return nil, fmt.Errorf("failed to create %s backend: %w", backendName(b), err)
+		}
+		m.secondaries = append(m.secondaries, &secondaryBackend{
+			name:  backendName(b),
+			store: store,
+		})
+	}
+	return m, nil
+}
+
+// backendName describes a backend for log messages without exposing credentials.
+func backendName(b config.StorageBackendConfig) string {
+	switch b.Type {
+	case "postgres":
+		return fmt.Sprintf("postgres %s:%d/%s", b.Postgres.Host, b.Postgres.Port, b.Postgres.Database)
+	case "memory":
+		return "memory"
+	}
+	return fmt.Sprintf("%s %s", b.Type, b.SQLite.Path)
+}
+
+// Init initializes all backends. Only an error of the primary is returned;
+// secondaries that fail to initialize are retried on the next write.
+func (m *MultiStorage) Init(ctx context.Context) error {
+	if err := m.primary.Init(ctx); err != nil {
+		return err
+	}
+	for _, sb := range m.secondaries {
+		sb.init(ctx)
+	}
+	return nil
+}
+
+// init initializes the backend if that hasn't succeeded yet.
+// Returns whether the backend is ready for writes.
+func (sb *secondaryBackend) init(ctx context.Context) bool {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if sb.ready {
+		return true
+	}
+	if err := sb.store.Init(ctx); err != nil {
+		// Release the connection pool before the next attempt
+		_ = sb.store.Close()
+		logger.Warn("Failed to initialize secondary storage backend",
+			zap.String("backend", sb.name),
+			zap.Error(err),
+		)
+		return false
+	}
+	sb.ready = true
+	return true
+}
+
+// Close closes all backends.
+func (m *MultiStorage) Close() error {
+	return errors.Join(m.primary.Close(), m.closeSecondaries())
+}
+
+func (m *MultiStorage) closeSecondaries() error {
+	var errs []error
+	for _, sb := range m.secondaries {
+		if err := sb.store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sb.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ping checks that the primary database is reachable.
+func (m *MultiStorage) Ping(ctx context.Context) error {
+	return m.primary.Ping(ctx)
+}
+
+// SaveResult saves a result to the primary and then to all secondaries.
+// result.ID is set by the primary.
+func (m *MultiStorage) SaveResult(ctx context.Context, result *TestResult) error {
+	if err := m.primary.SaveResult(ctx, result); err != nil {
+		return err
+	}
+	for _, sb := range m.secondaries {
+		if !sb.init(ctx) {
+			continue
+		}
+		// Secondaries assign their own IDs; don't overwrite the primary's
+		copied := *result
+		if err := sb.store.SaveResult(ctx, &copied); err != nil {
+			logSecondaryError(sb, 1, err)
+		}
+	}
+	return nil
+}
+
+// SaveResults saves results to the primary and then to all secondaries.
+func (m *MultiStorage) SaveResults(ctx context.Context, results []*TestResult) error {
+	return m.saveResults(ctx, results, Storage.SaveResults)
+}
+
+// SaveResultsAtomic saves results to the primary and then to all
+// secondaries. Each backend saves all results or none; a failed secondary
+// doesn't undo the save to the primary.
+func (m *MultiStorage) SaveResultsAtomic(ctx context.Context, results []*TestResult) error {
+	return m.saveResults(ctx, results, Storage.SaveResultsAtomic)
+}
+
+// saveResults saves results with save to the primary and then to all
+// secondaries.
+func (m *MultiStorage) saveResults(ctx context.Context, results []*TestResult, save func(Storage, context.Context, []*TestResult) error) error {
+	if err := save(m.primary, ctx, results); err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	for _, sb := range m.secondaries {
+		if !sb.init(ctx) {
+			continue
+		}
+		copied := make([]*TestResult, len(results))
+		for i, r := range results {
+			c := *r
+			copied[i] = &c
+		}
+		if err := save(sb.store, ctx, copied); err != nil {
+			logSecondaryError(sb, len(results), err)
+		}
+	}
+	return nil
+}
+
+func logSecondaryError(sb *secondaryBackend, count int, err error) {
+	logger.Warn("Failed to save results to secondary storage backend",
+		zap.String("backend", sb.name),
+		zap.Int("results", count),
+		zap.Error(err),
+	)
+}
+
+// SaveConnectionEvent saves an event to the primary and then to all
+// secondaries. event.ID is set by the primary.
+func (m *MultiStorage) SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	if err := m.primary.SaveConnectionEvent(ctx, event); err != nil {
+		return err
+	}
+	for _, sb := range m.secondaries {
+		if !sb.init(ctx) {
+			continue
+		}
+		copied := *event
+		if err := sb.store.SaveConnectionEvent(ctx, &copied); err != nil {
+			logger.Warn("Failed to save connection event to secondary storage backend",
+				zap.String("backend", sb.name),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// GetConnectionEvents returns the events from the primary.
+func (m *MultiStorage) GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error) {
+	return m.primary.GetConnectionEvents(ctx, connectionName, since, limit)
+}
+
+// GetResult retrieves a result from the primary.
+func (m *MultiStorage) GetResult(ctx context.Context, id int64) (*TestResult, error) {
+	return m.primary.GetResult(ctx, id)
+}
+
+// GetResults retrieves results from the primary.
+func (m *MultiStorage) GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error) {
+	return m.primary.GetResults(ctx, filter)
+}
+
+// GetResultsCursor retrieves a page of results from the primary.
+func (m *MultiStorage) GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error) {
+	return m.primary.GetResultsCursor(ctx, filter, beforeID)
+}
+
+// GetLatestResults retrieves the latest result per connection from the primary.
+func (m *MultiStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
+	return m.primary.GetLatestResults(ctx)
+}
+
+// GetLatestResult retrieves the most recent result from the primary.
+func (m *MultiStorage) GetLatestResult(ctx context.Context) (*TestResult, error) {
+	return m.primary.GetLatestResult(ctx)
+}
+
+// GetStats calculates statistics from the primary.
+func (m *MultiStorage) GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error) {
+	return m.primary.GetStats(ctx, connectionName, period)
+}
+
+// GetStatsBetween calculates statistics from the primary.
+func (m *MultiStorage) GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error) {
+	return m.primary.GetStatsBetween(ctx, connectionName, since, until)
+}
+
+// GetTrends calculates trends from the primary.
+func (m *MultiStorage) GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error) {
+	return m.primary.GetTrends(ctx, connectionName, groupBy, period, loc)
+}
+
+// GetServerDownloads returns the per-server downloads from the primary.
+func (m *MultiStorage) GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error) {
+	return m.primary.GetServerDownloads(ctx, connectionName, ipFamily, since)
+}
+
+// GetBaseline returns the baseline from the primary.
+func (m *MultiStorage) GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error) {
+	return m.primary.GetBaseline(ctx, connectionName, ipFamily, hour, since, loc)
+}
+
+// CountOldResults counts old results in the primary.
+func (m *MultiStorage) CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	return m.primary.CountOldResults(ctx, olderThan, connectionName)
+}
+
+// DeleteOldResults deletes old results from the primary only; the retention
+// of secondaries (e.g. a central database) is managed separately.
+func (m *MultiStorage) DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	return m.primary.DeleteOldResults(ctx, olderThan, connectionName)
+}
+
+// RollupResults rolls up old results in the primary only, like
+// DeleteOldResults.
+func (m *MultiStorage) RollupResults(ctx context.Context, olderThan time.Time) (int64, error) {
+	return m.primary.RollupResults(ctx, olderThan)
+}
+
+// DeleteOldRollups deletes old rollups from the primary.
+func (m *MultiStorage) DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error) {
+	return m.primary.DeleteOldRollups(ctx, olderThan)
+}
+
+// ThinResults thins out old results in the primary only, like
+// DeleteOldResults.
+func (m *MultiStorage) ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error) {
+	return m.primary.ThinResults(ctx, olderThan, keepEvery, level)
+}
@@ -3,18 +3,38 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
 
 	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/logger"
 )
 
 // PostgresStorage implements the Storage interface using PostgreSQL.
 type PostgresStorage struct {
 	db  *sql.DB
 	cfg config.PostgresConfig
+
+	// backup is storage.backup_before_migrate, which PostgreSQL can't honor
+	backup bool
+
+	// latestStmt and connectionResultsStmt are the queries the dashboard
+	// polls, prepared in Init so they are parsed and planned only once per
+	// connection. *sql.Stmt is safe for concurrent use.
+	latestStmt            *sql.Stmt
+	connectionResultsStmt *sql.Stmt
+
+	// pending are copies of results saved while the database was
+	// unreachable, oldest first, at most cfg.BufferSize. pendingMu also
+	// serializes result writes, so buffered results are saved first.
+	pendingMu sync.Mutex
+	pending   []*TestResult
 }
 
 // NewPostgresStorage creates a new PostgreSQL storage instance.
@@ -64,14 +84,35 @@ func (s *PostgresStorage) Init(ctx context.Context) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// A file copy doesn't apply to a database server
+	if s.backup {
+		logger.Warn("storage.backup_before_migrate has no effect on PostgreSQL, back up the database with pg_dump",
+			zap.String("database", s.cfg.Database),
+		)
+	}
+
 	// Create schema
 	if err := s.createSchema(ctx); err != nil {
 		return fmt.Errorf("failed to create schema: %w", err)
 	}
 
+	if err := s.prepareStatements(ctx); err != nil {
+		return fmt.Errorf("failed to prepare statements: %w", err)
+	}
+
 	return nil
 }
 
+// prepareStatements prepares the queries polled by the dashboard.
+func (s *PostgresStorage) prepareStatements(ctx context.Context) error {
+	var err error
+	if s.latestStmt, err = s.db.PrepareContext(ctx, latestResultsQueryPostgres); err != nil {
+		return err
+	}
+	s.connectionResultsStmt, err = s.db.PrepareContext(ctx, connectionResultsQueryPostgres)
+	return err
+}
+
 // createSchema creates the database tables if they don't exist.
 func (s *PostgresStorage) createSchema(ctx context.Context) error {
 	schema := `
@@ -90,34 +131,135 @@ func (s *PostgresStorage) createSchema(ctx context.Context) error {
 		source_ip TEXT,
 		dscp INTEGER,
 		error TEXT,
+		server_distance_km DOUBLE PRECISION DEFAULT 0,
+		latency_ok BOOLEAN DEFAULT TRUE,
+		download_ok BOOLEAN DEFAULT TRUE,
+		upload_ok BOOLEAN DEFAULT TRUE,
+		attempts INTEGER DEFAULT 1,
+		bytes_downloaded BIGINT DEFAULT 0,
+		bytes_uploaded BIGINT DEFAULT 0,
+		ip_family TEXT DEFAULT '',
+		config_hash TEXT DEFAULT '',
+		connect_ms DOUBLE PRECISION DEFAULT 0,
+		download_size TEXT DEFAULT '',
+		upload_size TEXT DEFAULT '',
+		source TEXT DEFAULT '',
+		threads INTEGER DEFAULT 0,
+		thin_level INTEGER DEFAULT 0,
 		created_at TIMESTAMPTZ DEFAULT NOW()
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_results_connection ON test_results(connection_name);
 	CREATE INDEX IF NOT EXISTS idx_results_created ON test_results(created_at);
 	CREATE INDEX IF NOT EXISTS idx_results_connection_created ON test_results(connection_name, created_at);
+
+	-- Columns added after the initial schema
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS server_distance_km DOUBLE PRECISION DEFAULT 0;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS latency_ok BOOLEAN DEFAULT TRUE;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS download_ok BOOLEAN DEFAULT TRUE;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS upload_ok BOOLEAN DEFAULT TRUE;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS attempts INTEGER DEFAULT 1;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS bytes_downloaded BIGINT DEFAULT 0;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS bytes_uploaded BIGINT DEFAULT 0;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS ip_family TEXT DEFAULT '';
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS config_hash TEXT DEFAULT '';
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS connect_ms DOUBLE PRECISION DEFAULT 0;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS download_size TEXT DEFAULT '';
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS upload_size TEXT DEFAULT '';
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS source TEXT DEFAULT '';
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS threads INTEGER DEFAULT 0;
+	ALTER TABLE test_results ADD COLUMN IF NOT EXISTS thin_level INTEGER DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS connection_events (
+		id BIGSERIAL PRIMARY KEY,
+		connection_name TEXT NOT NULL,
+		ip_family TEXT DEFAULT '',
+		from_state TEXT DEFAULT '',
+		to_state TEXT NOT NULL,
+		reason TEXT DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_events_connection_created ON connection_events(connection_name, created_at);
+
+	CREATE TABLE IF NOT EXISTS hourly_rollups (
+		connection_name TEXT NOT NULL,
+		hour TIMESTAMPTZ NOT NULL,
+		test_count INTEGER NOT NULL,
+		error_count INTEGER NOT NULL,
+		download_count INTEGER NOT NULL,
+		download_avg DOUBLE PRECISION NOT NULL,
+		download_min DOUBLE PRECISION NOT NULL,
+		download_max DOUBLE PRECISION NOT NULL,
+		upload_count INTEGER NOT NULL,
+		upload_avg DOUBLE PRECISION NOT NULL,
+		upload_min DOUBLE PRECISION NOT NULL,
+		upload_max DOUBLE PRECISION NOT NULL,
+		latency_count INTEGER NOT NULL,
+		latency_avg DOUBLE PRECISION NOT NULL,
+		latency_min DOUBLE PRECISION NOT NULL,
+		latency_max DOUBLE PRECISION NOT NULL,
+		jitter_avg DOUBLE PRECISION NOT NULL,
+		jitter_min DOUBLE PRECISION NOT NULL,
+		jitter_max DOUBLE PRECISION NOT NULL,
+		bytes_downloaded BIGINT NOT NULL,
+		bytes_uploaded BIGINT NOT NULL,
+		PRIMARY KEY (connection_name, hour)
+	);
 	`
 
 	_, err := s.db.ExecContext(ctx, schema)
 	return err
 }
 
-// Close closes the database connection.
+// Close saves buffered results if possible and closes the database
+// connection.
 func (s *PostgresStorage) Close() error {
+	s.flushOnClose()
+	for _, stmt := range []*sql.Stmt{s.latestStmt, s.connectionResultsStmt} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
 	if s.db != nil {
 		return s.db.Close()
 	}
 	return nil
 }
 
-// SaveResult saves a speedtest result to the database.
+// Ping checks that the database is reachable.
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// SaveResult saves a speedtest result to the database. If the database is
+// unreachable, the result is buffered and saved with the next write.
 func (s *PostgresStorage) SaveResult(ctx context.Context, result *TestResult) error {
+	return s.save(ctx, []*TestResult{result}, func() ([]*TestResult, error) {
+		if err := s.insertResult(ctx, result); err != nil {
+			return []*TestResult{result}, err
+		}
+		return nil, nil
+	})
+}
+
+// insertResult inserts a single result and sets its ID.
+func (s *PostgresStorage) insertResult(ctx context.Context, result *TestResult) error {
 	query := `
 	INSERT INTO test_results (
 		connection_name, server_id, server_name, server_country, server_host,
 		latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		source_ip, dscp, error, created_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		source_ip, dscp, error, created_at, server_distance_km,
+		latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
 	RETURNING id
 	`
 
@@ -135,7 +277,21 @@ func (s *PostgresStorage) SaveResult(ctx context.Context, result *TestResult) er
 		result.SourceIP,
 		result.DSCP,
 		result.Error,
-		result.CreatedAt,
+		utc(result.CreatedAt),
+		result.ServerDistanceKm,
+		result.LatencyOK,
+		result.DownloadOK,
+		result.UploadOK,
+		result.Attempts,
+		result.BytesDownloaded,
+		result.BytesUploaded,
+		result.IPFamily,
+		result.ConfigHash,
+		result.ConnectMs,
+		result.DownloadSize,
+		result.UploadSize,
+		result.Source,
+		result.Threads,
 	).Scan(&result.ID)
 
 	if err != nil {
@@ -145,12 +301,208 @@ func (s *PostgresStorage) SaveResult(ctx context.Context, result *TestResult) er
 	return nil
 }
 
+// SaveResults saves multiple results using multi-row INSERT statements of
+// up to saveBatchSize rows. If a batch fails, its rows are saved one at a
+// time. If the database is unreachable, the results are buffered and saved
+// together with the next write.
+func (s *PostgresStorage) SaveResults(ctx context.Context, results []*TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	// Results saved before a lost connection aren't saved again on retry
+	var saved int
+	var errs []error
+	err := s.save(ctx, results, func() ([]*TestResult, error) {
+		for saved < len(results) {
+			end := saved + saveBatchSize
+			if end > len(results) {
+				end = len(results)
+			}
+			n, rowErrs, err := s.insertBatchOrEach(ctx, results[saved:end])
+			saved += n
+			errs = append(errs, rowErrs...)
+			if err != nil {
+				return results[saved:], err
+			}
+		}
+		return nil, nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// insertBatchOrEach inserts a batch of results with one statement, or one
+// at a time if that fails for another reason than a lost connection. It
+// returns how many results it got through, the errors of those that failed
+// on their own, and the error of a lost connection that stopped it early.
+func (s *PostgresStorage) insertBatchOrEach(ctx context.Context, batch []*TestResult) (int, []error, error) {
+	err := s.insertResults(ctx, batch)
+	if err == nil {
+		return len(batch), nil, nil
+	}
+	if isConnectionError(err) {
+		return 0, nil, err
+	}
+
+	var errs []error
+	for i, result := range batch {
+		if err := s.insertResult(ctx, result); err != nil {
+			if isConnectionError(err) {
+				return i, errs, err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return len(batch), errs, nil
+}
+
+// SaveResultsAtomic saves multiple results in a single transaction, so
+// either all of them are saved or none. If the database is unreachable, the
+// results are buffered and saved together with the next write.
+func (s *PostgresStorage) SaveResultsAtomic(ctx context.Context, results []*TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return s.save(ctx, results, func() ([]*TestResult, error) {
+		if err := s.insertResults(ctx, results); err != nil {
+			return results, err
+		}
+		return nil, nil
+	})
+}
+
+// insertResults inserts results in a single transaction using multi-row
+// INSERT statements of up to saveBatchSize rows, and sets their IDs.
+func (s *PostgresStorage) insertResults(ctx context.Context, results []*TestResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids := make([]int64, 0, len(results))
+	for start := 0; start < len(results); start += saveBatchSize {
+		end := start + saveBatchSize
+		if end > len(results) {
+			end = len(results)
+		}
+
+		batchIDs, err := insertBatch(ctx, tx, results[start:end])
+		if err != nil {
+			return err
+		}
+		ids = append(ids, batchIDs...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Only assign IDs once the rows are committed
+	for i, result := range results {
+		result.ID = ids[i]
+	}
+
+	return nil
+}
+
+// insertBatch inserts results with a single multi-row INSERT within tx and
+// returns their IDs.
+func insertBatch(ctx context.Context, tx *sql.Tx, results []*TestResult) ([]int64, error) {
+	const columns = 28
+
+	var query strings.Builder
+	query.WriteString(`
+	INSERT INTO test_results (
+		connection_name, server_id, server_name, server_country, server_host,
+		latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
+		source_ip, dscp, error, created_at, server_distance_km,
+		latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	) VALUES `)
+
+	args := make([]interface{}, 0, len(results)*columns)
+	for i, result := range results {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for c := 0; c < columns; c++ {
+			if c > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", i*columns+c+1)
+		}
+		query.WriteString(")")
+
+		args = append(args,
+			result.ConnectionName,
+			result.ServerID,
+			result.ServerName,
+			result.ServerCountry,
+			result.ServerHost,
+			result.LatencyMs,
+			result.JitterMs,
+			result.DownloadMbps,
+			result.UploadMbps,
+			result.PacketLossPct,
+			result.SourceIP,
+			result.DSCP,
+			result.Error,
+			utc(result.CreatedAt),
+			result.ServerDistanceKm,
+			result.LatencyOK,
+			result.DownloadOK,
+			result.UploadOK,
+			result.Attempts,
+			result.BytesDownloaded,
+			result.BytesUploaded,
+			result.IPFamily,
+			result.ConfigHash,
+			result.ConnectMs,
+			result.DownloadSize,
+			result.UploadSize,
+			result.Source,
+			result.Threads,
+		)
+	}
+	query.WriteString(" RETURNING id")
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert results: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// RETURNING yields rows in VALUES order for a single INSERT
+	ids := make([]int64, 0, len(results))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan inserted ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to insert results: %w", err)
+	}
+	if len(ids) != len(results) {
+		return nil, fmt.Errorf("failed to insert results: got %d IDs for %d rows", len(ids), len(results))
+	}
+
+	return ids, nil
+}
+
 // GetResult retrieves a single result by ID.
 func (s *PostgresStorage) GetResult(ctx context.Context, id int64) (*TestResult, error) {
 	query := `
 	SELECT id, connection_name, server_id, server_name, server_country, server_host,
 		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		   source_ip, dscp, error, created_at
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
 	FROM test_results
 	WHERE id = $1
 	`
@@ -172,6 +524,20 @@ func (s *PostgresStorage) GetResult(ctx context.Context, id int64) (*TestResult,
 		&result.DSCP,
 		&result.Error,
 		&result.CreatedAt,
+		&result.ServerDistanceKm,
+		&result.LatencyOK,
+		&result.DownloadOK,
+		&result.UploadOK,
+		&result.Attempts,
+		&result.BytesDownloaded,
+		&result.BytesUploaded,
+		&result.IPFamily,
+		&result.ConfigHash,
+		&result.ConnectMs,
+		&result.DownloadSize,
+		&result.UploadSize,
+		&result.Source,
+		&result.Threads,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("result not found: %d", id)
@@ -179,16 +545,33 @@ func (s *PostgresStorage) GetResult(ctx context.Context, id int64) (*TestResult,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get result: %w", err)
 	}
+	result.CreatedAt = utc(result.CreatedAt)
 
 	return result, nil
 }
 
 // GetResults retrieves results based on filter criteria.
 func (s *PostgresStorage) GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error) {
+	return s.queryResults(ctx, filter, nil)
+}
+
+// GetResultsCursor retrieves results with an ID below beforeID (0 = from the
+// newest result), ordered by ID descending. Unlike offset pagination, pages
+// stay stable while new results are inserted.
+func (s *PostgresStorage) GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error) {
+	return s.queryResults(ctx, filter, &beforeID)
+}
+
+// queryResults retrieves results matching filter. With a cursor, results are
+// paged by ID (keyset pagination) instead of by created_at and offset.
+func (s *PostgresStorage) queryResults(ctx context.Context, filter ResultFilter, beforeID *int64) ([]TestResult, error) {
 	query := `
 	SELECT id, connection_name, server_id, server_name, server_country, server_host,
 		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		   source_ip, dscp, error, created_at
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
 	FROM test_results
 	WHERE 1=1
 	`
@@ -201,32 +584,77 @@ func (s *PostgresStorage) GetResults(ctx context.Context, filter ResultFilter) (
 		argNum++
 	}
 
+	if len(filter.ConnectionNames) > 0 {
+		placeholders := make([]string, 0, len(filter.ConnectionNames))
+		for _, name := range filter.ConnectionNames {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", argNum))
+			args = append(args, name)
+			argNum++
+		}
+		query += " AND connection_name IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
 	if !filter.Since.IsZero() {
 		query += fmt.Sprintf(" AND created_at >= $%d", argNum)
-		args = append(args, filter.Since)
+		args = append(args, utc(filter.Since))
 		argNum++
 	}
 
 	if !filter.Until.IsZero() {
 		query += fmt.Sprintf(" AND created_at <= $%d", argNum)
-		args = append(args, filter.Until)
+		args = append(args, utc(filter.Until))
 		argNum++
 	}
 
-	query += " ORDER BY created_at DESC"
+	if filter.ErrorOnly != nil {
+		if *filter.ErrorOnly {
+			query += " AND error != ''"
+		} else {
+			query += " AND error = ''"
+		}
+	}
+
+	if filter.Source != "" {
+		query += fmt.Sprintf(" AND source = $%d", argNum)
+		args = append(args, filter.Source)
+		argNum++
+	}
 
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argNum)
-		args = append(args, filter.Limit)
+	if filter.ConfigHash != "" {
+		query += fmt.Sprintf(" AND config_hash = $%d", argNum)
+		args = append(args, filter.ConfigHash)
 		argNum++
 	}
 
-	if filter.Offset > 0 {
+	if beforeID != nil && *beforeID > 0 {
+		query += fmt.Sprintf(" AND id < $%d", argNum)
+		args = append(args, *beforeID)
+		argNum++
+	}
+
+	if beforeID != nil {
+		query += " ORDER BY id DESC"
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
+
+	query += fmt.Sprintf(" LIMIT $%d", argNum)
+	args = append(args, clampLimit(filter.Limit))
+	argNum++
+
+	if beforeID == nil && filter.Offset > 0 {
 		query += fmt.Sprintf(" OFFSET $%d", argNum)
 		args = append(args, filter.Offset)
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	var rows *sql.Rows
+	var err error
+	if beforeID == nil && isConnectionResultsFilter(filter) {
+		// args are the connection, since and limit
+		rows, err = s.connectionResultsStmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = s.db.QueryContext(ctx, query, args...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query results: %w", err)
 	}
@@ -251,10 +679,25 @@ func (s *PostgresStorage) GetResults(ctx context.Context, filter ResultFilter) (
 			&r.DSCP,
 			&r.Error,
 			&r.CreatedAt,
+			&r.ServerDistanceKm,
+			&r.LatencyOK,
+			&r.DownloadOK,
+			&r.UploadOK,
+			&r.Attempts,
+			&r.BytesDownloaded,
+			&r.BytesUploaded,
+			&r.IPFamily,
+			&r.ConfigHash,
+			&r.ConnectMs,
+			&r.DownloadSize,
+			&r.UploadSize,
+			&r.Source,
+			&r.Threads,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		r.CreatedAt = utc(r.CreatedAt)
 		results = append(results, r)
 	}
 
@@ -265,19 +708,48 @@ func (s *PostgresStorage) GetResults(ctx context.Context, filter ResultFilter) (
 	return results, nil
 }
 
-// GetLatestResults retrieves the most recent result for each connection.
-func (s *PostgresStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
-	// PostgreSQL DISTINCT ON is more efficient than self-join
-	query := `
+// connectionResultsQueryPostgres selects a connection's newest results since
+// a time, as the dashboard charts do. It is the query queryResults builds for
+// a filter matched by isConnectionResultsFilter.
+const connectionResultsQueryPostgres = `
+	SELECT id, connection_name, server_id, server_name, server_country, server_host,
+		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	FROM test_results
+	WHERE connection_name = $1 AND created_at >= $2
+	ORDER BY created_at DESC
+	LIMIT $3
+	`
+
+// isConnectionResultsFilter returns true if filter only sets a connection,
+// a start time and optionally a limit.
+func isConnectionResultsFilter(filter ResultFilter) bool {
+	return filter.ConnectionName != "" && !filter.Since.IsZero() &&
+		len(filter.ConnectionNames) == 0 && filter.Until.IsZero() &&
+		filter.ErrorOnly == nil && filter.Source == "" && filter.ConfigHash == "" &&
+		filter.Offset == 0
+}
+
+// latestResultsQueryPostgres selects the most recent result for each connection.
+// PostgreSQL DISTINCT ON is more efficient than self-join
+const latestResultsQueryPostgres = `
 	SELECT DISTINCT ON (connection_name)
 		id, connection_name, server_id, server_name, server_country, server_host,
 		latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		source_ip, dscp, error, created_at
+		source_ip, dscp, error, created_at, server_distance_km,
+		latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
 	FROM test_results
 	ORDER BY connection_name, created_at DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+// GetLatestResults retrieves the most recent result for each connection.
+func (s *PostgresStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
+	rows, err := s.latestStmt.QueryContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query latest results: %w", err)
 	}
@@ -302,34 +774,122 @@ func (s *PostgresStorage) GetLatestResults(ctx context.Context) ([]TestResult, e
 			&r.DSCP,
 			&r.Error,
 			&r.CreatedAt,
+			&r.ServerDistanceKm,
+			&r.LatencyOK,
+			&r.DownloadOK,
+			&r.UploadOK,
+			&r.Attempts,
+			&r.BytesDownloaded,
+			&r.BytesUploaded,
+			&r.IPFamily,
+			&r.ConfigHash,
+			&r.ConnectMs,
+			&r.DownloadSize,
+			&r.UploadSize,
+			&r.Source,
+			&r.Threads,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		r.CreatedAt = utc(r.CreatedAt)
 		results = append(results, r)
 	}
 
 	return results, nil
 }
 
+// GetLatestResult retrieves the single most recent result across all connections.
+// Returns nil if no results exist.
+func (s *PostgresStorage) GetLatestResult(ctx context.Context) (*TestResult, error) {
+	query := `
+	SELECT id, connection_name, server_id, server_name, server_country, server_host,
+		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	FROM test_results
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	result := &TestResult{}
+	err := s.db.QueryRowContext(ctx, query).Scan(
+		&result.ID,
+		&result.ConnectionName,
+		&result.ServerID,
+		&result.ServerName,
+		&result.ServerCountry,
+		&result.ServerHost,
+		&result.LatencyMs,
+		&result.JitterMs,
+		&result.DownloadMbps,
+		&result.UploadMbps,
+		&result.PacketLossPct,
+		&result.SourceIP,
+		&result.DSCP,
+		&result.Error,
+		&result.CreatedAt,
+		&result.ServerDistanceKm,
+		&result.LatencyOK,
+		&result.DownloadOK,
+		&result.UploadOK,
+		&result.Attempts,
+		&result.BytesDownloaded,
+		&result.BytesUploaded,
+		&result.IPFamily,
+		&result.ConfigHash,
+		&result.ConnectMs,
+		&result.DownloadSize,
+		&result.UploadSize,
+		&result.Source,
+		&result.Threads,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest result: %w", err)
+	}
+	result.CreatedAt = utc(result.CreatedAt)
+
+	return result, nil
+}
+
 // GetStats calculates statistics for a connection over a time period.
 func (s *PostgresStorage) GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error) {
-	since := time.Now().Add(-period)
-	until := time.Now()
+	until := utc(time.Now())
+	return s.GetStatsBetween(ctx, connectionName, until.Add(-period), until)
+}
+
+// GetStatsBetween calculates statistics for a connection between since and
+// until.
+func (s *PostgresStorage) GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error) {
+	since, until = utc(since), utc(until)
+	period := until.Sub(since)
 
 	query := `
 	SELECT 
 		COUNT(*) as test_count,
 		COUNT(CASE WHEN error != '' THEN 1 END) as error_count,
-		AVG(CASE WHEN error = '' THEN download_mbps END) as avg_download,
-		AVG(CASE WHEN error = '' THEN upload_mbps END) as avg_upload,
-		AVG(CASE WHEN error = '' THEN latency_ms END) as avg_latency,
-		MIN(CASE WHEN error = '' THEN download_mbps END) as min_download,
-		MAX(CASE WHEN error = '' THEN download_mbps END) as max_download,
-		MIN(CASE WHEN error = '' THEN upload_mbps END) as min_upload,
-		MAX(CASE WHEN error = '' THEN upload_mbps END) as max_upload,
-		MIN(CASE WHEN error = '' THEN latency_ms END) as min_latency,
-		MAX(CASE WHEN error = '' THEN latency_ms END) as max_latency
+		AVG(CASE WHEN error = '' AND download_ok THEN download_mbps END) as avg_download,
+		AVG(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as avg_upload,
+		AVG(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as avg_latency,
+		MIN(CASE WHEN error = '' AND download_ok THEN download_mbps END) as min_download,
+		MAX(CASE WHEN error = '' AND download_ok THEN download_mbps END) as max_download,
+		MIN(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as min_upload,
+		MAX(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as max_upload,
+		MIN(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as min_latency,
+		MAX(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as max_latency,
+		AVG(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as avg_jitter,
+		MIN(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as min_jitter,
+		MAX(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as max_jitter,
+		COALESCE(SUM(bytes_downloaded), 0)::BIGINT as bytes_downloaded,
+		COALESCE(SUM(bytes_uploaded), 0)::BIGINT as bytes_uploaded,
+		COUNT(CASE WHEN error = '' AND download_ok THEN 1 END) as download_samples,
+		COUNT(CASE WHEN error = '' AND upload_ok THEN 1 END) as upload_samples,
+		COUNT(CASE WHEN error = '' AND latency_ok THEN 1 END) as latency_samples
 	FROM test_results
 	WHERE connection_name = $1 AND created_at >= $2 AND created_at <= $3
 	`
@@ -343,6 +903,7 @@ func (s *PostgresStorage) GetStats(ctx context.Context, connectionName string, p
 
 	var avgDownload, avgUpload, avgLatency sql.NullFloat64
 	var minDownload, maxDownload, minUpload, maxUpload, minLatency, maxLatency sql.NullFloat64
+	var avgJitter, minJitter, maxJitter sql.NullFloat64
 
 	err := s.db.QueryRowContext(ctx, query, connectionName, since, until).Scan(
 		&stats.TestCount,
@@ -356,6 +917,14 @@ func (s *PostgresStorage) GetStats(ctx context.Context, connectionName string, p
 		&maxUpload,
 		&minLatency,
 		&maxLatency,
+		&avgJitter,
+		&minJitter,
+		&maxJitter,
+		&stats.BytesDownloaded,
+		&stats.BytesUploaded,
+		&stats.downloadSamples,
+		&stats.uploadSamples,
+		&stats.latencySamples,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
@@ -388,15 +957,255 @@ func (s *PostgresStorage) GetStats(ctx context.Context, connectionName string, p
 	if maxLatency.Valid {
 		stats.MaxLatency = maxLatency.Float64
 	}
+	if avgJitter.Valid {
+		stats.AvgJitter = avgJitter.Float64
+	}
+	if minJitter.Valid {
+		stats.MinJitter = minJitter.Float64
+	}
+	if maxJitter.Valid {
+		stats.MaxJitter = maxJitter.Float64
+	}
+	stats.computeAvailability()
+
+	if err := s.getStatsExtremeTimes(ctx, stats, connectionName, since, until); err != nil {
+		return nil, err
+	}
+
+	rollups, err := queryRollups(ctx, s.db, rollupsBetweenQueryPostgres, connectionName, since, until)
+	if err != nil {
+		return nil, err
+	}
+	mergeRollupStats(stats, rollups)
 
 	return stats, nil
 }
 
-// DeleteOldResults removes results older than the specified time.
-func (s *PostgresStorage) DeleteOldResults(ctx context.Context, olderThan time.Time) (int64, error) {
+// statsExtremeTimesQueryPostgres selects the time of the most recent test
+// with each extreme value, per metric: min/max download, upload and latency.
+// Rows without a value of the metric sort last, so FIRST_VALUE is NULL only
+// if no test has one.
+const statsExtremeTimesQueryPostgres = `
+	WITH ok AS (
+		SELECT created_at,
+			CASE WHEN download_ok THEN download_mbps END AS download,
+			CASE WHEN upload_ok THEN upload_mbps END AS upload,
+			CASE WHEN latency_ok THEN latency_ms END AS latency
+		FROM test_results
+		WHERE connection_name = $1 AND created_at >= $2 AND created_at <= $3 AND error = ''
+	)
+	SELECT
+		FIRST_VALUE(CASE WHEN download IS NOT NULL THEN created_at END) OVER (ORDER BY download ASC NULLS LAST, created_at DESC),
+		FIRST_VALUE(CASE WHEN download IS NOT NULL THEN created_at END) OVER (ORDER BY download DESC NULLS LAST, created_at DESC),
+		FIRST_VALUE(CASE WHEN upload IS NOT NULL THEN created_at END) OVER (ORDER BY upload ASC NULLS LAST, created_at DESC),
+		FIRST_VALUE(CASE WHEN upload IS NOT NULL THEN created_at END) OVER (ORDER BY upload DESC NULLS LAST, created_at DESC),
+		FIRST_VALUE(CASE WHEN latency IS NOT NULL THEN created_at END) OVER (ORDER BY latency ASC NULLS LAST, created_at DESC),
+		FIRST_VALUE(CASE WHEN latency IS NOT NULL THEN created_at END) OVER (ORDER BY latency DESC NULLS LAST, created_at DESC)
+	FROM ok
+	LIMIT 1
+	`
+
+// getStatsExtremeTimes sets the times at which the extreme values of the
+// stats occurred, using window functions.
+func (s *PostgresStorage) getStatsExtremeTimes(ctx context.Context, stats *Stats, connectionName string, since, until time.Time) error {
+	values := make([]sql.NullTime, 6)
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	// Without tests in the period, the query returns no row
+	err := s.db.QueryRowContext(ctx, statsExtremeTimesQueryPostgres, connectionName, since, until).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stats extreme times: %w", err)
+	}
+	stats.setExtremeTimes(values)
+	return nil
+}
+
+// GetTrends returns the statistics of a connection over the given period,
+// bucketed by groupBy (TrendHourOfDay or TrendDayOfWeek) in the timezone loc.
+func (s *PostgresStorage) GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error) {
+	var field string
+	switch groupBy {
+	case TrendHourOfDay:
+		field = "HOUR"
+	case TrendDayOfWeek:
+		field = "DOW"
+	default:
+		return nil, fmt.Errorf("unknown trend grouping: %s", groupBy)
+	}
+
+	until := utc(time.Now())
+	since := until.Add(-period)
+
+	// Shift UTC by the offset of loc, independent of the session timezone
+	query := `
+	SELECT
+		CAST(EXTRACT(` + field + ` FROM (created_at AT TIME ZONE 'UTC') + $1 * INTERVAL '1 second') AS INTEGER) AS bucket,` + trendAggregatesSQL + `
+	FROM test_results
+	WHERE connection_name = $2 AND created_at >= $3 AND created_at <= $4
+	GROUP BY bucket
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, utcOffsetSeconds(loc), connectionName, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trends: %w", err)
+	}
+	defer rows.Close()
+
+	buckets, err := scanTrendBuckets(rows, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := queryRollups(ctx, s.db, rollupsBetweenQueryPostgres, connectionName, since, until)
+	if err != nil {
+		return nil, err
+	}
+	mergeRollupTrends(buckets, rollups, offsetBucketFunc(groupBy, loc))
+
+	return buckets, nil
+}
+
+// GetServerDownloads returns the average download in Mbps per server ID of
+// a connection's successful tests since the given time, limited to results of
+// the given IP family.
+func (s *PostgresStorage) GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error) {
+	query := `
+	SELECT server_id, AVG(download_mbps)
+	FROM test_results
+	WHERE connection_name = $1 AND ip_family = $2 AND created_at >= $3
+		AND error = '' AND download_ok AND server_id > 0
+	GROUP BY server_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, connectionName, ipFamily, utc(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server downloads: %w", err)
+	}
+	defer rows.Close()
+
+	downloads := make(map[int]float64)
+	for rows.Next() {
+		var serverID int
+		var avgDownload float64
+		if err := rows.Scan(&serverID, &avgDownload); err != nil {
+			return nil, fmt.Errorf("failed to scan server download: %w", err)
+		}
+		downloads[serverID] = avgDownload
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate server downloads: %w", err)
+	}
+
+	return downloads, nil
+}
+
+// GetBaseline returns the median download and upload of a connection's
+// successful tests since the given time at the given hour of day in the
+// timezone loc, limited to results of the given IP family.
+func (s *PostgresStorage) GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error) {
+	// Shift UTC by the offset of loc, independent of the session timezone
+	query := `
+	SELECT download_mbps, download_ok, upload_mbps, upload_ok
+	FROM test_results
+	WHERE connection_name = $1 AND ip_family = $2 AND created_at >= $3 AND error = ''
+		AND CAST(EXTRACT(HOUR FROM (created_at AT TIME ZONE 'UTC') + $4 * INTERVAL '1 second') AS INTEGER) = $5
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, connectionName, ipFamily, utc(since), utcOffsetSeconds(loc), hour)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBaseline(rows, hour)
+}
+
+// SaveConnectionEvent saves a connection health event.
+func (s *PostgresStorage) SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	query := `
+	INSERT INTO connection_events (connection_name, ip_family, from_state, to_state, reason, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
+	`
+
+	err := withReconnect(ctx, func() error {
+		return s.db.QueryRowContext(ctx, query,
+			event.ConnectionName, event.IPFamily, event.FromState, event.ToState, event.Reason, utc(event.CreatedAt),
+		).Scan(&event.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save connection event: %w", err)
+	}
+
+	return nil
+}
+
+// GetConnectionEvents returns a connection's health events since the given
+// time (zero = all), newest first, at most limit (0 = no limit).
+func (s *PostgresStorage) GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error) {
+	query := `
+	SELECT id, connection_name, ip_family, from_state, to_state, reason, created_at
+	FROM connection_events
+	WHERE connection_name = $1`
+	args := []interface{}{connectionName}
+	argNum := 2
+
+	if !since.IsZero() {
+		query += fmt.Sprintf(" AND created_at >= $%d", argNum)
+		args = append(args, utc(since))
+		argNum++
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argNum)
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanConnectionEvents(rows)
+}
+
+// CountOldResults returns the number of results older than the specified time,
+// optionally limited to a single connection.
+func (s *PostgresStorage) CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	query := "SELECT COUNT(*) FROM test_results WHERE created_at < $1"
+	args := []interface{}{utc(olderThan)}
+
+	if connectionName != "" {
+		query += " AND connection_name = $2"
+		args = append(args, connectionName)
+	}
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count old results: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteOldResults removes results older than the specified time,
+// optionally limited to a single connection.
+func (s *PostgresStorage) DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
 	query := "DELETE FROM test_results WHERE created_at < $1"
+	args := []interface{}{utc(olderThan)}
+
+	if connectionName != "" {
+		query += " AND connection_name = $2"
+		args = append(args, connectionName)
+	}
 
-	result, err := s.db.ExecContext(ctx, query, olderThan)
+	result, err := s.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old results: %w", err)
 	}
@@ -409,3 +1218,55 @@ func (s *PostgresStorage) DeleteOldResults(ctx context.Context, olderThan time.T
 	return count, nil
 }
 
+// rollupsBetweenQueryPostgres selects a connection's rollups of the hours
+// starting in a period.
+const rollupsBetweenQueryPostgres = `SELECT ` + rollupColumnsSQL + `
+	FROM hourly_rollups
+	WHERE connection_name = $1 AND hour >= $2 AND hour <= $3
+	ORDER BY hour`
+
+// rollupQueriesPostgres are the statements of RollupResults.
+var rollupQueriesPostgres = rollupQueries{
+	selectResults: "SELECT " + rollupSourceColumnsSQL + " FROM test_results WHERE created_at < $1 ORDER BY created_at, id",
+	selectRollups: "SELECT " + rollupColumnsSQL + " FROM hourly_rollups WHERE hour >= $1 AND hour < $2",
+	upsert: "INSERT INTO hourly_rollups (" + rollupColumnsSQL + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)` + rollupUpsertSQL,
+	deleteResults: "DELETE FROM test_results WHERE created_at < $1",
+}
+
+// RollupResults aggregates all results created before the start of the
+// hour of olderThan into hourly rollups and deletes them, in one
+// transaction.
+func (s *PostgresStorage) RollupResults(ctx context.Context, olderThan time.Time) (int64, error) {
+	return rollUp(ctx, s.db, rollupQueriesPostgres, olderThan)
+}
+
+// DeleteOldRollups removes rollups of hours before the specified time.
+func (s *PostgresStorage) DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM hourly_rollups WHERE hour < $1", utc(olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old rollups: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// thinQueriesPostgres are the statements of ThinResults.
+var thinQueriesPostgres = thinQueries{
+	selectResults: `SELECT id, connection_name, ip_family FROM test_results
+	WHERE created_at < $1 AND COALESCE(thin_level, 0) < $2 ORDER BY created_at, id`,
+	mark:         "UPDATE test_results SET thin_level = $1 WHERE id = $2",
+	deleteResult: "DELETE FROM test_results WHERE id = $1",
+}
+
+// ThinResults keeps every keepEvery-th result of each connection and IP
+// family created before olderThan that hasn't been thinned to level yet,
+// and deletes the others, in one transaction.
+func (s *PostgresStorage) ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error) {
+	return thinResults(ctx, s.db, thinQueriesPostgres, olderThan, keepEvery, level)
+}
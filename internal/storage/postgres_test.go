@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+)
+
+// newTestPostgres connects to the database given by FLOWGAUGE_TEST_POSTGRES_HOST
+// (and optionally _PORT, _DB, _USER and _PASSWORD), or skips without it. The
+// database's results are deleted, so it must be a disposable one.
+func newTestPostgres(tb testing.TB) *PostgresStorage {
+	tb.Helper()
+	host := os.Getenv("FLOWGAUGE_TEST_POSTGRES_HOST")
+	if host == "" {
+		tb.Skip("FLOWGAUGE_TEST_POSTGRES_HOST not set")
+	}
+
+	cfg := config.PostgresConfig{
+		Host:     host,
+		Port:     5432,
+		Database: envOr("FLOWGAUGE_TEST_POSTGRES_DB", "flowgauge_test"),
+		User:     envOr("FLOWGAUGE_TEST_POSTGRES_USER", "postgres"),
+		Password: os.Getenv("FLOWGAUGE_TEST_POSTGRES_PASSWORD"),
+		SSLMode:  "disable",
+	}
+	if port := os.Getenv("FLOWGAUGE_TEST_POSTGRES_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		cfg.Port = p
+	}
+
+	s, err := NewPostgresStorage(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := s.Init(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+	deleteResults := func() {
+		if _, err := s.db.Exec("DELETE FROM test_results"); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	deleteResults()
+	tb.Cleanup(func() {
+		deleteResults()
+		_ = s.Close()
+	})
+	return s
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// BenchmarkPostgresDashboardQueries compares the queries polled by the
+// dashboard using the statements prepared in Init with sending their SQL on
+// each call, as before they were prepared. Run with e.g.
+//
+//	FLOWGAUGE_TEST_POSTGRES_HOST=localhost go test -run '^$' -bench Postgres ./internal/storage
+func BenchmarkPostgresDashboardQueries(b *testing.B) {
+	ctx := context.Background()
+	s := newTestPostgres(b)
+
+	// A week of results every 30 minutes for 8 connections
+	start := time.Now().Add(-7 * 24 * time.Hour)
+	var results []*TestResult
+	for c := 0; c < 8; c++ {
+		for i := 0; i < 7*48; i++ {
+			results = append(results, &TestResult{
+				ConnectionName: fmt.Sprintf("wan%d", c),
+				DownloadMbps:   100,
+				UploadMbps:     20,
+				LatencyMs:      10,
+				CreatedAt:      start.Add(time.Duration(i) * 30 * time.Minute),
+			})
+		}
+	}
+	if err := s.SaveResults(ctx, results); err != nil {
+		b.Fatal(err)
+	}
+
+	since := utc(time.Now().Add(-24 * time.Hour))
+	limit := clampLimit(0)
+	benchmarks := []struct {
+		name  string
+		query func() (*sql.Rows, error)
+	}{
+		{"GetLatestResults/prepared", func() (*sql.Rows, error) {
+			return s.latestStmt.QueryContext(ctx)
+		}},
+		{"GetLatestResults/inline", func() (*sql.Rows, error) {
+			return s.db.QueryContext(ctx, latestResultsQueryPostgres)
+		}},
+		{"GetResults/prepared", func() (*sql.Rows, error) {
+			return s.connectionResultsStmt.QueryContext(ctx, "wan0", since, limit)
+		}},
+		{"GetResults/inline", func() (*sql.Rows, error) {
+			return s.db.QueryContext(ctx, connectionResultsQueryPostgres, "wan0", since, limit)
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rows, err := bm.query()
+				if err != nil {
+					b.Fatal(err)
+				}
+				for rows.Next() {
+				}
+				if err := rows.Err(); err != nil {
+					b.Fatal(err)
+				}
+				_ = rows.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/logger"
+)
+
+// A write that fails because the database connection was lost (e.g. by a
+// PostgreSQL restart) before it reached the server is attempted up to
+// reconnectAttempts times, waiting reconnectBackoff in between, doubled after
+// each attempt. The pool replaces broken connections, so a retry reconnects
+// once the server is back.
+const (
+	reconnectAttempts = 3
+	reconnectBackoff  = time.Second
+)
+
+// flushOnCloseTimeout bounds saving buffered results when closing.
+const flushOnCloseTimeout = 5 * time.Second
+
+// isConnectionError returns true if err means the database connection was
+// lost or couldn't be established, rather than that the query itself failed.
+// The write may have been applied anyway; see isRetryable.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is "connection exception"; 57P01-57P03 are sent while the
+		// server shuts down or starts up
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	return isRetryable(err)
+}
+
+// isRetryable returns true if err means the connection was lost before the
+// write was sent, so retrying it can't save it twice. A write whose response
+// was lost may have been committed and isn't retried.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	// database/sql returns driver.ErrBadConn once its own retries are used
+	// up; the pgx driver only reports it for errors that are safe to retry
+	return errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err)
+}
+
+// withReconnect calls write until it succeeds, fails with an error that
+// isn't safe to retry, reconnectAttempts are used up or ctx is done. It
+// returns the last error.
+func withReconnect(ctx context.Context, write func() error) error {
+	backoff := reconnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || attempt == reconnectAttempts || !isRetryable(err) {
+			return err
+		}
+
+		logger.Debug("Database connection lost, retrying write",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// save saves buffered results, then results with insert, which returns the
+// results it didn't save. If the database is unreachable, copies of those are
+// buffered instead and nil is returned; they are saved with the next write
+// (without their IDs being set).
+func (s *PostgresStorage) save(ctx context.Context, results []*TestResult, insert func() ([]*TestResult, error)) error {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	// Don't wait for the retries twice while the database is down
+	unsaved := results
+	err := s.flushPendingLocked(ctx)
+	if err == nil {
+		err = withReconnect(ctx, func() error {
+			var err error
+			unsaved, err = insert()
+			return err
+		})
+	}
+	if err == nil || !isRetryable(err) || s.cfg.BufferSize <= 0 {
+		return err
+	}
+
+	s.bufferLocked(unsaved)
+	logger.Warn("PostgreSQL unreachable, buffering results until it is back",
+		zap.String("database", s.cfg.Database),
+		zap.Int("buffered", len(s.pending)),
+		zap.Error(err),
+	)
+	return nil
+}
+
+// bufferLocked appends copies of results to the buffer, dropping the oldest
+// results beyond cfg.BufferSize. Must be called with pendingMu held.
+func (s *PostgresStorage) bufferLocked(results []*TestResult) {
+	for _, result := range results {
+		r := *result
+		s.pending = append(s.pending, &r)
+	}
+
+	if dropped := len(s.pending) - s.cfg.BufferSize; dropped > 0 {
+		s.pending = append([]*TestResult(nil), s.pending[dropped:]...)
+		logger.Warn("PostgreSQL result buffer full, dropped oldest results",
+			zap.String("database", s.cfg.Database),
+			zap.Int("dropped", dropped),
+		)
+	}
+}
+
+// flushPendingLocked saves the buffered results in one transaction, or one
+// at a time if that fails. Results that fail for another reason than a lost
+// connection are dropped, as retrying them can't succeed, and so are results
+// that may have been saved before the connection was lost. Must be called
+// with pendingMu held.
+func (s *PostgresStorage) flushPendingLocked(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	var saved int
+	var errs []error
+	err := withReconnect(ctx, func() error {
+		n, rowErrs, err := s.insertBatchOrEach(ctx, s.pending)
+		s.pending = s.pending[n:]
+		saved += n - len(rowErrs)
+		errs = append(errs, rowErrs...)
+		return err
+	})
+
+	dropped := len(errs)
+	if err != nil && !isRetryable(err) {
+		dropped += len(s.pending)
+		errs = append(errs, err)
+		s.pending = nil
+	}
+	if dropped > 0 {
+		logger.Error("Failed to save buffered results, dropping them",
+			zap.String("database", s.cfg.Database),
+			zap.Int("results", dropped),
+			zap.Error(errors.Join(errs...)),
+		)
+	}
+	if saved > 0 {
+		logger.Info("Saved buffered results after reconnecting",
+			zap.String("database", s.cfg.Database),
+			zap.Int("results", saved),
+		)
+	}
+	if err != nil && isRetryable(err) {
+		return err
+	}
+	return nil
+}
+
+// flushOnClose tries to save buffered results before the connection is
+// closed; results that still can't be saved are lost.
+func (s *PostgresStorage) flushOnClose() {
+	if s.db == nil {
+		return
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if len(s.pending) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flushOnCloseTimeout)
+	defer cancel()
+	if err := s.flushPendingLocked(ctx); err != nil {
+		logger.Warn("PostgreSQL unreachable on close, buffered results are lost",
+			zap.String("database", s.cfg.Database),
+			zap.Int("results", len(s.pending)),
+			zap.Error(err),
+		)
+	}
+}
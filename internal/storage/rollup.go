@@ -0,0 +1,421 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Results older than storage.rollup.raw_retention are aggregated per
+// connection and hour into the hourly_rollups table and deleted by
+// RollupResults. GetStats and GetTrends merge the rollups of their period
+// into the statistics of the remaining results, so long periods keep their
+// averages, extremes and counts. Rollups are less precise than the results:
+// a rollup is included if its hour starts within the period, and its extreme
+// values are dated to the start of the hour.
+
+// aggregate accumulates the average, minimum and maximum of a metric, and
+// the time of the most recent test with each extreme value.
+type aggregate struct {
+	count    int
+	sum      float64
+	min, max float64
+	minAt    time.Time
+	maxAt    time.Time
+}
+
+// add adds a value measured at the given time. Results are added oldest
+// first, so ties move the extreme times to the more recent test.
+func (a *aggregate) add(v float64, at time.Time) {
+	if a.count == 0 || v <= a.min {
+		a.min, a.minAt = v, at
+	}
+	if a.count == 0 || v >= a.max {
+		a.max, a.maxAt = v, at
+	}
+	a.count++
+	a.sum += v
+}
+
+// avg returns the average, or 0 without values.
+func (a *aggregate) avg() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return a.sum / float64(a.count)
+}
+
+// extremeTimes returns the times of the minimum and maximum, or nil without
+// values.
+func (a *aggregate) extremeTimes() (minAt, maxAt *time.Time) {
+	if a.count == 0 {
+		return nil, nil
+	}
+	return &a.minAt, &a.maxAt
+}
+
+// metricAggregates are the aggregates of GetStats, GetTrends and the hourly
+// rollups. Like the SQL queries, only phases that succeeded are included.
+type metricAggregates struct {
+	tests, errors                     int
+	download, upload, latency, jitter aggregate
+	bytesDownload, bytesUpload        int64
+}
+
+// add adds a result to the aggregates.
+func (m *metricAggregates) add(r TestResult) {
+	m.tests++
+	m.bytesDownload += r.BytesDownloaded
+	m.bytesUpload += r.BytesUploaded
+	if r.IsError() {
+		m.errors++
+		return
+	}
+	if r.DownloadOK {
+		m.download.add(r.DownloadMbps, r.CreatedAt)
+	}
+	if r.UploadOK {
+		m.upload.add(r.UploadMbps, r.CreatedAt)
+	}
+	if r.LatencyOK {
+		m.latency.add(r.LatencyMs, r.CreatedAt)
+		m.jitter.add(r.JitterMs, r.CreatedAt)
+	}
+}
+
+// merge adds the values of another aggregate. Ties move the extreme times
+// to the more recent value.
+func (a *aggregate) merge(o aggregate) {
+	if o.count == 0 {
+		return
+	}
+	if a.count == 0 || o.min < a.min || (o.min == a.min && o.minAt.After(a.minAt)) {
+		a.min, a.minAt = o.min, o.minAt
+	}
+	if a.count == 0 || o.max > a.max || (o.max == a.max && o.maxAt.After(a.maxAt)) {
+		a.max, a.maxAt = o.max, o.maxAt
+	}
+	a.count += o.count
+	a.sum += o.sum
+}
+
+// newAggregate returns the aggregate of count values with the given average
+// and extremes. Nil extreme times are left zero.
+func newAggregate(count int, avg, min, max float64, minAt, maxAt *time.Time) aggregate {
+	a := aggregate{count: count, sum: avg * float64(count), min: min, max: max}
+	if minAt != nil {
+		a.minAt = *minAt
+	}
+	if maxAt != nil {
+		a.maxAt = *maxAt
+	}
+	return a
+}
+
+// merge adds the aggregates of another set of results.
+func (m *metricAggregates) merge(o metricAggregates) {
+	m.tests += o.tests
+	m.errors += o.errors
+	m.download.merge(o.download)
+	m.upload.merge(o.upload)
+	m.latency.merge(o.latency)
+	m.jitter.merge(o.jitter)
+	m.bytesDownload += o.bytesDownload
+	m.bytesUpload += o.bytesUpload
+}
+
+// aggregates returns the aggregates the statistics were computed from.
+// Jitter is measured with latency, so it shares its sample count.
+func (s *Stats) aggregates() metricAggregates {
+	return metricAggregates{
+		tests:         s.TestCount,
+		errors:        s.ErrorCount,
+		download:      newAggregate(s.downloadSamples, s.AvgDownload, s.MinDownload, s.MaxDownload, s.MinDownloadAt, s.MaxDownloadAt),
+		upload:        newAggregate(s.uploadSamples, s.AvgUpload, s.MinUpload, s.MaxUpload, s.MinUploadAt, s.MaxUploadAt),
+		latency:       newAggregate(s.latencySamples, s.AvgLatency, s.MinLatency, s.MaxLatency, s.MinLatencyAt, s.MaxLatencyAt),
+		jitter:        newAggregate(s.latencySamples, s.AvgJitter, s.MinJitter, s.MaxJitter, nil, nil),
+		bytesDownload: s.BytesDownloaded,
+		bytesUpload:   s.BytesUploaded,
+	}
+}
+
+// setAggregates sets the statistics from the aggregates of their results.
+func (s *Stats) setAggregates(m metricAggregates) {
+	s.TestCount, s.ErrorCount = m.tests, m.errors
+	s.AvgDownload, s.MinDownload, s.MaxDownload = m.download.avg(), m.download.min, m.download.max
+	s.AvgUpload, s.MinUpload, s.MaxUpload = m.upload.avg(), m.upload.min, m.upload.max
+	s.AvgLatency, s.MinLatency, s.MaxLatency = m.latency.avg(), m.latency.min, m.latency.max
+	s.AvgJitter, s.MinJitter, s.MaxJitter = m.jitter.avg(), m.jitter.min, m.jitter.max
+	s.BytesDownloaded, s.BytesUploaded = m.bytesDownload, m.bytesUpload
+	s.downloadSamples, s.uploadSamples, s.latencySamples = m.download.count, m.upload.count, m.latency.count
+	s.MinDownloadAt, s.MaxDownloadAt = m.download.extremeTimes()
+	s.MinUploadAt, s.MaxUploadAt = m.upload.extremeTimes()
+	s.MinLatencyAt, s.MaxLatencyAt = m.latency.extremeTimes()
+	s.computeAvailability()
+}
+
+// aggregates returns the aggregates the bucket was computed from.
+func (b *TrendBucket) aggregates() metricAggregates {
+	return metricAggregates{
+		tests:    b.TestCount,
+		errors:   b.ErrorCount,
+		download: newAggregate(b.downloadSamples, b.AvgDownload, b.MinDownload, b.MaxDownload, nil, nil),
+		upload:   newAggregate(b.uploadSamples, b.AvgUpload, b.MinUpload, b.MaxUpload, nil, nil),
+		latency:  newAggregate(b.latencySamples, b.AvgLatency, b.MinLatency, b.MaxLatency, nil, nil),
+	}
+}
+
+// setAggregates sets the bucket's statistics from the aggregates of its
+// results.
+func (b *TrendBucket) setAggregates(m metricAggregates) {
+	b.TestCount, b.ErrorCount = m.tests, m.errors
+	b.AvgDownload, b.MinDownload, b.MaxDownload = m.download.avg(), m.download.min, m.download.max
+	b.AvgUpload, b.MinUpload, b.MaxUpload = m.upload.avg(), m.upload.min, m.upload.max
+	b.AvgLatency, b.MinLatency, b.MaxLatency = m.latency.avg(), m.latency.min, m.latency.max
+	b.downloadSamples, b.uploadSamples, b.latencySamples = m.download.count, m.upload.count, m.latency.count
+}
+
+// hourlyRollup holds the aggregates of a connection's results in one hour.
+type hourlyRollup struct {
+	connectionName string
+	// hour is the start of the hour in UTC
+	hour time.Time
+	metricAggregates
+}
+
+// rollupKey identifies the rollup of a connection and hour.
+type rollupKey struct {
+	connectionName string
+	hour           time.Time
+}
+
+// addRollup adds a result to the rollup of its connection and hour, creating
+// the rollup if needed.
+func addRollup(rollups map[rollupKey]*hourlyRollup, r TestResult) {
+	key := rollupKey{r.ConnectionName, utc(r.CreatedAt).Truncate(time.Hour)}
+	rollup := rollups[key]
+	if rollup == nil {
+		rollup = &hourlyRollup{connectionName: key.connectionName, hour: key.hour}
+		rollups[key] = rollup
+	}
+	rollup.add(r)
+}
+
+// mergeRollupStats merges rollups into statistics computed from results.
+func mergeRollupStats(stats *Stats, rollups []hourlyRollup) {
+	if len(rollups) == 0 {
+		return
+	}
+	m := stats.aggregates()
+	for _, r := range rollups {
+		m.merge(r.metricAggregates)
+	}
+	stats.setAggregates(m)
+}
+
+// mergeRollupTrends merges rollups into trend buckets computed from results,
+// using bucketOf to find the bucket of each rollup's hour.
+func mergeRollupTrends(buckets []TrendBucket, rollups []hourlyRollup, bucketOf func(time.Time) int) {
+	for _, r := range rollups {
+		i := bucketOf(r.hour)
+		if i < 0 || i >= len(buckets) {
+			continue
+		}
+		m := buckets[i].aggregates()
+		m.merge(r.metricAggregates)
+		buckets[i].setAggregates(m)
+	}
+}
+
+// rollupColumnsSQL are the columns of the hourly_rollups table, in the
+// order of hourlyRollup.args and scanRollups. Averages are stored rather than
+// sums so the table can be read directly.
+const rollupColumnsSQL = `connection_name, hour, test_count, error_count,
+		download_count, download_avg, download_min, download_max,
+		upload_count, upload_avg, upload_min, upload_max,
+		latency_count, latency_avg, latency_min, latency_max,
+		jitter_avg, jitter_min, jitter_max,
+		bytes_downloaded, bytes_uploaded`
+
+// args returns the values of rollupColumnsSQL.
+func (r *hourlyRollup) args() []interface{} {
+	return []interface{}{
+		r.connectionName, r.hour, r.tests, r.errors,
+		r.download.count, r.download.avg(), r.download.min, r.download.max,
+		r.upload.count, r.upload.avg(), r.upload.min, r.upload.max,
+		r.latency.count, r.latency.avg(), r.latency.min, r.latency.max,
+		r.jitter.avg(), r.jitter.min, r.jitter.max,
+		r.bytesDownload, r.bytesUpload,
+	}
+}
+
+// scanRollups reads rollups selected with rollupColumnsSQL.
+func scanRollups(rows *sql.Rows) ([]hourlyRollup, error) {
+	var rollups []hourlyRollup
+	for rows.Next() {
+		var r hourlyRollup
+		var counts [3]int
+		var values [12]float64
+		if err := rows.Scan(&r.connectionName, &r.hour, &r.tests, &r.errors,
+			&counts[0], &values[0], &values[1], &values[2],
+			&counts[1], &values[3], &values[4], &values[5],
+			&counts[2], &values[6], &values[7], &values[8],
+			&values[9], &values[10], &values[11],
+			&r.bytesDownload, &r.bytesUpload,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup: %w", err)
+		}
+
+		r.hour = utc(r.hour)
+		r.download = newAggregate(counts[0], values[0], values[1], values[2], &r.hour, &r.hour)
+		r.upload = newAggregate(counts[1], values[3], values[4], values[5], &r.hour, &r.hour)
+		r.latency = newAggregate(counts[2], values[6], values[7], values[8], &r.hour, &r.hour)
+		r.jitter = newAggregate(counts[2], values[9], values[10], values[11], &r.hour, &r.hour)
+		rollups = append(rollups, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rollups: %w", err)
+	}
+	return rollups, nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryRollups returns the rollups selected by query.
+func queryRollups(ctx context.Context, q queryer, query string, args ...interface{}) ([]hourlyRollup, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollups: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRollups(rows)
+}
+
+// rollupQueries are the dialect-specific statements of rollUp.
+type rollupQueries struct {
+	// selectResults selects rollupSourceColumnsSQL of all results created
+	// before $1, oldest first, so ties of a minimum or maximum are timed at
+	// the latest result like in GetStats
+	selectResults string
+	// selectRollups selects rollupColumnsSQL of all rollups from hour $1 up
+	// to (excluding) hour $2
+	selectRollups string
+	// upsert inserts or replaces a rollup given its rollupColumnsSQL
+	upsert string
+	// deleteResults deletes all results created before $1
+	deleteResults string
+}
+
+// rollupUpsertSQL completes an INSERT of rollupColumnsSQL to replace the
+// existing rollup of the connection and hour (same syntax in SQLite and
+// PostgreSQL).
+const rollupUpsertSQL = `
+	ON CONFLICT (connection_name, hour) DO UPDATE SET
+		test_count = excluded.test_count, error_count = excluded.error_count,
+		download_count = excluded.download_count, download_avg = excluded.download_avg,
+		download_min = excluded.download_min, download_max = excluded.download_max,
+		upload_count = excluded.upload_count, upload_avg = excluded.upload_avg,
+		upload_min = excluded.upload_min, upload_max = excluded.upload_max,
+		latency_count = excluded.latency_count, latency_avg = excluded.latency_avg,
+		latency_min = excluded.latency_min, latency_max = excluded.latency_max,
+		jitter_avg = excluded.jitter_avg, jitter_min = excluded.jitter_min, jitter_max = excluded.jitter_max,
+		bytes_downloaded = excluded.bytes_downloaded, bytes_uploaded = excluded.bytes_uploaded`
+
+// rollupSourceColumnsSQL are the result columns aggregated into rollups.
+const rollupSourceColumnsSQL = `connection_name, created_at, error,
+		download_mbps, download_ok, upload_mbps, upload_ok,
+		latency_ms, jitter_ms, latency_ok, bytes_downloaded, bytes_uploaded`
+
+// rollUp aggregates all results created before the start of the hour of
+// olderThan into hourly rollups, merging them into existing rollups of the
+// same hours, and deletes the results, in one transaction. It returns the
+// number of results rolled up.
+func rollUp(ctx context.Context, db *sql.DB, q rollupQueries, olderThan time.Time) (int64, error) {
+	cutoff := utc(olderThan).Truncate(time.Hour)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rollups, first, err := aggregateResults(ctx, tx, q.selectResults, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if len(rollups) == 0 {
+		return 0, nil
+	}
+
+	// Results may arrive late (e.g. imports), so hours can be rolled up twice
+	existing, err := queryRollups(ctx, tx, q.selectRollups, first, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range existing {
+		if r := rollups[rollupKey{e.connectionName, e.hour}]; r != nil {
+			r.merge(e.metricAggregates)
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, q.upsert)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare rollup statement: %w", err)
+	}
+	defer stmt.Close()
+	for _, r := range rollups {
+		if _, err := stmt.ExecContext(ctx, r.args()...); err != nil {
+			return 0, fmt.Errorf("failed to save rollup: %w", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, q.deleteResults, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rolled up results: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit rollup: %w", err)
+	}
+	return count, nil
+}
+
+// aggregateResults aggregates the results selected by query into rollups,
+// and returns them with the earliest hour.
+func aggregateResults(ctx context.Context, tx *sql.Tx, query string, cutoff time.Time) (map[rollupKey]*hourlyRollup, time.Time, error) {
+	rows, err := tx.QueryContext(ctx, query, cutoff)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get results to roll up: %w", err)
+	}
+	defer rows.Close()
+
+	rollups := make(map[rollupKey]*hourlyRollup)
+	var first time.Time
+	for rows.Next() {
+		var r TestResult
+		if err := rows.Scan(&r.ConnectionName, &r.CreatedAt, &r.Error,
+			&r.DownloadMbps, &r.DownloadOK, &r.UploadMbps, &r.UploadOK,
+			&r.LatencyMs, &r.JitterMs, &r.LatencyOK, &r.BytesDownloaded, &r.BytesUploaded,
+		); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to scan result: %w", err)
+		}
+		r.CreatedAt = utc(r.CreatedAt)
+		addRollup(rollups, r)
+		if hour := r.CreatedAt.Truncate(time.Hour); first.IsZero() || hour.Before(first) {
+			first = hour
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("error iterating results: %w", err)
+	}
+	return rollups, first, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lan-dot-party/flowgauge/internal/logger"
+)
+
+// slowQueryStorage wraps a Storage and logs a warning for every query that
+// takes longer than threshold, e.g. to spot missing indexes on large tables.
+// Init and Close are passed through untimed.
+type slowQueryStorage struct {
+	Storage
+	threshold time.Duration
+}
+
+// withSlowQueryLog wraps s to log slow queries. A threshold <= 0 disables the
+// logging and returns s unchanged.
+func withSlowQueryLog(s Storage, threshold time.Duration) Storage {
+	if threshold <= 0 {
+		return s
+	}
+	return &slowQueryStorage{Storage: s, threshold: threshold}
+}
+
+// observe logs the query if it started longer than the threshold ago.
+// Use as: defer s.observe("Name", time.Now()).
+func (s *slowQueryStorage) observe(query string, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < s.threshold {
+		return
+	}
+	logger.Warn("Slow storage query",
+		zap.String("query", query),
+		zap.Duration("duration", elapsed),
+		zap.Duration("threshold", s.threshold),
+	)
+}
+
+// The Storage methods below call the wrapped method, timed by observe.
+
+func (s *slowQueryStorage) Ping(ctx context.Context) error {
+	defer s.observe("Ping", time.Now())
+	return s.Storage.Ping(ctx)
+}
+
+func (s *slowQueryStorage) SaveResult(ctx context.Context, result *TestResult) error {
+	defer s.observe("SaveResult", time.Now())
+	return s.Storage.SaveResult(ctx, result)
+}
+
+func (s *slowQueryStorage) SaveResults(ctx context.Context, results []*TestResult) error {
+	defer s.observe("SaveResults", time.Now())
+	return s.Storage.SaveResults(ctx, results)
+}
+
+func (s *slowQueryStorage) SaveResultsAtomic(ctx context.Context, results []*TestResult) error {
+	defer s.observe("SaveResultsAtomic", time.Now())
+	return s.Storage.SaveResultsAtomic(ctx, results)
+}
+
+func (s *slowQueryStorage) GetResult(ctx context.Context, id int64) (*TestResult, error) {
+	defer s.observe("GetResult", time.Now())
+	return s.Storage.GetResult(ctx, id)
+}
+
+func (s *slowQueryStorage) GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error) {
+	defer s.observe("GetResults", time.Now())
+	return s.Storage.GetResults(ctx, filter)
+}
+
+func (s *slowQueryStorage) GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error) {
+	defer s.observe("GetResultsCursor", time.Now())
+	return s.Storage.GetResultsCursor(ctx, filter, beforeID)
+}
+
+func (s *slowQueryStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
+	defer s.observe("GetLatestResults", time.Now())
+	return s.Storage.GetLatestResults(ctx)
+}
+
+func (s *slowQueryStorage) GetLatestResult(ctx context.Context) (*TestResult, error) {
+	defer s.observe("GetLatestResult", time.Now())
+	return s.Storage.GetLatestResult(ctx)
+}
+
+func (s *slowQueryStorage) GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error) {
+	defer s.observe("GetStats", time.Now())
+	return s.Storage.GetStats(ctx, connectionName, period)
+}
+
+func (s *slowQueryStorage) GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error) {
+	defer s.observe("GetStatsBetween", time.Now())
+	return s.Storage.GetStatsBetween(ctx, connectionName, since, until)
+}
+
+func (s *slowQueryStorage) GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error) {
+	defer s.observe("GetTrends", time.Now())
+	return s.Storage.GetTrends(ctx, connectionName, groupBy, period, loc)
+}
+
+func (s *slowQueryStorage) GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error) {
+	defer s.observe("GetServerDownloads", time.Now())
+	return s.Storage.GetServerDownloads(ctx, connectionName, ipFamily, since)
+}
+
+func (s *slowQueryStorage) GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error) {
+	defer s.observe("GetBaseline", time.Now())
+	return s.Storage.GetBaseline(ctx, connectionName, ipFamily, hour, since, loc)
+}
+
+func (s *slowQueryStorage) SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	defer s.observe("SaveConnectionEvent", time.Now())
+	return s.Storage.SaveConnectionEvent(ctx, event)
+}
+
+func (s *slowQueryStorage) GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error) {
+	defer s.observe("GetConnectionEvents", time.Now())
+	return s.Storage.GetConnectionEvents(ctx, connectionName, since, limit)
+}
+
+func (s *slowQueryStorage) CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	defer s.observe("CountOldResults", time.Now())
+	return s.Storage.CountOldResults(ctx, olderThan, connectionName)
+}
+
+func (s *slowQueryStorage) DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	defer s.observe("DeleteOldResults", time.Now())
+	return s.Storage.DeleteOldResults(ctx, olderThan, connectionName)
+}
+
+func (s *slowQueryStorage) RollupResults(ctx context.Context, olderThan time.Time) (int64, error) {
+	defer s.observe("RollupResults", time.Now())
+	return s.Storage.RollupResults(ctx, olderThan)
+}
+
+func (s *slowQueryStorage) DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error) {
+	defer s.observe("DeleteOldRollups", time.Now())
+	return s.Storage.DeleteOldRollups(ctx, olderThan)
+}
+
+func (s *slowQueryStorage) ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error) {
+	defer s.observe("ThinResults", time.Now())
+	return s.Storage.ThinResults(ctx, olderThan, keepEvery, level)
+}
@@ -3,20 +3,28 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
 
 	"github.com/lan-dot-party/flowgauge/internal/config"
+	"github.com/lan-dot-party/flowgauge/internal/logger"
 )
 
 // SQLiteStorage implements the Storage interface using SQLite.
 type SQLiteStorage struct {
 	db   *sql.DB
 	path string
+
+	// backup copies the database to <path>.bak before migrations and
+	// deletes (storage.backup_before_migrate)
+	backup bool
 }
 
 // NewSQLiteStorage creates a new SQLite storage instance.
@@ -51,6 +59,19 @@ func (s *SQLiteStorage) Init(ctx context.Context) error {
 		return fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
+	// Copy an existing database before migrations rewrite it
+	if s.backup {
+		pending, err := s.migrationPending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending {
+			if err := s.backupDatabase(ctx, "migration"); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Create schema
 	if err := s.createSchema(ctx); err != nil {
 		return fmt.Errorf("failed to create schema: %w", err)
@@ -77,16 +98,246 @@ func (s *SQLiteStorage) createSchema(ctx context.Context) error {
 		source_ip TEXT,
 		dscp INTEGER,
 		error TEXT,
+		server_distance_km REAL DEFAULT 0,
+		latency_ok INTEGER DEFAULT 1,
+		download_ok INTEGER DEFAULT 1,
+		upload_ok INTEGER DEFAULT 1,
+		attempts INTEGER DEFAULT 1,
+		bytes_downloaded INTEGER DEFAULT 0,
+		bytes_uploaded INTEGER DEFAULT 0,
+		ip_family TEXT DEFAULT '',
+		config_hash TEXT DEFAULT '',
+		connect_ms REAL DEFAULT 0,
+		download_size TEXT DEFAULT '',
+		upload_size TEXT DEFAULT '',
+		source TEXT DEFAULT '',
+		threads INTEGER DEFAULT 0,
+		thin_level INTEGER DEFAULT 0,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_results_connection ON test_results(connection_name);
 	CREATE INDEX IF NOT EXISTS idx_results_created ON test_results(created_at);
 	CREATE INDEX IF NOT EXISTS idx_results_connection_created ON test_results(connection_name, created_at);
+
+	CREATE TABLE IF NOT EXISTS connection_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		connection_name TEXT NOT NULL,
+		ip_family TEXT DEFAULT '',
+		from_state TEXT DEFAULT '',
+		to_state TEXT NOT NULL,
+		reason TEXT DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_events_connection_created ON connection_events(connection_name, created_at);
+
+	CREATE TABLE IF NOT EXISTS hourly_rollups (
+		connection_name TEXT NOT NULL,
+		hour TIMESTAMP NOT NULL,
+		test_count INTEGER NOT NULL,
+		error_count INTEGER NOT NULL,
+		download_count INTEGER NOT NULL,
+		download_avg REAL NOT NULL,
+		download_min REAL NOT NULL,
+		download_max REAL NOT NULL,
+		upload_count INTEGER NOT NULL,
+		upload_avg REAL NOT NULL,
+		upload_min REAL NOT NULL,
+		upload_max REAL NOT NULL,
+		latency_count INTEGER NOT NULL,
+		latency_avg REAL NOT NULL,
+		latency_min REAL NOT NULL,
+		latency_max REAL NOT NULL,
+		jitter_avg REAL NOT NULL,
+		jitter_min REAL NOT NULL,
+		jitter_max REAL NOT NULL,
+		bytes_downloaded INTEGER NOT NULL,
+		bytes_uploaded INTEGER NOT NULL,
+		PRIMARY KEY (connection_name, hour)
+	);
 	`
 
-	_, err := s.db.ExecContext(ctx, schema)
-	return err
+	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	return s.migrateSchema(ctx)
+}
+
+// sqliteColumnMigrations are the columns added after the initial schema.
+// Existing rows predate per-phase status and are treated as successful on
+// the first attempt.
+var sqliteColumnMigrations = []struct {
+	column     string
+	definition string
+}{
+	{"server_distance_km", "REAL DEFAULT 0"},
+	{"latency_ok", "INTEGER DEFAULT 1"},
+	{"download_ok", "INTEGER DEFAULT 1"},
+	{"upload_ok", "INTEGER DEFAULT 1"},
+	{"attempts", "INTEGER DEFAULT 1"},
+	{"bytes_downloaded", "INTEGER DEFAULT 0"},
+	{"bytes_uploaded", "INTEGER DEFAULT 0"},
+	{"ip_family", "TEXT DEFAULT ''"},
+	{"config_hash", "TEXT DEFAULT ''"},
+	{"connect_ms", "REAL DEFAULT 0"},
+	{"download_size", "TEXT DEFAULT ''"},
+	{"upload_size", "TEXT DEFAULT ''"},
+	{"source", "TEXT DEFAULT ''"},
+	{"threads", "INTEGER DEFAULT 0"},
+	{"thin_level", "INTEGER DEFAULT 0"},
+}
+
+// migrateSchema adds columns introduced after the initial schema to
+// existing databases.
+func (s *SQLiteStorage) migrateSchema(ctx context.Context) error {
+	columns, err := s.resultColumns(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sqliteColumnMigrations {
+		if columns[m.column] {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE test_results ADD COLUMN %s %s", m.column, m.definition)
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", m.column, err)
+		}
+	}
+
+	return s.normalizeTimestamps(ctx)
+}
+
+// migrationPending reports whether an existing database needs columns
+// added or timestamps rewritten. A new database has nothing to migrate.
+func (s *SQLiteStorage) migrationPending(ctx context.Context) (bool, error) {
+	columns, err := s.resultColumns(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(columns) == 0 {
+		return false, nil
+	}
+
+	for _, m := range sqliteColumnMigrations {
+		if !columns[m.column] {
+			return true, nil
+		}
+	}
+
+	var pending bool
+	err = s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM test_results WHERE created_at NOT LIKE '% +0000 UTC')").Scan(&pending)
+	if err != nil {
+		return false, fmt.Errorf("failed to read timestamps: %w", err)
+	}
+	return pending, nil
+}
+
+// backupDatabase copies the database to <path>.bak, replacing an older
+// backup. VACUUM INTO reads a consistent snapshot, so the copy is safe while
+// the database is in use; it is written to a temporary file first so a
+// failed backup never replaces the previous one.
+func (s *SQLiteStorage) backupDatabase(ctx context.Context, reason string) error {
+	dest := s.path + ".bak"
+	tmp := dest + ".tmp"
+
+	// VACUUM INTO fails if the target exists, e.g. after an interrupted backup
+	_ = os.Remove(tmp)
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to back up database before %s: %w", reason, err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to back up database before %s: %w", reason, err)
+	}
+
+	logger.Info("Backed up database",
+		zap.String("backup", dest),
+		zap.String("reason", reason),
+	)
+	return nil
+}
+
+// resultColumns returns the columns of the test_results table, or none if
+// the table doesn't exist yet.
+func (s *SQLiteStorage) resultColumns(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "PRAGMA table_info(test_results)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table info: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table info: %w", err)
+	}
+	return columns, nil
+}
+
+// normalizeTimestamps rewrites timestamps stored by older versions with the
+// local UTC offset (e.g. "+0100 CET") in UTC. created_at is compared as text,
+// so mixed offsets made time filters off by hours, e.g. around DST changes.
+func (s *SQLiteStorage) normalizeTimestamps(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, created_at FROM test_results WHERE created_at NOT LIKE '% +0000 UTC'")
+	if err != nil {
+		return fmt.Errorf("failed to read timestamps: %w", err)
+	}
+
+	updates := make(map[int64]time.Time)
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan timestamp: %w", err)
+		}
+		updates[id] = utc(createdAt)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read timestamps: %w", err)
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE test_results SET created_at = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare timestamp update: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for id, createdAt := range updates {
+		if _, err := stmt.ExecContext(ctx, createdAt, id); err != nil {
+			return fmt.Errorf("failed to update timestamp of result %d: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Close closes the database connection.
@@ -97,14 +348,28 @@ func (s *SQLiteStorage) Close() error {
 	return nil
 }
 
+// Ping checks that the database is reachable.
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
 // SaveResult saves a speedtest result to the database.
 func (s *SQLiteStorage) SaveResult(ctx context.Context, result *TestResult) error {
 	query := `
 	INSERT INTO test_results (
 		connection_name, server_id, server_name, server_country, server_host,
 		latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		source_ip, dscp, error, created_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		source_ip, dscp, error, created_at, server_distance_km,
+		latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	res, err := s.db.ExecContext(ctx, query,
@@ -121,7 +386,21 @@ func (s *SQLiteStorage) SaveResult(ctx context.Context, result *TestResult) erro
 		result.SourceIP,
 		result.DSCP,
 		result.Error,
-		result.CreatedAt,
+		utc(result.CreatedAt),
+		result.ServerDistanceKm,
+		result.LatencyOK,
+		result.DownloadOK,
+		result.UploadOK,
+		result.Attempts,
+		result.BytesDownloaded,
+		result.BytesUploaded,
+		result.IPFamily,
+		result.ConfigHash,
+		result.ConnectMs,
+		result.DownloadSize,
+		result.UploadSize,
+		result.Source,
+		result.Threads,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert result: %w", err)
@@ -136,12 +415,116 @@ func (s *SQLiteStorage) SaveResult(ctx context.Context, result *TestResult) erro
 	return nil
 }
 
+// SaveResults saves multiple results in a single transaction using a
+// prepared statement. If the transaction fails, it falls back to saving
+// the results one at a time and returns the errors of those that failed.
+func (s *SQLiteStorage) SaveResults(ctx context.Context, results []*TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	if err := s.SaveResultsAtomic(ctx, results); err == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, result := range results {
+		if err := s.SaveResult(ctx, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SaveResultsAtomic saves multiple results in a single transaction using a
+// prepared statement, so either all of them are saved or none.
+func (s *SQLiteStorage) SaveResultsAtomic(ctx context.Context, results []*TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO test_results (
+		connection_name, server_id, server_name, server_country, server_host,
+		latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
+		source_ip, dscp, error, created_at, server_distance_km,
+		latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	ids := make([]int64, len(results))
+	for i, result := range results {
+		res, err := stmt.ExecContext(ctx,
+			result.ConnectionName,
+			result.ServerID,
+			result.ServerName,
+			result.ServerCountry,
+			result.ServerHost,
+			result.LatencyMs,
+			result.JitterMs,
+			result.DownloadMbps,
+			result.UploadMbps,
+			result.PacketLossPct,
+			result.SourceIP,
+			result.DSCP,
+			result.Error,
+			utc(result.CreatedAt),
+			result.ServerDistanceKm,
+			result.LatencyOK,
+			result.DownloadOK,
+			result.UploadOK,
+			result.Attempts,
+			result.BytesDownloaded,
+			result.BytesUploaded,
+			result.IPFamily,
+			result.ConfigHash,
+			result.ConnectMs,
+			result.DownloadSize,
+			result.UploadSize,
+			result.Source,
+			result.Threads,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert result: %w", err)
+		}
+		if ids[i], err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Only assign IDs once the rows are committed
+	for i, result := range results {
+		result.ID = ids[i]
+	}
+
+	return nil
+}
+
 // GetResult retrieves a single result by ID.
 func (s *SQLiteStorage) GetResult(ctx context.Context, id int64) (*TestResult, error) {
 	query := `
 	SELECT id, connection_name, server_id, server_name, server_country, server_host,
 		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		   source_ip, dscp, error, created_at
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
 	FROM test_results
 	WHERE id = ?
 	`
@@ -163,6 +546,20 @@ func (s *SQLiteStorage) GetResult(ctx context.Context, id int64) (*TestResult, e
 		&result.DSCP,
 		&result.Error,
 		&result.CreatedAt,
+		&result.ServerDistanceKm,
+		&result.LatencyOK,
+		&result.DownloadOK,
+		&result.UploadOK,
+		&result.Attempts,
+		&result.BytesDownloaded,
+		&result.BytesUploaded,
+		&result.IPFamily,
+		&result.ConfigHash,
+		&result.ConnectMs,
+		&result.DownloadSize,
+		&result.UploadSize,
+		&result.Source,
+		&result.Threads,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("result not found: %d", id)
@@ -170,16 +567,33 @@ func (s *SQLiteStorage) GetResult(ctx context.Context, id int64) (*TestResult, e
 	if err != nil {
 		return nil, fmt.Errorf("failed to get result: %w", err)
 	}
+	result.CreatedAt = utc(result.CreatedAt)
 
 	return result, nil
 }
 
 // GetResults retrieves results based on filter criteria.
 func (s *SQLiteStorage) GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error) {
+	return s.queryResults(ctx, filter, nil)
+}
+
+// GetResultsCursor retrieves results with an ID below beforeID (0 = from the
+// newest result), ordered by ID descending. Unlike offset pagination, pages
+// stay stable while new results are inserted.
+func (s *SQLiteStorage) GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error) {
+	return s.queryResults(ctx, filter, &beforeID)
+}
+
+// queryResults retrieves results matching filter. With a cursor, results are
+// paged by ID (keyset pagination) instead of by created_at and offset.
+func (s *SQLiteStorage) queryResults(ctx context.Context, filter ResultFilter, beforeID *int64) ([]TestResult, error) {
 	query := `
 	SELECT id, connection_name, server_id, server_name, server_country, server_host,
 		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
-		   source_ip, dscp, error, created_at
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
 	FROM test_results
 	WHERE 1=1
 	`
@@ -190,24 +604,56 @@ func (s *SQLiteStorage) GetResults(ctx context.Context, filter ResultFilter) ([]
 		args = append(args, filter.ConnectionName)
 	}
 
+	if len(filter.ConnectionNames) > 0 {
+		query += " AND connection_name IN (?" + strings.Repeat(", ?", len(filter.ConnectionNames)-1) + ")"
+		for _, name := range filter.ConnectionNames {
+			args = append(args, name)
+		}
+	}
+
 	if !filter.Since.IsZero() {
 		query += " AND created_at >= ?"
-		args = append(args, filter.Since)
+		args = append(args, utc(filter.Since))
 	}
 
 	if !filter.Until.IsZero() {
 		query += " AND created_at <= ?"
-		args = append(args, filter.Until)
+		args = append(args, utc(filter.Until))
 	}
 
-	query += " ORDER BY created_at DESC"
+	if filter.ErrorOnly != nil {
+		if *filter.ErrorOnly {
+			query += " AND error != ''"
+		} else {
+			query += " AND error = ''"
+		}
+	}
 
-	if filter.Limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, filter.Limit)
+	if filter.Source != "" {
+		query += " AND source = ?"
+		args = append(args, filter.Source)
 	}
 
-	if filter.Offset > 0 {
+	if filter.ConfigHash != "" {
+		query += " AND config_hash = ?"
+		args = append(args, filter.ConfigHash)
+	}
+
+	if beforeID != nil && *beforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, *beforeID)
+	}
+
+	if beforeID != nil {
+		query += " ORDER BY id DESC"
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
+
+	query += " LIMIT ?"
+	args = append(args, clampLimit(filter.Limit))
+
+	if beforeID == nil && filter.Offset > 0 {
 		query += " OFFSET ?"
 		args = append(args, filter.Offset)
 	}
@@ -237,10 +683,25 @@ func (s *SQLiteStorage) GetResults(ctx context.Context, filter ResultFilter) ([]
 			&r.DSCP,
 			&r.Error,
 			&r.CreatedAt,
+			&r.ServerDistanceKm,
+			&r.LatencyOK,
+			&r.DownloadOK,
+			&r.UploadOK,
+			&r.Attempts,
+			&r.BytesDownloaded,
+			&r.BytesUploaded,
+			&r.IPFamily,
+			&r.ConfigHash,
+			&r.ConnectMs,
+			&r.DownloadSize,
+			&r.UploadSize,
+			&r.Source,
+			&r.Threads,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		r.CreatedAt = utc(r.CreatedAt)
 		results = append(results, r)
 	}
 
@@ -251,12 +712,14 @@ func (s *SQLiteStorage) GetResults(ctx context.Context, filter ResultFilter) ([]
 	return results, nil
 }
 
-// GetLatestResults retrieves the most recent result for each connection.
-func (s *SQLiteStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
-	query := `
+// latestResultsQuerySQLite selects the most recent result for each connection.
+const latestResultsQuerySQLite = `
 	SELECT t.id, t.connection_name, t.server_id, t.server_name, t.server_country, t.server_host,
 		   t.latency_ms, t.jitter_ms, t.download_mbps, t.upload_mbps, t.packet_loss_pct,
-		   t.source_ip, t.dscp, t.error, t.created_at
+		   t.source_ip, t.dscp, t.error, t.created_at, t.server_distance_km,
+		   t.latency_ok, t.download_ok, t.upload_ok, t.attempts,
+		   t.bytes_downloaded, t.bytes_uploaded, t.ip_family, t.config_hash, t.connect_ms,
+		   t.download_size, t.upload_size, t.source, t.threads
 	FROM test_results t
 	INNER JOIN (
 		SELECT connection_name, MAX(created_at) as max_created
@@ -266,7 +729,9 @@ func (s *SQLiteStorage) GetLatestResults(ctx context.Context) ([]TestResult, err
 	ORDER BY t.connection_name
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+// GetLatestResults retrieves the most recent result for each connection.
+func (s *SQLiteStorage) GetLatestResults(ctx context.Context) ([]TestResult, error) {
+	rows, err := s.db.QueryContext(ctx, latestResultsQuerySQLite)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query latest results: %w", err)
 	}
@@ -291,34 +756,122 @@ func (s *SQLiteStorage) GetLatestResults(ctx context.Context) ([]TestResult, err
 			&r.DSCP,
 			&r.Error,
 			&r.CreatedAt,
+			&r.ServerDistanceKm,
+			&r.LatencyOK,
+			&r.DownloadOK,
+			&r.UploadOK,
+			&r.Attempts,
+			&r.BytesDownloaded,
+			&r.BytesUploaded,
+			&r.IPFamily,
+			&r.ConfigHash,
+			&r.ConnectMs,
+			&r.DownloadSize,
+			&r.UploadSize,
+			&r.Source,
+			&r.Threads,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		r.CreatedAt = utc(r.CreatedAt)
 		results = append(results, r)
 	}
 
 	return results, nil
 }
 
+// GetLatestResult retrieves the single most recent result across all connections.
+// Returns nil if no results exist.
+func (s *SQLiteStorage) GetLatestResult(ctx context.Context) (*TestResult, error) {
+	query := `
+	SELECT id, connection_name, server_id, server_name, server_country, server_host,
+		   latency_ms, jitter_ms, download_mbps, upload_mbps, packet_loss_pct,
+		   source_ip, dscp, error, created_at, server_distance_km,
+		   latency_ok, download_ok, upload_ok, attempts,
+		bytes_downloaded, bytes_uploaded, ip_family, config_hash, connect_ms,
+		download_size, upload_size, source, threads
+	FROM test_results
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	result := &TestResult{}
+	err := s.db.QueryRowContext(ctx, query).Scan(
+		&result.ID,
+		&result.ConnectionName,
+		&result.ServerID,
+		&result.ServerName,
+		&result.ServerCountry,
+		&result.ServerHost,
+		&result.LatencyMs,
+		&result.JitterMs,
+		&result.DownloadMbps,
+		&result.UploadMbps,
+		&result.PacketLossPct,
+		&result.SourceIP,
+		&result.DSCP,
+		&result.Error,
+		&result.CreatedAt,
+		&result.ServerDistanceKm,
+		&result.LatencyOK,
+		&result.DownloadOK,
+		&result.UploadOK,
+		&result.Attempts,
+		&result.BytesDownloaded,
+		&result.BytesUploaded,
+		&result.IPFamily,
+		&result.ConfigHash,
+		&result.ConnectMs,
+		&result.DownloadSize,
+		&result.UploadSize,
+		&result.Source,
+		&result.Threads,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest result: %w", err)
+	}
+	result.CreatedAt = utc(result.CreatedAt)
+
+	return result, nil
+}
+
 // GetStats calculates statistics for a connection over a time period.
 func (s *SQLiteStorage) GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error) {
-	since := time.Now().Add(-period)
-	until := time.Now()
+	until := utc(time.Now())
+	return s.GetStatsBetween(ctx, connectionName, until.Add(-period), until)
+}
+
+// GetStatsBetween calculates statistics for a connection between since and
+// until.
+func (s *SQLiteStorage) GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error) {
+	since, until = utc(since), utc(until)
+	period := until.Sub(since)
 
 	query := `
 	SELECT 
 		COUNT(*) as test_count,
 		COUNT(CASE WHEN error != '' THEN 1 END) as error_count,
-		AVG(CASE WHEN error = '' THEN download_mbps END) as avg_download,
-		AVG(CASE WHEN error = '' THEN upload_mbps END) as avg_upload,
-		AVG(CASE WHEN error = '' THEN latency_ms END) as avg_latency,
-		MIN(CASE WHEN error = '' THEN download_mbps END) as min_download,
-		MAX(CASE WHEN error = '' THEN download_mbps END) as max_download,
-		MIN(CASE WHEN error = '' THEN upload_mbps END) as min_upload,
-		MAX(CASE WHEN error = '' THEN upload_mbps END) as max_upload,
-		MIN(CASE WHEN error = '' THEN latency_ms END) as min_latency,
-		MAX(CASE WHEN error = '' THEN latency_ms END) as max_latency
+		AVG(CASE WHEN error = '' AND download_ok THEN download_mbps END) as avg_download,
+		AVG(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as avg_upload,
+		AVG(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as avg_latency,
+		MIN(CASE WHEN error = '' AND download_ok THEN download_mbps END) as min_download,
+		MAX(CASE WHEN error = '' AND download_ok THEN download_mbps END) as max_download,
+		MIN(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as min_upload,
+		MAX(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) as max_upload,
+		MIN(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as min_latency,
+		MAX(CASE WHEN error = '' AND latency_ok THEN latency_ms END) as max_latency,
+		AVG(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as avg_jitter,
+		MIN(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as min_jitter,
+		MAX(CASE WHEN error = '' AND latency_ok THEN jitter_ms END) as max_jitter,
+		COALESCE(SUM(bytes_downloaded), 0) as bytes_downloaded,
+		COALESCE(SUM(bytes_uploaded), 0) as bytes_uploaded,
+		COUNT(CASE WHEN error = '' AND download_ok THEN 1 END) as download_samples,
+		COUNT(CASE WHEN error = '' AND upload_ok THEN 1 END) as upload_samples,
+		COUNT(CASE WHEN error = '' AND latency_ok THEN 1 END) as latency_samples
 	FROM test_results
 	WHERE connection_name = ? AND created_at >= ? AND created_at <= ?
 	`
@@ -332,6 +885,7 @@ func (s *SQLiteStorage) GetStats(ctx context.Context, connectionName string, per
 
 	var avgDownload, avgUpload, avgLatency sql.NullFloat64
 	var minDownload, maxDownload, minUpload, maxUpload, minLatency, maxLatency sql.NullFloat64
+	var avgJitter, minJitter, maxJitter sql.NullFloat64
 
 	err := s.db.QueryRowContext(ctx, query, connectionName, since, until).Scan(
 		&stats.TestCount,
@@ -345,6 +899,14 @@ func (s *SQLiteStorage) GetStats(ctx context.Context, connectionName string, per
 		&maxUpload,
 		&minLatency,
 		&maxLatency,
+		&avgJitter,
+		&minJitter,
+		&maxJitter,
+		&stats.BytesDownloaded,
+		&stats.BytesUploaded,
+		&stats.downloadSamples,
+		&stats.uploadSamples,
+		&stats.latencySamples,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
@@ -377,15 +939,260 @@ func (s *SQLiteStorage) GetStats(ctx context.Context, connectionName string, per
 	if maxLatency.Valid {
 		stats.MaxLatency = maxLatency.Float64
 	}
+	if avgJitter.Valid {
+		stats.AvgJitter = avgJitter.Float64
+	}
+	if minJitter.Valid {
+		stats.MinJitter = minJitter.Float64
+	}
+	if maxJitter.Valid {
+		stats.MaxJitter = maxJitter.Float64
+	}
+	stats.computeAvailability()
+
+	if err := s.getStatsExtremeTimes(ctx, stats, connectionName, since, until); err != nil {
+		return nil, err
+	}
+
+	rollups, err := queryRollups(ctx, s.db, rollupsBetweenQuerySQLite, connectionName, since, until)
+	if err != nil {
+		return nil, err
+	}
+	mergeRollupStats(stats, rollups)
 
 	return stats, nil
 }
 
-// DeleteOldResults removes results older than the specified time.
-func (s *SQLiteStorage) DeleteOldResults(ctx context.Context, olderThan time.Time) (int64, error) {
+// statsExtremeTimesQuerySQLite selects the time of the most recent test with
+// each extreme value, per metric: min/max download, upload and latency.
+const statsExtremeTimesQuerySQLite = `
+	WITH ok AS (
+		SELECT created_at, download_mbps, upload_mbps, latency_ms, download_ok, upload_ok, latency_ok
+		FROM test_results
+		WHERE connection_name = ? AND created_at >= ? AND created_at <= ? AND error = ''
+	)
+	SELECT
+		(SELECT created_at FROM ok WHERE download_ok ORDER BY download_mbps ASC, created_at DESC LIMIT 1),
+		(SELECT created_at FROM ok WHERE download_ok ORDER BY download_mbps DESC, created_at DESC LIMIT 1),
+		(SELECT created_at FROM ok WHERE upload_ok ORDER BY upload_mbps ASC, created_at DESC LIMIT 1),
+		(SELECT created_at FROM ok WHERE upload_ok ORDER BY upload_mbps DESC, created_at DESC LIMIT 1),
+		(SELECT created_at FROM ok WHERE latency_ok ORDER BY latency_ms ASC, created_at DESC LIMIT 1),
+		(SELECT created_at FROM ok WHERE latency_ok ORDER BY latency_ms DESC, created_at DESC LIMIT 1)
+	`
+
+// getStatsExtremeTimes sets the times at which the extreme values of the
+// stats occurred, with a secondary query.
+func (s *SQLiteStorage) getStatsExtremeTimes(ctx context.Context, stats *Stats, connectionName string, since, until time.Time) error {
+	values := make([]sql.NullTime, 6)
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := s.db.QueryRowContext(ctx, statsExtremeTimesQuerySQLite, connectionName, since, until).Scan(dest...); err != nil {
+		return fmt.Errorf("failed to get stats extreme times: %w", err)
+	}
+	stats.setExtremeTimes(values)
+	return nil
+}
+
+// sqliteCreatedAtTimeString converts created_at to a time string SQLite's date
+// functions understand. The driver stores timestamps in Go's time.Time.String()
+// format ("2024-01-15 14:30:00.123456789 +0100 CET"), which they can't parse:
+// keep the seconds-precision date and time and convert the "+0100" offset to
+// "+01:00" (it starts after the first space following the time).
+const sqliteCreatedAtTimeString = `(substr(created_at, 1, 19) ||
+		substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' ||
+		substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2))`
+
+// GetTrends returns the statistics of a connection over the given period,
+// bucketed by groupBy (TrendHourOfDay or TrendDayOfWeek) in the timezone loc.
+func (s *SQLiteStorage) GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error) {
+	var format string
+	switch groupBy {
+	case TrendHourOfDay:
+		format = "%H"
+	case TrendDayOfWeek:
+		format = "%w"
+	default:
+		return nil, fmt.Errorf("unknown trend grouping: %s", groupBy)
+	}
+
+	until := utc(time.Now())
+	since := until.Add(-period)
+
+	// strftime converts to UTC, the modifier shifts to the local time
+	query := `
+	SELECT
+		CAST(strftime(?, ` + sqliteCreatedAtTimeString + `, ?) AS INTEGER) AS bucket,` + trendAggregatesSQL + `
+	FROM test_results
+	WHERE connection_name = ? AND created_at >= ? AND created_at <= ?
+	GROUP BY bucket
+	`
+	modifier := fmt.Sprintf("%+d seconds", utcOffsetSeconds(loc))
+
+	rows, err := s.db.QueryContext(ctx, query, format, modifier, connectionName, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trends: %w", err)
+	}
+	defer rows.Close()
+
+	buckets, err := scanTrendBuckets(rows, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := queryRollups(ctx, s.db, rollupsBetweenQuerySQLite, connectionName, since, until)
+	if err != nil {
+		return nil, err
+	}
+	mergeRollupTrends(buckets, rollups, offsetBucketFunc(groupBy, loc))
+
+	return buckets, nil
+}
+
+// GetServerDownloads returns the average download in Mbps per server ID of
+// a connection's successful tests since the given time, limited to results of
+// the given IP family.
+func (s *SQLiteStorage) GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error) {
+	query := `
+	SELECT server_id, AVG(download_mbps)
+	FROM test_results
+	WHERE connection_name = ? AND ip_family = ? AND created_at >= ?
+		AND error = '' AND download_ok AND server_id > 0
+	GROUP BY server_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, connectionName, ipFamily, utc(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server downloads: %w", err)
+	}
+	defer rows.Close()
+
+	downloads := make(map[int]float64)
+	for rows.Next() {
+		var serverID int
+		var avgDownload float64
+		if err := rows.Scan(&serverID, &avgDownload); err != nil {
+			return nil, fmt.Errorf("failed to scan server download: %w", err)
+		}
+		downloads[serverID] = avgDownload
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate server downloads: %w", err)
+	}
+
+	return downloads, nil
+}
+
+// GetBaseline returns the median download and upload of a connection's
+// successful tests since the given time at the given hour of day in the
+// timezone loc, limited to results of the given IP family.
+func (s *SQLiteStorage) GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error) {
+	// strftime converts to UTC, the modifier shifts to the local time
+	query := `
+	SELECT download_mbps, download_ok, upload_mbps, upload_ok
+	FROM test_results
+	WHERE connection_name = ? AND ip_family = ? AND created_at >= ? AND error = ''
+		AND CAST(strftime('%H', ` + sqliteCreatedAtTimeString + `, ?) AS INTEGER) = ?
+	`
+	modifier := fmt.Sprintf("%+d seconds", utcOffsetSeconds(loc))
+
+	rows, err := s.db.QueryContext(ctx, query, connectionName, ipFamily, utc(since), modifier, hour)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBaseline(rows, hour)
+}
+
+// SaveConnectionEvent saves a connection health event.
+func (s *SQLiteStorage) SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	query := `
+	INSERT INTO connection_events (connection_name, ip_family, from_state, to_state, reason, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		event.ConnectionName, event.IPFamily, event.FromState, event.ToState, event.Reason, utc(event.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save connection event: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	event.ID = id
+
+	return nil
+}
+
+// GetConnectionEvents returns a connection's health events since the given
+// time (zero = all), newest first, at most limit (0 = no limit).
+func (s *SQLiteStorage) GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error) {
+	query := `
+	SELECT id, connection_name, ip_family, from_state, to_state, reason, created_at
+	FROM connection_events
+	WHERE connection_name = ?`
+	args := []interface{}{connectionName}
+
+	if !since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, utc(since))
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanConnectionEvents(rows)
+}
+
+// CountOldResults returns the number of results older than the specified time,
+// optionally limited to a single connection.
+func (s *SQLiteStorage) CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	query := "SELECT COUNT(*) FROM test_results WHERE created_at < ?"
+	args := []interface{}{utc(olderThan)}
+
+	if connectionName != "" {
+		query += " AND connection_name = ?"
+		args = append(args, connectionName)
+	}
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count old results: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteOldResults removes results older than the specified time,
+// optionally limited to a single connection.
+func (s *SQLiteStorage) DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error) {
+	if s.backup {
+		if err := s.backupDatabase(ctx, "delete"); err != nil {
+			return 0, err
+		}
+	}
+
 	query := "DELETE FROM test_results WHERE created_at < ?"
+	args := []interface{}{utc(olderThan)}
 
-	result, err := s.db.ExecContext(ctx, query, olderThan)
+	if connectionName != "" {
+		query += " AND connection_name = ?"
+		args = append(args, connectionName)
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old results: %w", err)
 	}
@@ -398,3 +1205,69 @@ func (s *SQLiteStorage) DeleteOldResults(ctx context.Context, olderThan time.Tim
 	return count, nil
 }
 
+// rollupsBetweenQuerySQLite selects a connection's rollups of the hours
+// starting in a period.
+const rollupsBetweenQuerySQLite = `SELECT ` + rollupColumnsSQL + `
+	FROM hourly_rollups
+	WHERE connection_name = ? AND hour >= ? AND hour <= ?
+	ORDER BY hour`
+
+// rollupQueriesSQLite are the statements of RollupResults.
+var rollupQueriesSQLite = rollupQueries{
+	selectResults: "SELECT " + rollupSourceColumnsSQL + " FROM test_results WHERE created_at < ? ORDER BY created_at, id",
+	selectRollups: "SELECT " + rollupColumnsSQL + " FROM hourly_rollups WHERE hour >= ? AND hour < ?",
+	upsert: "INSERT INTO hourly_rollups (" + rollupColumnsSQL + `)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)` + rollupUpsertSQL,
+	deleteResults: "DELETE FROM test_results WHERE created_at < ?",
+}
+
+// RollupResults aggregates all results created before the start of the
+// hour of olderThan into hourly rollups and deletes them, in one
+// transaction. Like DeleteOldResults, it backs up the database first if
+// enabled, as the raw results are gone afterwards.
+func (s *SQLiteStorage) RollupResults(ctx context.Context, olderThan time.Time) (int64, error) {
+	if s.backup {
+		if err := s.backupDatabase(ctx, "rollup"); err != nil {
+			return 0, err
+		}
+	}
+
+	return rollUp(ctx, s.db, rollupQueriesSQLite, olderThan)
+}
+
+// DeleteOldRollups removes rollups of hours before the specified time.
+func (s *SQLiteStorage) DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM hourly_rollups WHERE hour < ?", utc(olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old rollups: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// thinQueriesSQLite are the statements of ThinResults.
+var thinQueriesSQLite = thinQueries{
+	selectResults: `SELECT id, connection_name, ip_family FROM test_results
+	WHERE created_at < ? AND COALESCE(thin_level, 0) < ? ORDER BY created_at, id`,
+	mark:         "UPDATE test_results SET thin_level = ? WHERE id = ?",
+	deleteResult: "DELETE FROM test_results WHERE id = ?",
+}
+
+// ThinResults keeps every keepEvery-th result of each connection and IP
+// family created before olderThan that hasn't been thinned to level yet,
+// and deletes the others, in one transaction. Like DeleteOldResults, it
+// backs up the database first if enabled.
+func (s *SQLiteStorage) ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error) {
+	if s.backup {
+		if err := s.backupDatabase(ctx, "thinning"); err != nil {
+			return 0, err
+		}
+	}
+
+	return thinResults(ctx, s.db, thinQueriesSQLite, olderThan, keepEvery, level)
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/lan-dot-party/flowgauge/internal/config"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "flowgauge.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// TestSQLiteTimezoneRoundTrip saves results with timestamps in several
+// zones, including both sides of DST changes, and checks that they are read
+// back as the same instants in UTC and filtered by instant.
+func TestSQLiteTimezoneRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	newYork := mustLoadLocation(t, "America/New_York")
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+
+	// Chronological; the Berlin times are on both sides of the changes to
+	// and from summer time in 2024, where 02:30 is skipped and repeated
+	times := []time.Time{
+		time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC).In(berlin),  // 01:30 CET
+		time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC).In(berlin),  // 03:30 CEST
+		time.Date(2024, 3, 31, 6, 45, 0, 0, time.UTC).In(newYork), // 02:45 EDT
+		time.Date(2024, 6, 15, 12, 0, 0, 123000000, time.UTC).In(kolkata),
+		time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).In(berlin), // 02:30 CEST
+		time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC).In(berlin), // 02:30 CET
+		time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC).In(newYork), // 01:30 EDT
+		time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC).In(newYork), // 01:30 EST
+		time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC).In(time.FixedZone("", -(9*3600 + 30*60))),
+	}
+
+	saved := make(map[int64]time.Time, len(times))
+	for _, at := range times {
+		result := &TestResult{
+			ConnectionName: "WAN1",
+			DownloadMbps:   100,
+			DownloadOK:     true,
+			CreatedAt:      at,
+		}
+		if err := s.SaveResult(ctx, result); err != nil {
+			t.Fatalf("SaveResult(%v): %v", at, err)
+		}
+		saved[result.ID] = at
+	}
+
+	results, err := s.GetResults(ctx, ResultFilter{ConnectionName: "WAN1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(times) {
+		t.Fatalf("got %d results, want %d", len(results), len(times))
+	}
+	for _, r := range results {
+		want, ok := saved[r.ID]
+		if !ok {
+			t.Fatalf("unexpected result ID %d", r.ID)
+		}
+		if !r.CreatedAt.Equal(want) {
+			t.Errorf("result %d: CreatedAt = %v, want %v", r.ID, r.CreatedAt, want.UTC())
+		}
+		if r.CreatedAt.Location() != time.UTC {
+			t.Errorf("result %d: CreatedAt in %v, want UTC", r.ID, r.CreatedAt.Location())
+		}
+	}
+
+	tests := []struct {
+		name         string
+		since, until time.Time
+		want         []time.Time
+	}{
+		{
+			name:  "across spring forward",
+			since: times[0].In(newYork),
+			until: times[1].In(kolkata),
+			want:  times[0:2],
+		},
+		{
+			name:  "repeated hour, first pass only",
+			since: times[4],
+			until: times[5].Add(-time.Second),
+			want:  times[4:5],
+		},
+		{
+			name:  "repeated hour, second pass only",
+			since: times[4].Add(time.Second),
+			until: times[5].In(time.UTC),
+			want:  times[5:6],
+		},
+		{
+			name:  "both passes of the repeated New York hour",
+			since: times[6].In(berlin),
+			until: times[7].In(berlin),
+			want:  times[6:8],
+		},
+		{
+			name:  "since only",
+			since: times[7].Add(time.Nanosecond),
+			want:  times[8:],
+		},
+		{
+			name:  "until only",
+			until: times[2].In(time.UTC),
+			want:  times[0:3],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.GetResults(ctx, ResultFilter{
+				ConnectionName: "WAN1",
+				Since:          tt.since,
+				Until:          tt.until,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.want))
+			}
+			// Results are returned newest first
+			for i, r := range results {
+				want := tt.want[len(tt.want)-1-i]
+				if !r.CreatedAt.Equal(want) {
+					t.Errorf("result %d: CreatedAt = %v, want %v", i, r.CreatedAt, want.UTC())
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -14,27 +15,97 @@ type Storage interface {
 	// Lifecycle
 	Init(ctx context.Context) error
 	Close() error
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
 
 	// Results
 	SaveResult(ctx context.Context, result *TestResult) error
+	// SaveResults saves results in bulk; if saving them together fails,
+	// they are saved one at a time, so a bad row doesn't fail the others
+	SaveResults(ctx context.Context, results []*TestResult) error
+	// SaveResultsAtomic saves all results in one transaction: if it fails,
+	// none of them is saved
+	SaveResultsAtomic(ctx context.Context, results []*TestResult) error
 	GetResult(ctx context.Context, id int64) (*TestResult, error)
 	GetResults(ctx context.Context, filter ResultFilter) ([]TestResult, error)
+	// GetResultsCursor returns results with an ID below beforeID (0 = from the
+	// newest result), ordered by ID descending. filter.Offset is ignored.
+	GetResultsCursor(ctx context.Context, filter ResultFilter, beforeID int64) ([]TestResult, error)
 	GetLatestResults(ctx context.Context) ([]TestResult, error)
+	GetLatestResult(ctx context.Context) (*TestResult, error)
 
 	// Stats
 	GetStats(ctx context.Context, connectionName string, period time.Duration) (*Stats, error)
+	// GetStatsBetween is like GetStats for the period between since and
+	// until, e.g. to compare with a previous period
+	GetStatsBetween(ctx context.Context, connectionName string, since, until time.Time) (*Stats, error)
+	// GetTrends buckets a connection's results by hour of day or day of week
+	// (see TrendHourOfDay), in the timezone loc
+	GetTrends(ctx context.Context, connectionName, groupBy string, period time.Duration, loc *time.Location) ([]TrendBucket, error)
+	// GetServerDownloads returns the average download in Mbps per server ID
+	// of a connection's successful tests since the given time, limited to
+	// results of the given IP family ("" for single-stack connections)
+	GetServerDownloads(ctx context.Context, connectionName, ipFamily string, since time.Time) (map[int]float64, error)
+	// GetBaseline returns the median download and upload of a connection's
+	// successful tests since the given time at the given hour of day in the
+	// timezone loc, limited to results of the given IP family
+	GetBaseline(ctx context.Context, connectionName, ipFamily string, hour int, since time.Time, loc *time.Location) (*Baseline, error)
 
-	// Cleanup
-	DeleteOldResults(ctx context.Context, olderThan time.Time) (int64, error)
+	// Connection health events
+	SaveConnectionEvent(ctx context.Context, event *ConnectionEvent) error
+	// GetConnectionEvents returns a connection's events since the given time
+	// (zero = all), newest first, at most limit (0 = no limit)
+	GetConnectionEvents(ctx context.Context, connectionName string, since time.Time, limit int) ([]ConnectionEvent, error)
+
+	// Cleanup (connectionName is optional; empty matches all connections)
+	CountOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error)
+	DeleteOldResults(ctx context.Context, olderThan time.Time, connectionName string) (int64, error)
+
+	// Rollups
+	// RollupResults aggregates all results created before the start of the
+	// hour of olderThan into hourly rollups, which GetStats and GetTrends
+	// include, and deletes them. It returns the number of results rolled up.
+	RollupResults(ctx context.Context, olderThan time.Time) (int64, error)
+	// DeleteOldRollups removes rollups of hours before the specified time
+	DeleteOldRollups(ctx context.Context, olderThan time.Time) (int64, error)
+
+	// Thinning
+	// ThinResults keeps every keepEvery-th result of each connection and IP
+	// family created before olderThan that hasn't been thinned to level yet,
+	// marks the kept results with level and deletes the others. It returns
+	// the number of results deleted.
+	ThinResults(ctx context.Context, olderThan time.Time, keepEvery, level int) (int64, error)
+}
+
+// saveBatchSize is the number of rows inserted per statement by SaveResults.
+// With 28 columns this stays well below PostgreSQL's 65535 parameter limit.
+const saveBatchSize = 1000
+
+// utc returns t in UTC (without its monotonic clock reading). Timestamps are
+// stored, compared and returned in UTC, so filters match regardless of the
+// local timezone and its DST offset; callers convert them for display.
+func utc(t time.Time) time.Time {
+	return t.UTC()
 }
 
 // ResultFilter defines criteria for filtering results.
 type ResultFilter struct {
 	ConnectionName string
-	Since          time.Time
-	Until          time.Time
-	Limit          int
-	Offset         int
+	// ConnectionNames restricts results to any of the given connections (e.g. a group)
+	ConnectionNames []string
+	Since           time.Time
+	Until           time.Time
+	// ErrorOnly restricts results to failed (true) or successful (false)
+	// tests; nil matches all
+	ErrorOnly *bool
+	// ConfigHash restricts results to those produced with the given
+	// connection configuration (see config.ConnectionHash)
+	ConfigHash string
+	// Source restricts results to those of tests started by the given
+	// source (see speedtest.SourceScheduled)
+	Source string
+	Limit  int
+	Offset int
 }
 
 // Stats contains aggregated statistics for a connection.
@@ -49,22 +120,146 @@ type Stats struct {
 	MaxUpload      float64       `json:"max_upload_mbps"`
 	MinLatency     float64       `json:"min_latency_ms"`
 	MaxLatency     float64       `json:"max_latency_ms"`
+	AvgJitter      float64       `json:"avg_jitter_ms"`
+	MinJitter      float64       `json:"min_jitter_ms"`
+	MaxJitter      float64       `json:"max_jitter_ms"`
 	TestCount      int           `json:"test_count"`
 	ErrorCount     int           `json:"error_count"`
+	Availability   float64       `json:"availability"`
+	Uptime         float64       `json:"uptime_percent"`
 	Period         time.Duration `json:"period"`
 	Since          time.Time     `json:"since"`
 	Until          time.Time     `json:"until"`
+	// BytesDownloaded and BytesUploaded are the bytes transferred by all
+	// tests in the period, including failed ones
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+	// The times of the tests with the extreme values (the most recent one
+	// if tied); nil without successful tests
+	MinDownloadAt *time.Time `json:"min_download_at,omitempty"`
+	MaxDownloadAt *time.Time `json:"max_download_at,omitempty"`
+	MinUploadAt   *time.Time `json:"min_upload_at,omitempty"`
+	MaxUploadAt   *time.Time `json:"max_upload_at,omitempty"`
+	MinLatencyAt  *time.Time `json:"min_latency_at,omitempty"`
+	MaxLatencyAt  *time.Time `json:"max_latency_at,omitempty"`
+
+	// The number of values the averages were computed from, to merge
+	// rollups into them
+	downloadSamples, uploadSamples, latencySamples int
+}
+
+// InLocation converts the statistics' timestamps to the given location.
+func (s *Stats) InLocation(loc *time.Location) {
+	s.Since = s.Since.In(loc)
+	s.Until = s.Until.In(loc)
+	for _, t := range s.extremeTimes() {
+		if *t != nil {
+			local := (*t).In(loc)
+			*t = &local
+		}
+	}
+}
+
+// extremeTimes returns pointers to the timestamps of the extreme values, in
+// the column order of the extremes queries: min/max download, upload, latency.
+func (s *Stats) extremeTimes() []**time.Time {
+	return []**time.Time{
+		&s.MinDownloadAt, &s.MaxDownloadAt,
+		&s.MinUploadAt, &s.MaxUploadAt,
+		&s.MinLatencyAt, &s.MaxLatencyAt,
+	}
+}
+
+// setExtremeTimes sets the timestamps of the extreme values from the scanned
+// columns of an extremes query.
+func (s *Stats) setExtremeTimes(values []sql.NullTime) {
+	for i, t := range s.extremeTimes() {
+		if values[i].Valid {
+			at := utc(values[i].Time)
+			*t = &at
+		}
+	}
+}
+
+// computeAvailability sets Availability (ratio of successful tests, 0-1)
+// and Uptime (the same as a percentage) from the test counts.
+// Both stay 0 when there are no tests, instead of becoming NaN.
+func (s *Stats) computeAvailability() {
+	if s.TestCount <= 0 {
+		s.Availability = 0
+		s.Uptime = 0
+		return
+	}
+	s.Availability = float64(s.TestCount-s.ErrorCount) / float64(s.TestCount)
+	s.Uptime = s.Availability * 100
+}
+
+// clampLimit caps a query limit to config.MaxResultsLimitCeiling.
+// A limit <= 0 (no limit) is also capped, so no query can materialize an
+// unbounded number of rows.
+func clampLimit(limit int) int {
+	if limit <= 0 || limit > config.MaxResultsLimitCeiling {
+		return config.MaxResultsLimitCeiling
+	}
+	return limit
+}
+
+// CombineStats aggregates the stats of multiple connections into one.
+// Averages are weighted by each connection's number of samples per metric.
+func CombineStats(name string, stats []*Stats) *Stats {
+	combined := &Stats{ConnectionName: name}
+
+	var m metricAggregates
+	for _, st := range stats {
+		combined.Period = st.Period
+		combined.Since = st.Since
+		combined.Until = st.Until
+		m.merge(st.aggregates())
+	}
+	combined.setAggregates(m)
+
+	return combined
 }
 
 // NewStorage creates a new Storage instance based on the configuration.
+// With additional backends configured, it returns a MultiStorage. Queries
+// slower than storage.slow_query_threshold are logged.
 func NewStorage(cfg config.StorageConfig) (Storage, error) {
+	primary, err := newBackend(cfg.Primary(), cfg.BackupBeforeMigrate)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Backends) == 0 {
+		return withSlowQueryLog(primary, cfg.SlowQueryThreshold), nil
+	}
+	multi, err := NewMultiStorage(primary, cfg.Backends, cfg.BackupBeforeMigrate)
+	if err != nil {
+		return nil, err
+	}
+	return withSlowQueryLog(multi, cfg.SlowQueryThreshold), nil
+}
+
+// newBackend creates a single storage backend. With backup set, SQLite
+// databases are copied before migrations and deletes.
+func newBackend(cfg config.StorageBackendConfig, backup bool) (Storage, error) {
 	switch cfg.Type {
 	case "sqlite":
-		return NewSQLiteStorage(cfg.SQLite)
+		s, err := NewSQLiteStorage(cfg.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		s.backup = backup
+		return s, nil
 	case "postgres":
-		return NewPostgresStorage(cfg.Postgres)
+		s, err := NewPostgresStorage(cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		s.backup = backup
+		return s, nil
+	case "memory":
+		return NewMemoryStorage(cfg.Memory), nil
 	default:
 		return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
 	}
 }
-
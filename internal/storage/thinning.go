@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Results older than the age of a storage.thinning tier are thinned out by
+// ThinResults: of the results of each connection and IP family, every Nth is
+// kept and the others are deleted. Kept results are marked with the level of
+// the tier (the thin_level column), so a tier thins each result only once,
+// and the next tier thins the results kept by the previous one further.
+// Counting restarts with every run, so runs should cover many results each.
+
+// thinKey identifies the results that are thinned together.
+type thinKey struct {
+	connectionName string
+	ipFamily       string
+}
+
+// thinCounter decides which results to keep: the first and then every
+// keepEvery-th result of each connection and IP family. Results must be
+// passed oldest first.
+type thinCounter struct {
+	keepEvery int
+	seen      map[thinKey]int
+}
+
+func newThinCounter(keepEvery int) *thinCounter {
+	return &thinCounter{keepEvery: keepEvery, seen: make(map[thinKey]int)}
+}
+
+// keep counts a result and returns true if it is kept.
+func (c *thinCounter) keep(connectionName, ipFamily string) bool {
+	key := thinKey{connectionName, ipFamily}
+	n := c.seen[key]
+	c.seen[key] = n + 1
+	return n%c.keepEvery == 0
+}
+
+// thinQueries are the dialect-specific statements of thinResults.
+type thinQueries struct {
+	// selectResults selects id, connection_name and ip_family of the
+	// results created before $1 with a thin_level below $2, oldest first
+	selectResults string
+	// mark sets the thin_level of result $2 to $1
+	mark string
+	// deleteResult deletes result $1
+	deleteResult string
+}
+
+// thinResults thins out the results created before olderThan that haven't
+// been thinned to level yet, keeping every keepEvery-th result of each
+// connection and IP family, in one transaction. It returns the number of
+// results deleted.
+func thinResults(ctx context.Context, db *sql.DB, q thinQueries, olderThan time.Time, keepEvery, level int) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	kept, deleted, err := selectThinned(ctx, tx, q.selectResults, utc(olderThan), keepEvery, level)
+	if err != nil {
+		return 0, err
+	}
+	if len(kept) == 0 && len(deleted) == 0 {
+		return 0, nil
+	}
+
+	if err := execEach(ctx, tx, q.mark, kept, level); err != nil {
+		return 0, fmt.Errorf("failed to mark thinned results: %w", err)
+	}
+	if err := execEach(ctx, tx, q.deleteResult, deleted); err != nil {
+		return 0, fmt.Errorf("failed to delete thinned results: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit thinning: %w", err)
+	}
+	return int64(len(deleted)), nil
+}
+
+// selectThinned returns the IDs of the results selected by query to keep
+// and to delete.
+func selectThinned(ctx context.Context, tx *sql.Tx, query string, cutoff time.Time, keepEvery, level int) (kept, deleted []int64, err error) {
+	rows, err := tx.QueryContext(ctx, query, cutoff, level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get results to thin: %w", err)
+	}
+	defer rows.Close()
+
+	counter := newThinCounter(keepEvery)
+	for rows.Next() {
+		var id int64
+		var connectionName string
+		var ipFamily sql.NullString
+		if err := rows.Scan(&id, &connectionName, &ipFamily); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		if counter.keep(connectionName, ipFamily.String) {
+			kept = append(kept, id)
+		} else {
+			deleted = append(deleted, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating results: %w", err)
+	}
+	return kept, deleted, nil
+}
+
+// execEach executes the statement once per ID, with args before the ID.
+func execEach(ctx context.Context, tx *sql.Tx, query string, ids []int64, args ...any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(ctx, append(args, id)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
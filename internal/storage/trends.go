@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Groupings of GetTrends.
+const (
+	// TrendHourOfDay buckets results by the hour of day (0-23)
+	TrendHourOfDay = "hour_of_day"
+	// TrendDayOfWeek buckets results by the day of week (0 = Sunday to 6 = Saturday)
+	TrendDayOfWeek = "day_of_week"
+)
+
+// TrendBucket contains the statistics of all results in one bucket of a
+// trend, e.g. all tests between 20:00 and 20:59 for TrendHourOfDay.
+type TrendBucket struct {
+	// Bucket is the hour of day or day of week, depending on the grouping
+	Bucket      int     `json:"bucket"`
+	TestCount   int     `json:"test_count"`
+	ErrorCount  int     `json:"error_count"`
+	AvgDownload float64 `json:"avg_download_mbps"`
+	MinDownload float64 `json:"min_download_mbps"`
+	MaxDownload float64 `json:"max_download_mbps"`
+	AvgUpload   float64 `json:"avg_upload_mbps"`
+	MinUpload   float64 `json:"min_upload_mbps"`
+	MaxUpload   float64 `json:"max_upload_mbps"`
+	AvgLatency  float64 `json:"avg_latency_ms"`
+	MinLatency  float64 `json:"min_latency_ms"`
+	MaxLatency  float64 `json:"max_latency_ms"`
+
+	// The number of values the averages were computed from, to merge
+	// rollups into them
+	downloadSamples, uploadSamples, latencySamples int
+}
+
+// trendBucketCount returns the number of buckets of a grouping, or 0 if the
+// grouping is unknown.
+func trendBucketCount(groupBy string) int {
+	switch groupBy {
+	case TrendHourOfDay:
+		return 24
+	case TrendDayOfWeek:
+		return 7
+	default:
+		return 0
+	}
+}
+
+// IsValidTrendGrouping returns true if groupBy is a grouping supported by GetTrends.
+func IsValidTrendGrouping(groupBy string) bool {
+	return trendBucketCount(groupBy) > 0
+}
+
+// utcOffsetSeconds returns the current UTC offset of loc in seconds. Trends
+// are bucketed with a fixed offset, as SQLite has no timezone support; around
+// DST changes, results near the hour boundary may shift by one bucket.
+func utcOffsetSeconds(loc *time.Location) int {
+	if loc == nil {
+		return 0
+	}
+	_, offset := time.Now().In(loc).Zone()
+	return offset
+}
+
+// offsetBucketFunc returns a function that buckets UTC times by groupBy with
+// the fixed offset of utcOffsetSeconds, like the SQL trend queries.
+func offsetBucketFunc(groupBy string, loc *time.Location) func(time.Time) int {
+	offset := time.Duration(utcOffsetSeconds(loc)) * time.Second
+	return func(t time.Time) int {
+		return trendBucket(groupBy, utc(t).Add(offset))
+	}
+}
+
+// trendBucket returns the bucket of a time by groupBy, in the time's location.
+func trendBucket(groupBy string, t time.Time) int {
+	if groupBy == TrendDayOfWeek {
+		return int(t.Weekday())
+	}
+	return t.Hour()
+}
+
+// scanTrendBuckets reads the rows of a trend query and returns all buckets of
+// the grouping in order, including empty ones (with a TestCount of 0).
+// Columns: bucket, test count, error count, avg/min/max of download, upload
+// and latency, then the number of download, upload and latency values.
+func scanTrendBuckets(rows *sql.Rows, groupBy string) ([]TrendBucket, error) {
+	buckets := make([]TrendBucket, trendBucketCount(groupBy))
+	for i := range buckets {
+		buckets[i].Bucket = i
+	}
+
+	for rows.Next() {
+		var bucket int
+		var tb TrendBucket
+		var values [9]sql.NullFloat64
+		if err := rows.Scan(&bucket, &tb.TestCount, &tb.ErrorCount,
+			&values[0], &values[1], &values[2],
+			&values[3], &values[4], &values[5],
+			&values[6], &values[7], &values[8],
+			&tb.downloadSamples, &tb.uploadSamples, &tb.latencySamples,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan trend bucket: %w", err)
+		}
+		if bucket < 0 || bucket >= len(buckets) {
+			continue
+		}
+
+		tb.Bucket = bucket
+		tb.AvgDownload, tb.MinDownload, tb.MaxDownload = values[0].Float64, values[1].Float64, values[2].Float64
+		tb.AvgUpload, tb.MinUpload, tb.MaxUpload = values[3].Float64, values[4].Float64, values[5].Float64
+		tb.AvgLatency, tb.MinLatency, tb.MaxLatency = values[6].Float64, values[7].Float64, values[8].Float64
+		buckets[bucket] = tb
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trend buckets: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// trendAggregatesSQL are the aggregate columns of a trend query, after the
+// bucket column. Like GetStats, only phases that succeeded are included.
+const trendAggregatesSQL = `
+		COUNT(*) AS test_count,
+		COUNT(CASE WHEN error != '' THEN 1 END) AS error_count,
+		AVG(CASE WHEN error = '' AND download_ok THEN download_mbps END) AS avg_download,
+		MIN(CASE WHEN error = '' AND download_ok THEN download_mbps END) AS min_download,
+		MAX(CASE WHEN error = '' AND download_ok THEN download_mbps END) AS max_download,
+		AVG(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) AS avg_upload,
+		MIN(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) AS min_upload,
+		MAX(CASE WHEN error = '' AND upload_ok THEN upload_mbps END) AS max_upload,
+		AVG(CASE WHEN error = '' AND latency_ok THEN latency_ms END) AS avg_latency,
+		MIN(CASE WHEN error = '' AND latency_ok THEN latency_ms END) AS min_latency,
+		MAX(CASE WHEN error = '' AND latency_ok THEN latency_ms END) AS max_latency,
+		COUNT(CASE WHEN error = '' AND download_ok THEN 1 END) AS download_samples,
+		COUNT(CASE WHEN error = '' AND upload_ok THEN 1 END) AS upload_samples,
+		COUNT(CASE WHEN error = '' AND latency_ok THEN 1 END) AS latency_samples`
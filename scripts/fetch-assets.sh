@@ -0,0 +1,30 @@
+#!/bin/sh
+# Downloads the dashboard's third-party assets into internal/api/assets, so
+# they are embedded into the binary (see webserver.embed_assets).
+set -e
+
+HTMX_VERSION="1.9.10"
+CHARTJS_VERSION="4.4.1"
+FONTS_URL="https://fonts.googleapis.com/css2?family=JetBrains+Mono:wght@400;500;700&family=Space+Grotesk:wght@400;500;600;700&display=swap"
+# Google Fonts serves woff2 files only to browsers that support them
+USER_AGENT="Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+
+DIR="$(cd "$(dirname "$0")/.." && pwd)/internal/api/assets"
+mkdir -p "$DIR/fonts"
+
+echo "Downloading htmx ${HTMX_VERSION}..."
+curl -fsSL -o "$DIR/htmx.min.js" "https://unpkg.com/htmx.org@${HTMX_VERSION}/dist/htmx.min.js"
+
+echo "Downloading Chart.js ${CHARTJS_VERSION}..."
+curl -fsSL -o "$DIR/chart.umd.min.js" "https://cdn.jsdelivr.net/npm/chart.js@${CHARTJS_VERSION}/dist/chart.umd.min.js"
+
+echo "Downloading fonts..."
+css="$(curl -fsSL -A "$USER_AGENT" "$FONTS_URL")"
+for url in $(printf '%s\n' "$css" | grep -o 'https://fonts.gstatic.com/[^)]*'); do
+	file="$(printf '%s' "$url" | tr '/' '\n' | tail -n 1)"
+	curl -fsSL -o "$DIR/fonts/$file" "$url"
+done
+# Point the stylesheet at the local font files
+printf '%s\n' "$css" | sed 's|https://fonts.gstatic.com/[^)]*/\([^/)]*\))|/assets/fonts/\1)|g' > "$DIR/fonts.css"
+
+echo "Assets written to $DIR"